@@ -0,0 +1,134 @@
+// Command orchestrator runs a task hub client, worker and the HTTP API in one
+// process.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/microsoft/durabletask-go/api"
+	"github.com/shopspring/decimal"
+
+	"github.com/Youmanvi/taskorchestrator/internal/app"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/httpapi"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/config"
+	"github.com/Youmanvi/taskorchestrator/internal/workflows"
+)
+
+func main() {
+	configPath := flag.String("config", "configs/dev.yaml", "path to the configuration file")
+	sample := flag.Bool("sample", false, "schedule a sample order, wait for it and exit")
+	flag.Parse()
+
+	if err := run(*configPath, *sample); err != nil {
+		fmt.Fprintf(os.Stderr, "orchestrator: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string, sample bool) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	application, err := app.New(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := application.Start(ctx); err != nil {
+		// New opened the stores and telemetry; close and flush them anyway
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.App.Timeout)
+		defer cancel()
+		return errors.Join(err, application.Shutdown(shutdownCtx))
+	}
+
+	server := httpapi.NewServer(application.Client, application.Logger, cfg.App.Port)
+	server.SetCircuitBreakers(application.Breakers)
+	server.SetApprovals(application.Approvals)
+	server.SetDeadLetters(application.DeadLetters)
+	server.SetOutbox(application.Outbox)
+	server.SetIdempotency(application.Idempotency)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	var runErr error
+	if sample {
+		runErr = runSampleOrder(ctx, application)
+	} else {
+		select {
+		case <-ctx.Done():
+		case runErr = <-serverErr:
+		}
+	}
+	stop()
+
+	// Give in-flight requests and activities up to the app timeout to drain
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.App.Timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		application.Logger.Error("http server shutdown failed", err)
+	}
+	if err := application.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	return runErr
+}
+
+// runSampleOrder schedules a demo order and waits for it to finish
+func runSampleOrder(ctx context.Context, application *app.App) error {
+	order, err := domain.NewOrder(
+		fmt.Sprintf("ORD-SAMPLE-%d", os.Getpid()),
+		"CUST-SAMPLE",
+		[]domain.OrderItem{
+			{SKU: "ITEM-001", Quantity: 2, Price: decimal.RequireFromString("29.99")},
+			{SKU: "ITEM-002", Quantity: 1, Price: decimal.RequireFromString("49.99")},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	input := workflows.OrderProcessingInput{
+		Order:         *order,
+		CustomerEmail: "customer@example.com",
+	}
+	inputBytes, _ := json.Marshal(input)
+
+	id, err := application.Client.ScheduleNewOrchestration(ctx, "order_processing",
+		api.WithInstanceID(api.InstanceID(order.ID)),
+		api.WithRawInput(string(inputBytes)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to schedule sample order: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, application.Config.App.Timeout)
+	defer cancel()
+
+	metadata, err := application.Client.WaitForOrchestrationCompletion(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed waiting for sample order: %w", err)
+	}
+
+	application.Logger.Logger.Info().
+		Str("orchestration_id", string(id)).
+		Str("status", metadata.RuntimeStatus.String()).
+		Str("output", metadata.SerializedOutput).
+		Msg("sample order finished")
+	return nil
+}
@@ -0,0 +1,56 @@
+// Command worker runs a standalone task hub worker that processes
+// orchestrations scheduled by clients on other machines.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Youmanvi/taskorchestrator/internal/app"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/config"
+)
+
+func main() {
+	configPath := flag.String("config", "configs/dev.yaml", "path to the configuration file")
+	flag.Parse()
+
+	if err := run(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "worker: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string) error {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	application, err := app.New(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := application.Start(ctx); err != nil {
+		// New opened the stores and telemetry; close and flush them anyway
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.App.Timeout)
+		defer cancel()
+		return errors.Join(err, application.Shutdown(shutdownCtx))
+	}
+
+	<-ctx.Done()
+	stop()
+
+	// Give in-flight activities up to the app timeout to drain
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.App.Timeout)
+	defer cancel()
+	return application.Shutdown(shutdownCtx)
+}
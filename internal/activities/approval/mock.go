@@ -0,0 +1,69 @@
+package approval
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+)
+
+// MockApprovalStore is an in-memory ApprovalStore for testing
+type MockApprovalStore struct {
+	mu        sync.RWMutex
+	approvals map[string]domain.Approval
+}
+
+// NewMockApprovalStore creates an empty in-memory approval store
+func NewMockApprovalStore() *MockApprovalStore {
+	return &MockApprovalStore{approvals: make(map[string]domain.Approval)}
+}
+
+// Create records a pending approval unless the order already has one
+func (s *MockApprovalStore) Create(ctx context.Context, approval *domain.Approval) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.approvals[approval.OrderID]; !exists {
+		s.approvals[approval.OrderID] = *approval
+	}
+	return nil
+}
+
+// Get returns a copy of the order's approval
+func (s *MockApprovalStore) Get(ctx context.Context, orderID string) (*domain.Approval, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	approval, exists := s.approvals[orderID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrApprovalNotFound, orderID)
+	}
+	return &approval, nil
+}
+
+// Save replaces the stored approval
+func (s *MockApprovalStore) Save(ctx context.Context, approval *domain.Approval) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.approvals[approval.OrderID]; !exists {
+		return fmt.Errorf("%w: %s", ErrApprovalNotFound, approval.OrderID)
+	}
+	s.approvals[approval.OrderID] = *approval
+	return nil
+}
+
+// ListPending returns pending approvals, oldest first
+func (s *MockApprovalStore) ListPending(ctx context.Context) ([]*domain.Approval, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pending := []*domain.Approval{}
+	for _, approval := range s.approvals {
+		if approval.IsPending() {
+			approval := approval
+			pending = append(pending, &approval)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].RequestedAt.Before(pending[j].RequestedAt) })
+	return pending, nil
+}
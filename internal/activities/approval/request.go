@@ -0,0 +1,99 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// EventName is the external event an approver raises on the order's
+// orchestration instance; its payload is a Decision
+const EventName = "approval"
+
+// Decision values carried by the approval event
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+)
+
+// Decision is the payload of the approval event
+type Decision struct {
+	Approver string `json:"approver"`
+	Decision string `json:"decision"` // DecisionApprove or DecisionReject
+	Comment  string `json:"comment,omitempty"`
+}
+
+// Validate checks that the decision names an approver and a known outcome
+func (d Decision) Validate() error {
+	if d.Approver == "" {
+		return fmt.Errorf("approver is required")
+	}
+	if d.Decision != DecisionApprove && d.Decision != DecisionReject {
+		return fmt.Errorf("decision must be %q or %q", DecisionApprove, DecisionReject)
+	}
+	return nil
+}
+
+// ErrApprovalNotFound is returned for an order without an approval request
+var ErrApprovalNotFound = stderrors.New("approval not found")
+
+// ApprovalStore persists approval requests so pending ones can be listed
+type ApprovalStore interface {
+	// Create records a pending approval; creating one that already exists
+	// for the order is a no-op
+	Create(ctx context.Context, approval *domain.Approval) error
+	// Get returns the approval for an order or ErrApprovalNotFound
+	Get(ctx context.Context, orderID string) (*domain.Approval, error)
+	// Save persists a decided approval
+	Save(ctx context.Context, approval *domain.Approval) error
+	// ListPending returns approvals awaiting a decision, oldest first
+	ListPending(ctx context.Context) ([]*domain.Approval, error)
+}
+
+// RequestApprovalInput is the input for recording an approval request
+type RequestApprovalInput struct {
+	OrderID     string
+	CustomerID  string
+	Amount      decimal.Decimal
+	RequestedAt time.Time
+	Deadline    time.Time
+}
+
+// RequestApprovalOutput is the output of recording an approval request
+type RequestApprovalOutput struct {
+	Status string
+}
+
+// RequestApprovalActivity records a pending approval request so approvers
+// can find it
+func RequestApprovalActivity(store ApprovalStore) func(ctx context.Context, input []byte) ([]byte, error) {
+	return func(ctx context.Context, input []byte) ([]byte, error) {
+		var inp RequestApprovalInput
+		if err := json.Unmarshal(input, &inp); err != nil {
+			return nil, errors.NewPermanentError("INVALID_INPUT", "failed to unmarshal approval request input", err)
+		}
+
+		approval, err := domain.NewApproval(inp.OrderID, inp.CustomerID, inp.Amount, inp.RequestedAt, inp.Deadline)
+		if err != nil {
+			return nil, errors.NewPermanentError("INVALID_APPROVAL", err.Error(), err)
+		}
+
+		if err := store.Create(ctx, approval); err != nil {
+			return nil, errors.NewTransientError("APPROVAL_STORE_FAILED", fmt.Sprintf("failed to record approval request: %v", err), err)
+		}
+
+		result, err := json.Marshal(RequestApprovalOutput{Status: string(domain.ApprovalStatusPending)})
+		if err != nil {
+			return nil, errors.NewPermanentError("SERIALIZATION_ERROR", "failed to marshal approval request output", err)
+		}
+
+		return result, nil
+	}
+}
@@ -0,0 +1,70 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// ResolveApprovalInput is the input for recording the outcome of an approval
+type ResolveApprovalInput struct {
+	OrderID  string
+	Status   domain.ApprovalStatus // Approved, rejected or expired
+	Approver string
+	Comment  string
+}
+
+// ResolveApprovalOutput is the output of recording the outcome of an approval
+type ResolveApprovalOutput struct {
+	Status string
+}
+
+// ResolveApprovalActivity records the outcome of an approval request.
+// Resolving an approval that is already decided leaves it unchanged, so the
+// activity can be retried.
+func ResolveApprovalActivity(store ApprovalStore) func(ctx context.Context, input []byte) ([]byte, error) {
+	return func(ctx context.Context, input []byte) ([]byte, error) {
+		var inp ResolveApprovalInput
+		if err := json.Unmarshal(input, &inp); err != nil {
+			return nil, errors.NewPermanentError("INVALID_INPUT", "failed to unmarshal approval resolve input", err)
+		}
+
+		approval, err := store.Get(ctx, inp.OrderID)
+		if stderrors.Is(err, ErrApprovalNotFound) {
+			return nil, errors.NewPermanentError("APPROVAL_NOT_FOUND", err.Error(), err)
+		}
+		if err != nil {
+			return nil, errors.NewTransientError("APPROVAL_STORE_FAILED", fmt.Sprintf("failed to load approval: %v", err), err)
+		}
+
+		if approval.IsPending() {
+			now := time.Now()
+			switch inp.Status {
+			case domain.ApprovalStatusApproved:
+				err = approval.Approve(inp.Approver, inp.Comment, now)
+			case domain.ApprovalStatusRejected:
+				err = approval.Reject(inp.Approver, inp.Comment, now)
+			default:
+				err = approval.Expire(now)
+			}
+			if err != nil {
+				return nil, errors.NewPermanentError("INVALID_TRANSITION", err.Error(), err)
+			}
+			if err := store.Save(ctx, approval); err != nil {
+				return nil, errors.NewTransientError("APPROVAL_STORE_FAILED", fmt.Sprintf("failed to save approval: %v", err), err)
+			}
+		}
+
+		result, err := json.Marshal(ResolveApprovalOutput{Status: string(approval.Status)})
+		if err != nil {
+			return nil, errors.NewPermanentError("SERIALIZATION_ERROR", "failed to marshal approval resolve output", err)
+		}
+
+		return result, nil
+	}
+}
@@ -0,0 +1,203 @@
+package approval
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/shopspring/decimal"
+
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+)
+
+// SQLiteApprovalStore keeps approval requests and their decisions in SQLite
+type SQLiteApprovalStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteApprovalStore opens the approval database
+func NewSQLiteApprovalStore(dbPath string) (*SQLiteApprovalStore, error) {
+	db, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open approval database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping approval database: %w", err)
+	}
+
+	s := &SQLiteApprovalStore{db: db}
+	if err := s.initSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// initSchema creates the approval tables
+func (s *SQLiteApprovalStore) initSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS approvals (
+		order_id TEXT PRIMARY KEY,
+		customer_id TEXT NOT NULL,
+		amount TEXT NOT NULL,
+		status TEXT NOT NULL,
+		requested_at DATETIME NOT NULL,
+		deadline DATETIME NOT NULL,
+		approver TEXT NOT NULL DEFAULT '',
+		comment TEXT NOT NULL DEFAULT '',
+		decided_at DATETIME
+	);
+
+	-- Audit history of status changes, in order of seq
+	CREATE TABLE IF NOT EXISTS approval_transitions (
+		order_id TEXT NOT NULL,
+		seq INTEGER NOT NULL,
+		from_status TEXT NOT NULL,
+		to_status TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		at DATETIME NOT NULL,
+		PRIMARY KEY (order_id, seq)
+	);
+
+	-- Approvers list pending requests oldest first
+	CREATE INDEX IF NOT EXISTS idx_approvals_status_requested
+		ON approvals(status, requested_at);
+	`
+
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// Create records a pending approval unless the order already has one
+func (s *SQLiteApprovalStore) Create(ctx context.Context, approval *domain.Approval) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO approvals (order_id, customer_id, amount, status, requested_at, deadline)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, approval.OrderID, approval.CustomerID, approval.Amount.String(), string(approval.Status),
+		approval.RequestedAt.UTC(), approval.Deadline.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to create approval for %s: %w", approval.OrderID, err)
+	}
+	return nil
+}
+
+// Get loads the approval for an order and its history
+func (s *SQLiteApprovalStore) Get(ctx context.Context, orderID string) (*domain.Approval, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT order_id, customer_id, amount, status, requested_at, deadline, approver, comment, decided_at
+		FROM approvals WHERE order_id = ?
+	`, orderID)
+
+	approval, err := scanApproval(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrApprovalNotFound, orderID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load approval: %w", err)
+	}
+
+	history, err := s.db.QueryContext(ctx,
+		"SELECT from_status, to_status, reason, at FROM approval_transitions WHERE order_id = ? ORDER BY seq", orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load approval history: %w", err)
+	}
+	defer history.Close()
+
+	for history.Next() {
+		var t domain.Transition
+		if err := history.Scan(&t.From, &t.To, &t.Reason, &t.At); err != nil {
+			return nil, err
+		}
+		approval.History = append(approval.History, t)
+	}
+	return approval, history.Err()
+}
+
+// Save persists the approval's decision and any history entries not yet
+// written
+func (s *SQLiteApprovalStore) Save(ctx context.Context, approval *domain.Approval) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin approval save: %w", err)
+	}
+	defer tx.Rollback()
+
+	var decidedAt sql.NullTime
+	if !approval.DecidedAt.IsZero() {
+		decidedAt = sql.NullTime{Time: approval.DecidedAt.UTC(), Valid: true}
+	}
+
+	result, err := tx.ExecContext(ctx,
+		"UPDATE approvals SET status = ?, approver = ?, comment = ?, decided_at = ? WHERE order_id = ?",
+		string(approval.Status), approval.Approver, approval.Comment, decidedAt, approval.OrderID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update approval: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrApprovalNotFound, approval.OrderID)
+	}
+
+	for seq, t := range approval.History {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO approval_transitions (order_id, seq, from_status, to_status, reason, at) VALUES (?, ?, ?, ?, ?, ?)",
+			approval.OrderID, seq, t.From, t.To, t.Reason, t.At.UTC(),
+		); err != nil {
+			return fmt.Errorf("failed to record approval transition: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListPending returns pending approvals, oldest first
+func (s *SQLiteApprovalStore) ListPending(ctx context.Context) ([]*domain.Approval, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT order_id, customer_id, amount, status, requested_at, deadline, approver, comment, decided_at
+		FROM approvals WHERE status = ? ORDER BY requested_at
+	`, string(domain.ApprovalStatusPending))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending approvals: %w", err)
+	}
+	defer rows.Close()
+
+	pending := []*domain.Approval{}
+	for rows.Next() {
+		approval, err := scanApproval(rows)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, approval)
+	}
+	return pending, rows.Err()
+}
+
+// Close closes the database
+func (s *SQLiteApprovalStore) Close() error {
+	return s.db.Close()
+}
+
+// scanApproval reads one approvals row
+func scanApproval(row interface{ Scan(...any) error }) (*domain.Approval, error) {
+	var approval domain.Approval
+	var amount, status string
+	var decidedAt sql.NullTime
+	if err := row.Scan(&approval.OrderID, &approval.CustomerID, &amount, &status,
+		&approval.RequestedAt, &approval.Deadline, &approval.Approver, &approval.Comment, &decidedAt); err != nil {
+		return nil, err
+	}
+
+	parsed, err := decimal.NewFromString(amount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount for approval %s: %w", approval.OrderID, err)
+	}
+	approval.Amount = parsed
+	approval.Status = domain.ApprovalStatus(status)
+	approval.DecidedAt = decidedAt.Time
+	return &approval, nil
+}
@@ -0,0 +1,61 @@
+package approval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+)
+
+func TestSQLiteApprovalStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewSQLiteApprovalStore(t.TempDir() + "/approvals.db")
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, id := range []string{"ORD-2", "ORD-1"} {
+		a, err := domain.NewApproval(id, "CUST-1", decimal.RequireFromString("1500.50"), now.Add(time.Duration(i)*time.Minute), now.Add(time.Hour))
+		require.NoError(t, err)
+		require.NoError(t, store.Create(ctx, a))
+		require.NoError(t, store.Create(ctx, a), "create is idempotent")
+	}
+
+	pending, err := store.ListPending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, "ORD-2", pending[0].OrderID, "oldest first")
+	assert.True(t, decimal.RequireFromString("1500.50").Equal(pending[0].Amount))
+	assert.True(t, now.Add(time.Hour).Equal(pending[0].Deadline))
+
+	a, err := store.Get(ctx, "ORD-1")
+	require.NoError(t, err)
+	require.NoError(t, a.Approve("alice", "ok", now))
+	require.NoError(t, store.Save(ctx, a))
+
+	// Re-creating a decided approval leaves the decision alone
+	again, err := domain.NewApproval("ORD-1", "CUST-1", decimal.NewFromInt(1), now, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, store.Create(ctx, again))
+
+	a, err = store.Get(ctx, "ORD-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ApprovalStatusApproved, a.Status)
+	assert.Equal(t, "alice", a.Approver)
+	assert.False(t, a.DecidedAt.IsZero())
+	require.Len(t, a.History, 1)
+	assert.Equal(t, "approved", a.History[0].To)
+
+	pending, err = store.ListPending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "ORD-2", pending[0].OrderID)
+
+	_, err = store.Get(ctx, "missing")
+	assert.ErrorIs(t, err, ErrApprovalNotFound)
+}
@@ -0,0 +1,98 @@
+package deadletter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MockDeadLetterStore is an in-memory DeadLetterStore for testing
+type MockDeadLetterStore struct {
+	mu      sync.RWMutex
+	entries []Entry // Indexed by ID-1
+}
+
+// NewMockDeadLetterStore creates an empty in-memory dead-letter store
+func NewMockDeadLetterStore() *MockDeadLetterStore {
+	return &MockDeadLetterStore{}
+}
+
+// Add records a pending entry unless an identical call is already recorded
+func (s *MockDeadLetterStore) Add(ctx context.Context, entry Entry) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.entries {
+		if existing.OrchestrationID == entry.OrchestrationID && existing.Activity == entry.Activity && bytes.Equal(existing.Payload, entry.Payload) {
+			return existing.ID, nil
+		}
+	}
+
+	now := time.Now().UTC()
+	entry.ID = int64(len(s.entries) + 1)
+	entry.Status = StatusPending
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	s.entries = append(s.entries, entry)
+	return entry.ID, nil
+}
+
+// Get returns a copy of an entry
+func (s *MockDeadLetterStore) Get(ctx context.Context, id int64) (*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if id < 1 || id > int64(len(s.entries)) {
+		return nil, fmt.Errorf("%w: %d", ErrEntryNotFound, id)
+	}
+	entry := s.entries[id-1]
+	return &entry, nil
+}
+
+// List returns entries with the given status, oldest first
+func (s *MockDeadLetterStore) List(ctx context.Context, status Status) ([]*Entry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []*Entry{}
+	for _, entry := range s.entries {
+		if status == "" || entry.Status == status {
+			entry := entry
+			entries = append(entries, &entry)
+		}
+	}
+	return entries, nil
+}
+
+// RecordReplay sets the attempt count and error of a pending entry
+func (s *MockDeadLetterStore) RecordReplay(ctx context.Context, id int64, attempts int, errorCode, errorMessage string) error {
+	return s.update(id, func(entry *Entry) {
+		entry.Attempts = attempts
+		if errorCode != "" {
+			entry.ErrorCode = errorCode
+			entry.ErrorMessage = errorMessage
+		}
+	})
+}
+
+// Resolve moves a pending entry to status
+func (s *MockDeadLetterStore) Resolve(ctx context.Context, id int64, status Status) error {
+	return s.update(id, func(entry *Entry) { entry.Status = status })
+}
+
+// update applies fn to a pending entry
+func (s *MockDeadLetterStore) update(id int64, fn func(entry *Entry)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id < 1 || id > int64(len(s.entries)) {
+		return fmt.Errorf("%w: %d", ErrEntryNotFound, id)
+	}
+	entry := &s.entries[id-1]
+	if entry.Status != StatusPending {
+		return fmt.Errorf("%w: %d is %s", ErrEntryResolved, id, entry.Status)
+	}
+	fn(entry)
+	entry.UpdatedAt = time.Now().UTC()
+	return nil
+}
@@ -0,0 +1,108 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// Status is the state of a dead-letter entry
+type Status string
+
+const (
+	StatusPending   Status = "pending"   // Waiting to be replayed or discarded
+	StatusReplayed  Status = "replayed"  // A replay succeeded
+	StatusDiscarded Status = "discarded" // An operator gave up on it
+)
+
+// ErrEntryNotFound is returned for an unknown entry ID
+var ErrEntryNotFound = stderrors.New("dead-letter entry not found")
+
+// ErrEntryResolved is returned when replaying or discarding an entry that is
+// no longer pending
+var ErrEntryResolved = stderrors.New("dead-letter entry already resolved")
+
+// Entry is a non-critical activity call that still failed after its retries
+type Entry struct {
+	ID              int64           `json:"id"`
+	OrchestrationID string          `json:"orchestration_id"`
+	Activity        string          `json:"activity"`
+	Payload         json.RawMessage `json:"payload"` // The activity input, replayed as is
+	ErrorCode       string          `json:"error_code"`
+	ErrorMessage    string          `json:"error_message"`
+	Attempts        int             `json:"attempts"` // Every attempt so far, including replays
+	Status          Status          `json:"status"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// DeadLetterStore persists dead-letter entries
+type DeadLetterStore interface {
+	// Add records a pending entry and returns its ID. Adding the same
+	// orchestration, activity and payload again returns the existing ID.
+	Add(ctx context.Context, entry Entry) (int64, error)
+	// Get returns an entry or ErrEntryNotFound
+	Get(ctx context.Context, id int64) (*Entry, error)
+	// List returns entries with the given status, oldest first; an empty
+	// status lists every entry
+	List(ctx context.Context, status Status) ([]*Entry, error)
+	// RecordReplay sets a pending entry's total attempt count after a replay
+	// and, when errorCode is set, the error the replay failed with. It
+	// returns ErrEntryResolved unless the entry is pending.
+	RecordReplay(ctx context.Context, id int64, attempts int, errorCode, errorMessage string) error
+	// Resolve moves a pending entry to replayed or discarded. It returns
+	// ErrEntryResolved unless the entry is pending.
+	Resolve(ctx context.Context, id int64, status Status) error
+}
+
+// RecordInput is the input for recording a failed activity call
+type RecordInput struct {
+	OrchestrationID string
+	Activity        string
+	Payload         json.RawMessage
+	ErrorCode       string
+	ErrorMessage    string
+	Attempts        int
+}
+
+// RecordOutput is the output of recording a failed activity call
+type RecordOutput struct {
+	ID int64
+}
+
+// RecordActivity records a failed non-critical activity call in the
+// dead-letter queue
+func RecordActivity(store DeadLetterStore) func(ctx context.Context, input []byte) ([]byte, error) {
+	return func(ctx context.Context, input []byte) ([]byte, error) {
+		var inp RecordInput
+		if err := json.Unmarshal(input, &inp); err != nil {
+			return nil, errors.NewPermanentError("INVALID_INPUT", "failed to unmarshal dead-letter input", err)
+		}
+		if inp.OrchestrationID == "" || inp.Activity == "" {
+			return nil, errors.NewPermanentError("INVALID_INPUT", "orchestration ID and activity are required", nil)
+		}
+
+		id, err := store.Add(ctx, Entry{
+			OrchestrationID: inp.OrchestrationID,
+			Activity:        inp.Activity,
+			Payload:         inp.Payload,
+			ErrorCode:       inp.ErrorCode,
+			ErrorMessage:    inp.ErrorMessage,
+			Attempts:        inp.Attempts,
+		})
+		if err != nil {
+			return nil, errors.NewTransientError("DEAD_LETTER_STORE_FAILED", fmt.Sprintf("failed to record dead-letter entry: %v", err), err)
+		}
+
+		result, err := json.Marshal(RecordOutput{ID: id})
+		if err != nil {
+			return nil, errors.NewPermanentError("SERIALIZATION_ERROR", "failed to marshal dead-letter output", err)
+		}
+
+		return result, nil
+	}
+}
@@ -0,0 +1,61 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// ResolveInput is the input for recording the outcome of a replay
+type ResolveInput struct {
+	ID           int64
+	Succeeded    bool
+	Attempts     int    // Total attempts, including the replay's
+	ErrorCode    string // Set when the replay failed
+	ErrorMessage string
+}
+
+// ResolveOutput is the output of recording the outcome of a replay
+type ResolveOutput struct {
+	Status string
+}
+
+// ResolveActivity records the outcome of replaying a dead-letter entry. A
+// successful replay marks the entry replayed; a failed one leaves it pending
+// with the new error and attempt count.
+func ResolveActivity(store DeadLetterStore) func(ctx context.Context, input []byte) ([]byte, error) {
+	return func(ctx context.Context, input []byte) ([]byte, error) {
+		var inp ResolveInput
+		if err := json.Unmarshal(input, &inp); err != nil {
+			return nil, errors.NewPermanentError("INVALID_INPUT", "failed to unmarshal dead-letter resolve input", err)
+		}
+
+		err := store.RecordReplay(ctx, inp.ID, inp.Attempts, inp.ErrorCode, inp.ErrorMessage)
+		if err == nil && inp.Succeeded {
+			err = store.Resolve(ctx, inp.ID, StatusReplayed)
+		}
+		switch {
+		case stderrors.Is(err, ErrEntryNotFound):
+			return nil, errors.NewPermanentError("DEAD_LETTER_NOT_FOUND", err.Error(), err)
+		case stderrors.Is(err, ErrEntryResolved):
+			// Discarded while the replay ran; the discard stands
+		case err != nil:
+			return nil, errors.NewTransientError("DEAD_LETTER_STORE_FAILED", fmt.Sprintf("failed to resolve dead-letter entry: %v", err), err)
+		}
+
+		entry, err := store.Get(ctx, inp.ID)
+		if err != nil {
+			return nil, errors.NewTransientError("DEAD_LETTER_STORE_FAILED", fmt.Sprintf("failed to load dead-letter entry: %v", err), err)
+		}
+
+		result, err := json.Marshal(ResolveOutput{Status: string(entry.Status)})
+		if err != nil {
+			return nil, errors.NewPermanentError("SERIALIZATION_ERROR", "failed to marshal dead-letter resolve output", err)
+		}
+
+		return result, nil
+	}
+}
@@ -0,0 +1,181 @@
+package deadletter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteDeadLetterStore keeps dead-letter entries in SQLite
+type SQLiteDeadLetterStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteDeadLetterStore opens the dead-letter database
+func NewSQLiteDeadLetterStore(dbPath string) (*SQLiteDeadLetterStore, error) {
+	db, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping dead-letter database: %w", err)
+	}
+
+	s := &SQLiteDeadLetterStore{db: db}
+	if err := s.initSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// initSchema creates the dead_letters table
+func (s *SQLiteDeadLetterStore) initSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS dead_letters (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		orchestration_id TEXT NOT NULL,
+		activity TEXT NOT NULL,
+		payload BLOB NOT NULL,
+		error_code TEXT NOT NULL DEFAULT '',
+		error_message TEXT NOT NULL DEFAULT '',
+		attempts INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL,
+		UNIQUE (orchestration_id, activity, payload)
+	);
+
+	-- Operators list entries by status, oldest first
+	CREATE INDEX IF NOT EXISTS idx_dead_letters_status_id ON dead_letters(status, id);
+	`
+
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// Add records a pending entry unless an identical call is already recorded
+func (s *SQLiteDeadLetterStore) Add(ctx context.Context, entry Entry) (int64, error) {
+	now := time.Now().UTC()
+	payload := []byte(entry.Payload)
+	if payload == nil {
+		payload = []byte{}
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO dead_letters
+			(orchestration_id, activity, payload, error_code, error_message, attempts, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, entry.OrchestrationID, entry.Activity, payload, entry.ErrorCode, entry.ErrorMessage,
+		entry.Attempts, string(StatusPending), now, now); err != nil {
+		return 0, fmt.Errorf("failed to add dead-letter entry: %w", err)
+	}
+
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id FROM dead_letters WHERE orchestration_id = ? AND activity = ? AND payload = ?",
+		entry.OrchestrationID, entry.Activity, payload,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read dead-letter entry ID: %w", err)
+	}
+	return id, nil
+}
+
+// Get returns an entry
+func (s *SQLiteDeadLetterStore) Get(ctx context.Context, id int64) (*Entry, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, orchestration_id, activity, payload, error_code, error_message, attempts, status, created_at, updated_at
+		FROM dead_letters WHERE id = ?
+	`, id)
+
+	entry, err := scanEntry(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %d", ErrEntryNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dead-letter entry: %w", err)
+	}
+	return entry, nil
+}
+
+// List returns entries with the given status, oldest first
+func (s *SQLiteDeadLetterStore) List(ctx context.Context, status Status) ([]*Entry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, orchestration_id, activity, payload, error_code, error_message, attempts, status, created_at, updated_at
+		FROM dead_letters WHERE ? = '' OR status = ? ORDER BY id
+	`, string(status), string(status))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead-letter entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*Entry{}
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// RecordReplay sets the attempt count and error of a pending entry
+func (s *SQLiteDeadLetterStore) RecordReplay(ctx context.Context, id int64, attempts int, errorCode, errorMessage string) error {
+	if errorCode == "" {
+		return s.updatePending(ctx, id, "attempts = ?", attempts)
+	}
+	return s.updatePending(ctx, id, "attempts = ?, error_code = ?, error_message = ?", attempts, errorCode, errorMessage)
+}
+
+// Resolve moves a pending entry to status
+func (s *SQLiteDeadLetterStore) Resolve(ctx context.Context, id int64, status Status) error {
+	return s.updatePending(ctx, id, "status = ?", string(status))
+}
+
+// updatePending applies set to an entry if it is still pending, reporting
+// ErrEntryNotFound or ErrEntryResolved otherwise
+func (s *SQLiteDeadLetterStore) updatePending(ctx context.Context, id int64, set string, args ...any) error {
+	args = append(args, time.Now().UTC(), id, string(StatusPending))
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE dead_letters SET "+set+", updated_at = ? WHERE id = ? AND status = ?", args...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update dead-letter entry: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	entry, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %d is %s", ErrEntryResolved, id, entry.Status)
+}
+
+// Close closes the database
+func (s *SQLiteDeadLetterStore) Close() error {
+	return s.db.Close()
+}
+
+// scanEntry reads one dead_letters row
+func scanEntry(row interface{ Scan(...any) error }) (*Entry, error) {
+	var entry Entry
+	var payload []byte
+	var status string
+	if err := row.Scan(&entry.ID, &entry.OrchestrationID, &entry.Activity, &payload, &entry.ErrorCode,
+		&entry.ErrorMessage, &entry.Attempts, &status, &entry.CreatedAt, &entry.UpdatedAt); err != nil {
+		return nil, err
+	}
+	entry.Payload = payload
+	entry.Status = Status(status)
+	return &entry, nil
+}
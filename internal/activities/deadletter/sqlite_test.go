@@ -0,0 +1,70 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteDeadLetterStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewSQLiteDeadLetterStore(t.TempDir() + "/deadletter.db")
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	entry := Entry{
+		OrchestrationID: "ORD-1",
+		Activity:        "notification:order_confirmation",
+		Payload:         json.RawMessage(`{"OrderID":"ORD-1"}`),
+		ErrorCode:       "EMAIL_SEND_FAILED",
+		ErrorMessage:    "[EMAIL_SEND_FAILED] failed to send confirmation email",
+		Attempts:        3,
+	}
+	id, err := store.Add(ctx, entry)
+	require.NoError(t, err)
+
+	// Recording the same failed call again does not add a second entry
+	again, err := store.Add(ctx, entry)
+	require.NoError(t, err)
+	assert.Equal(t, id, again)
+
+	other := entry
+	other.OrchestrationID = "ORD-2"
+	otherID, err := store.Add(ctx, other)
+	require.NoError(t, err)
+	assert.NotEqual(t, id, otherID)
+
+	got, err := store.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, got.Status)
+	assert.JSONEq(t, `{"OrderID":"ORD-1"}`, string(got.Payload))
+	assert.Equal(t, 3, got.Attempts)
+
+	// A failed replay keeps the entry pending with the new error
+	require.NoError(t, store.RecordReplay(ctx, id, 6, "SMTP_DOWN", "[SMTP_DOWN] still down"))
+	got, err = store.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 6, got.Attempts)
+	assert.Equal(t, "SMTP_DOWN", got.ErrorCode)
+
+	require.NoError(t, store.Resolve(ctx, id, StatusReplayed))
+	assert.ErrorIs(t, store.Resolve(ctx, id, StatusDiscarded), ErrEntryResolved)
+	require.NoError(t, store.Resolve(ctx, otherID, StatusDiscarded))
+
+	pending, err := store.List(ctx, StatusPending)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	all, err := store.List(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, StatusReplayed, all[0].Status)
+	assert.Equal(t, StatusDiscarded, all[1].Status)
+
+	_, err = store.Get(ctx, 99)
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+	assert.ErrorIs(t, store.Resolve(ctx, 99, StatusDiscarded), ErrEntryNotFound)
+}
@@ -3,6 +3,7 @@ package inventory
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"github.com/Youmanvi/taskorchestrator/internal/domain"
 	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
@@ -27,11 +28,14 @@ func CheckAvailabilityActivity(manager InventoryManager) func(ctx context.Contex
 			return nil, errors.NewPermanentError("INVALID_INPUT", "failed to unmarshal check input", err)
 		}
 
-		// For demo purposes, all items are available
-		// In a real implementation, this would check a database
+		unavailable, err := manager.CheckAvailability(ctx, inp.Items)
+		if err != nil {
+			return nil, errors.NewTransientError("INVENTORY_CHECK_FAILED", fmt.Sprintf("failed to check inventory: %v", err), err)
+		}
+
 		output := CheckAvailabilityOutput{
-			Available:        len(inp.Items) > 0,
-			UnavailableItems: []string{},
+			Available:        len(inp.Items) > 0 && len(unavailable) == 0,
+			UnavailableItems: unavailable,
 		}
 
 		result, err := json.Marshal(output)
@@ -0,0 +1,51 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// CommitInventoryInput is the input for committing a reservation
+type CommitInventoryInput struct {
+	ReservationID string
+}
+
+// CommitInventoryOutput is the output of committing a reservation
+type CommitInventoryOutput struct {
+	Status string
+}
+
+// CommitInventoryActivity takes a reservation's stock once its order is paid
+func CommitInventoryActivity(manager InventoryManager) func(ctx context.Context, input []byte) ([]byte, error) {
+	return func(ctx context.Context, input []byte) ([]byte, error) {
+		var inp CommitInventoryInput
+		if err := json.Unmarshal(input, &inp); err != nil {
+			return nil, errors.NewPermanentError("INVALID_INPUT", "failed to unmarshal commit input", err)
+		}
+
+		if inp.ReservationID == "" {
+			return nil, errors.NewPermanentError("MISSING_RESERVATION_ID", "reservation ID is required", nil)
+		}
+
+		err := manager.Commit(ctx, inp.ReservationID)
+		switch {
+		case stderrors.Is(err, ErrReservationNotActive):
+			return nil, errors.NewPermanentError("RESERVATION_NOT_ACTIVE", err.Error(), err)
+		case stderrors.Is(err, ErrReservationNotFound):
+			return nil, errors.NewPermanentError("RESERVATION_NOT_FOUND", err.Error(), err)
+		case err != nil:
+			return nil, errors.NewTransientError("COMMIT_FAILED", fmt.Sprintf("failed to commit inventory: %v", err), err)
+		}
+
+		result, err := json.Marshal(CommitInventoryOutput{Status: "committed"})
+		if err != nil {
+			return nil, errors.NewPermanentError("SERIALIZATION_ERROR", "failed to marshal commit output", err)
+		}
+
+		return result, nil
+	}
+}
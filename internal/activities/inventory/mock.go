@@ -4,29 +4,84 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/Youmanvi/taskorchestrator/internal/domain"
 )
 
 // MockInventoryManager is a mock implementation of InventoryManager for testing
 type MockInventoryManager struct {
-	mu           sync.RWMutex
-	reservations map[string]*domain.InventoryReservation
+	mu               sync.RWMutex
+	reservations     map[string]*domain.InventoryReservation
+	unavailable      map[string]bool
+	closedWarehouses map[string]bool
 }
 
 // NewMockInventoryManager creates a new mock inventory manager
 func NewMockInventoryManager() *MockInventoryManager {
 	return &MockInventoryManager{
-		reservations: make(map[string]*domain.InventoryReservation),
+		reservations:     make(map[string]*domain.InventoryReservation),
+		unavailable:      make(map[string]bool),
+		closedWarehouses: make(map[string]bool),
 	}
 }
 
+// SetUnavailable marks SKUs as out of stock for subsequent checks and reservations
+func (m *MockInventoryManager) SetUnavailable(skus ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sku := range skus {
+		m.unavailable[sku] = true
+	}
+}
+
+// SetWarehouseClosed makes reservations in the given warehouses fail with
+// ErrInsufficientStock while availability checks still pass, as when stock
+// runs out between the check and the reservation
+func (m *MockInventoryManager) SetWarehouseClosed(warehouses ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, warehouse := range warehouses {
+		m.closedWarehouses[warehouse] = true
+	}
+}
+
+// CheckAvailability reports SKUs marked unavailable
+func (m *MockInventoryManager) CheckAvailability(ctx context.Context, items []domain.OrderItem) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	unavailable := []string{}
+	for _, item := range items {
+		if m.unavailable[item.SKU] {
+			unavailable = append(unavailable, item.SKU)
+		}
+	}
+	return unavailable, nil
+}
+
 // Reserve simulates reserving inventory
-func (m *MockInventoryManager) Reserve(ctx context.Context, orderID string, items []domain.OrderItem) (string, error) {
+func (m *MockInventoryManager) Reserve(ctx context.Context, orderID, warehouse string, items []domain.OrderItem, idempotencyKey string) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	reservationID := fmt.Sprintf("RES_%s", orderID)
+	for _, item := range items {
+		if m.unavailable[item.SKU] {
+			return "", fmt.Errorf("%w: %s", ErrInsufficientStock, item.SKU)
+		}
+	}
+	if m.closedWarehouses[warehouse] {
+		return "", fmt.Errorf("%w: warehouse %s", ErrInsufficientStock, warehouse)
+	}
+
+	// Reservation IDs are derived from the order and warehouse, so repeated
+	// reserves for the same order (and idempotency key) land on the same
+	// reservation
+	reservationID := domain.ReservationID(orderID, warehouse)
+	if res, exists := m.reservations[reservationID]; exists &&
+		(res.Status == domain.ReservationStatusActive || res.Status == domain.ReservationStatusCommitted) {
+		return reservationID, nil
+	}
 
 	// Convert OrderItems to ReservedItems
 	reservedItems := make([]domain.ReservedItem, len(items))
@@ -55,11 +110,98 @@ func (m *MockInventoryManager) Release(ctx context.Context, reservationID string
 	if !exists {
 		return fmt.Errorf("reservation not found: %s", reservationID)
 	}
+	if res.Status != domain.ReservationStatusActive {
+		return nil
+	}
+
+	return res.MarkReleased(time.Now())
+}
+
+// ReleaseItems simulates releasing part of a reservation
+func (m *MockInventoryManager) ReleaseItems(ctx context.Context, reservationID string, items []domain.OrderItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res, exists := m.reservations[reservationID]
+	if !exists {
+		return fmt.Errorf("reservation not found: %s", reservationID)
+	}
+	if res.Status != domain.ReservationStatusActive {
+		return nil
+	}
+
+	released := make([]domain.ReservedItem, 0, len(items))
+	for _, item := range items {
+		released = append(released, domain.ReservedItem{SKU: item.SKU, Quantity: item.Quantity})
+	}
+	if err := res.ReleaseItems(released, time.Now()); err != nil {
+		return fmt.Errorf("%w: %v", ErrReleaseExceedsReserved, err)
+	}
+	return nil
+}
+
+// Commit simulates taking a reservation's stock for its order
+func (m *MockInventoryManager) Commit(ctx context.Context, reservationID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res, exists := m.reservations[reservationID]
+	if !exists {
+		return fmt.Errorf("reservation not found: %s", reservationID)
+	}
+	switch res.Status {
+	case domain.ReservationStatusActive:
+		return res.MarkCommitted(time.Now())
+	case domain.ReservationStatusCommitted, domain.ReservationStatusRestocked:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s is %s", ErrReservationNotActive, reservationID, res.Status)
+	}
+}
+
+// Restock simulates returning committed stock
+func (m *MockInventoryManager) Restock(ctx context.Context, reservationID string, items []domain.OrderItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res, exists := m.reservations[reservationID]
+	if !exists {
+		return fmt.Errorf("reservation not found: %s", reservationID)
+	}
+	if res.Status != domain.ReservationStatusCommitted {
+		return nil
+	}
+	if len(items) == 0 {
+		return res.MarkRestocked(time.Now())
+	}
 
-	res.MarkReleased()
+	restocked := make([]domain.ReservedItem, 0, len(items))
+	for _, item := range items {
+		restocked = append(restocked, domain.ReservedItem{SKU: item.SKU, Quantity: item.Quantity})
+	}
+	if err := res.RestockItems(restocked, time.Now()); err != nil {
+		return fmt.Errorf("%w: %v", ErrRestockExceedsCommitted, err)
+	}
 	return nil
 }
 
+// CheckRestock simulates checking a restock against the committed stock
+func (m *MockInventoryManager) CheckRestock(ctx context.Context, reservationID string, items []domain.OrderItem) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	res, exists := m.reservations[reservationID]
+	if !exists {
+		return fmt.Errorf("reservation not found: %s", reservationID)
+	}
+
+	restocked := make([]domain.ReservedItem, 0, len(items))
+	for _, item := range items {
+		restocked = append(restocked, domain.ReservedItem{SKU: item.SKU, Quantity: item.Quantity})
+	}
+	return checkRestock(res, restocked)
+}
+
 // GetReservation retrieves a reservation
 func (m *MockInventoryManager) GetReservation(reservationID string) (*domain.InventoryReservation, bool) {
 	m.mu.RLock()
@@ -3,14 +3,17 @@ package inventory
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
 	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
 )
 
 // ReleaseInventoryInput is the input for releasing inventory
 type ReleaseInventoryInput struct {
 	ReservationID string
+	Items         []domain.OrderItem // Quantities to release; empty releases the whole reservation
 }
 
 // ReleaseInventoryOutput is the output of releasing inventory
@@ -30,12 +33,23 @@ func ReleaseInventoryActivity(manager InventoryManager) func(ctx context.Context
 			return nil, errors.NewPermanentError("MISSING_RESERVATION_ID", "reservation ID is required", nil)
 		}
 
-		if err := manager.Release(ctx, inp.ReservationID); err != nil {
+		status := "released"
+		var err error
+		if len(inp.Items) > 0 {
+			status = "partially_released"
+			err = manager.ReleaseItems(ctx, inp.ReservationID, inp.Items)
+		} else {
+			err = manager.Release(ctx, inp.ReservationID)
+		}
+		if stderrors.Is(err, ErrReleaseExceedsReserved) {
+			return nil, errors.NewPermanentError("RELEASE_EXCEEDS_RESERVED", err.Error(), err)
+		}
+		if err != nil {
 			return nil, errors.NewTransientError("RELEASE_FAILED", fmt.Sprintf("failed to release inventory: %v", err), err)
 		}
 
 		output := ReleaseInventoryOutput{
-			Status: "released",
+			Status: status,
 		}
 
 		result, err := json.Marshal(output)
@@ -3,16 +3,19 @@ package inventory
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 
 	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/middleware"
 	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
 )
 
 // ReserveInventoryInput is the input for reserving inventory
 type ReserveInventoryInput struct {
-	OrderID string
-	Items   []domain.OrderItem
+	OrderID   string
+	Warehouse string // Warehouse the items are reserved in; empty is the default warehouse
+	Items     []domain.OrderItem
 }
 
 // ReserveInventoryOutput is the output of reserving inventory
@@ -23,8 +26,24 @@ type ReserveInventoryOutput struct {
 
 // InventoryManager manages inventory reservations
 type InventoryManager interface {
-	Reserve(ctx context.Context, orderID string, items []domain.OrderItem) (string, error)
+	// CheckAvailability returns the SKUs that cannot currently be reserved
+	CheckAvailability(ctx context.Context, items []domain.OrderItem) ([]string, error)
+	// Reserve reserves items for an order in a warehouse; reserves repeated
+	// with the same idempotencyKey must return the original reservation
+	Reserve(ctx context.Context, orderID, warehouse string, items []domain.OrderItem, idempotencyKey string) (string, error)
 	Release(ctx context.Context, reservationID string) error
+	// ReleaseItems releases only the given quantities, leaving the rest of
+	// the reservation held
+	ReleaseItems(ctx context.Context, reservationID string, items []domain.OrderItem) error
+	// Commit takes a reservation's stock out of the warehouse once its order
+	// is paid; a committed reservation no longer expires
+	Commit(ctx context.Context, reservationID string) error
+	// Restock puts committed stock back, only the given quantities when
+	// items is set, otherwise everything still committed
+	Restock(ctx context.Context, reservationID string, items []domain.OrderItem) error
+	// CheckRestock returns ErrRestockExceedsCommitted, changing nothing, if
+	// Restock(items) would restock more than is still committed
+	CheckRestock(ctx context.Context, reservationID string, items []domain.OrderItem) error
 }
 
 // ReserveInventoryActivity reserves inventory for an order
@@ -43,9 +62,17 @@ func ReserveInventoryActivity(manager InventoryManager) func(ctx context.Context
 			return nil, errors.NewPermanentError("EMPTY_ITEMS", "items list cannot be empty", nil)
 		}
 
-		reservationID, err := manager.Reserve(ctx, inp.OrderID, inp.Items)
+		idempotencyKey := middleware.IdempotencyKeyFromContext(ctx)
+		if idempotencyKey == "" {
+			idempotencyKey = fmt.Sprintf("reserve/%s", domain.ReservationID(inp.OrderID, inp.Warehouse))
+		}
+
+		reservationID, err := manager.Reserve(ctx, inp.OrderID, inp.Warehouse, inp.Items, idempotencyKey)
 		if err != nil {
 			// Classify error
+			if stderrors.Is(err, ErrInsufficientStock) {
+				return nil, errors.NewPermanentError("INSUFFICIENT_STOCK", fmt.Sprintf("failed to reserve inventory: %v", err), err)
+			}
 			return nil, errors.NewPermanentError("RESERVATION_FAILED", fmt.Sprintf("failed to reserve inventory: %v", err), err)
 		}
 
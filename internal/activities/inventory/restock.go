@@ -0,0 +1,120 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// RestockInventoryInput is the input for restocking a committed reservation
+type RestockInventoryInput struct {
+	ReservationID string
+	Items         []domain.OrderItem // Quantities to restock; empty restocks everything committed
+}
+
+// RestockInventoryOutput is the output of restocking inventory
+type RestockInventoryOutput struct {
+	Status string
+}
+
+// CheckRestockInput is the input for checking a restock before it happens
+type CheckRestockInput struct {
+	ReservationID string
+	Items         []domain.OrderItem // Quantities that will be restocked
+}
+
+// CheckRestockOutput is the output of checking a restock
+type CheckRestockOutput struct {
+	Status string
+}
+
+// RestockInventoryActivity puts a refunded or cancelled order's committed
+// stock back on hand
+func RestockInventoryActivity(manager InventoryManager) func(ctx context.Context, input []byte) ([]byte, error) {
+	return func(ctx context.Context, input []byte) ([]byte, error) {
+		var inp RestockInventoryInput
+		if err := json.Unmarshal(input, &inp); err != nil {
+			return nil, errors.NewPermanentError("INVALID_INPUT", "failed to unmarshal restock input", err)
+		}
+
+		if inp.ReservationID == "" {
+			return nil, errors.NewPermanentError("MISSING_RESERVATION_ID", "reservation ID is required", nil)
+		}
+
+		status := "restocked"
+		if len(inp.Items) > 0 {
+			status = "partially_restocked"
+		}
+		err := manager.Restock(ctx, inp.ReservationID, inp.Items)
+		if stderrors.Is(err, ErrRestockExceedsCommitted) {
+			return nil, errors.NewPermanentError("RESTOCK_EXCEEDS_COMMITTED", err.Error(), err)
+		}
+		if err != nil {
+			return nil, errors.NewTransientError("RESTOCK_FAILED", fmt.Sprintf("failed to restock inventory: %v", err), err)
+		}
+
+		result, err := json.Marshal(RestockInventoryOutput{Status: status})
+		if err != nil {
+			return nil, errors.NewPermanentError("SERIALIZATION_ERROR", "failed to marshal restock output", err)
+		}
+
+		return result, nil
+	}
+}
+
+// CheckRestockActivity fails permanently if a return's items are no longer
+// committed to the reservation, for instance because they were already
+// returned, so a refund can be rejected before any money moves
+func CheckRestockActivity(manager InventoryManager) func(ctx context.Context, input []byte) ([]byte, error) {
+	return func(ctx context.Context, input []byte) ([]byte, error) {
+		var inp CheckRestockInput
+		if err := json.Unmarshal(input, &inp); err != nil {
+			return nil, errors.NewPermanentError("INVALID_INPUT", "failed to unmarshal restock check input", err)
+		}
+
+		if inp.ReservationID == "" {
+			return nil, errors.NewPermanentError("MISSING_RESERVATION_ID", "reservation ID is required", nil)
+		}
+		if len(inp.Items) == 0 {
+			return nil, errors.NewPermanentError("MISSING_ITEMS", "items to restock are required", nil)
+		}
+
+		err := manager.CheckRestock(ctx, inp.ReservationID, inp.Items)
+		switch {
+		case stderrors.Is(err, ErrRestockExceedsCommitted):
+			return nil, errors.NewPermanentError("RESTOCK_EXCEEDS_COMMITTED", err.Error(), err)
+		case stderrors.Is(err, ErrReservationNotFound):
+			return nil, errors.NewPermanentError("RESERVATION_NOT_FOUND", err.Error(), err)
+		case err != nil:
+			return nil, errors.NewTransientError("CHECK_RESTOCK_FAILED", fmt.Sprintf("failed to check restock: %v", err), err)
+		}
+
+		result, err := json.Marshal(CheckRestockOutput{Status: "restockable"})
+		if err != nil {
+			return nil, errors.NewPermanentError("SERIALIZATION_ERROR", "failed to marshal restock check output", err)
+		}
+
+		return result, nil
+	}
+}
+
+// checkRestock restocks items from a copy of res, returning
+// ErrRestockExceedsCommitted if res does not have them all committed
+func checkRestock(res *domain.InventoryReservation, items []domain.ReservedItem) error {
+	if res.Status != domain.ReservationStatusCommitted {
+		return fmt.Errorf("%w: %s is %s", ErrRestockExceedsCommitted, res.ID, res.Status)
+	}
+	// RestockItems rewrites Items in place, so the copy gets its own
+	check := *res
+	check.Items = append([]domain.ReservedItem(nil), res.Items...)
+	check.History = nil
+	if err := check.RestockItems(items, time.Now()); err != nil {
+		return fmt.Errorf("%w: %v", ErrRestockExceedsCommitted, err)
+	}
+	return nil
+}
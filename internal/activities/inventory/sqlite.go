@@ -0,0 +1,636 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+)
+
+// ErrInsufficientStock is returned when a SKU cannot cover a reservation
+var ErrInsufficientStock = stderrors.New("insufficient stock")
+
+// ErrReservationNotFound is returned when releasing an unknown reservation
+var ErrReservationNotFound = stderrors.New("reservation not found")
+
+// ErrReleaseExceedsReserved is returned when releasing more of a SKU than a
+// reservation holds
+var ErrReleaseExceedsReserved = stderrors.New("release exceeds reserved quantity")
+
+// ErrReservationNotActive is returned when committing a reservation that was
+// released or expired
+var ErrReservationNotActive = stderrors.New("reservation not active")
+
+// ErrRestockExceedsCommitted is returned when restocking more of a SKU than
+// a reservation committed
+var ErrRestockExceedsCommitted = stderrors.New("restock exceeds committed quantity")
+
+// SQLiteInventoryManager keeps stock levels and reservations in SQLite.
+// Reserve, Release, Commit and Restock adjust stock atomically in a
+// transaction, and a background sweeper expires active reservations past
+// their ExpiresAt.
+type SQLiteInventoryManager struct {
+	db             *sql.DB
+	reservationTTL time.Duration
+	sweepTick      *time.Ticker
+	logger         *observability.Logger
+	done           chan struct{}
+	wg             sync.WaitGroup
+}
+
+// NewSQLiteInventoryManager opens the inventory database and starts the
+// reservation sweeper, which logs failed sweeps to logger (nil to discard
+// them). A zero sweepInterval disables the sweeper.
+func NewSQLiteInventoryManager(dbPath string, reservationTTL, sweepInterval time.Duration, logger *observability.Logger) (*SQLiteInventoryManager, error) {
+	db, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open inventory database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping inventory database: %w", err)
+	}
+
+	m := &SQLiteInventoryManager{
+		db:             db,
+		reservationTTL: reservationTTL,
+		logger:         logger,
+		done:           make(chan struct{}),
+	}
+
+	if err := m.initSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if sweepInterval > 0 {
+		m.sweepTick = time.NewTicker(sweepInterval)
+		m.wg.Add(1)
+		go m.sweepWorker()
+	}
+
+	return m, nil
+}
+
+// initSchema creates the stock and reservation tables
+func (m *SQLiteInventoryManager) initSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS inventory_stock (
+		sku TEXT PRIMARY KEY,
+		on_hand INTEGER NOT NULL CHECK (on_hand >= 0),
+		reserved INTEGER NOT NULL DEFAULT 0 CHECK (reserved >= 0),
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS inventory_reservations (
+		id TEXT PRIMARY KEY,
+		order_id TEXT NOT NULL,
+		status TEXT NOT NULL,
+		idempotency_key TEXT,
+		created_at DATETIME NOT NULL,
+		expires_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS inventory_reservation_items (
+		reservation_id TEXT NOT NULL,
+		sku TEXT NOT NULL,
+		quantity INTEGER NOT NULL,
+		PRIMARY KEY (reservation_id, sku)
+	);
+
+	-- Audit history of status changes, in order of seq
+	CREATE TABLE IF NOT EXISTS inventory_reservation_transitions (
+		reservation_id TEXT NOT NULL,
+		seq INTEGER NOT NULL,
+		from_status TEXT NOT NULL,
+		to_status TEXT NOT NULL,
+		reason TEXT NOT NULL DEFAULT '',
+		at DATETIME NOT NULL,
+		PRIMARY KEY (reservation_id, seq)
+	);
+
+	-- Sweeper scans active reservations by expiry
+	CREATE INDEX IF NOT EXISTS idx_reservations_status_expires
+		ON inventory_reservations(status, expires_at);
+	`
+
+	_, err := m.db.Exec(schema)
+	return err
+}
+
+// SetStock sets the on-hand quantity for a SKU, creating it if needed
+func (m *SQLiteInventoryManager) SetStock(ctx context.Context, sku string, onHand int32) error {
+	_, err := m.db.ExecContext(ctx, `
+		INSERT INTO inventory_stock (sku, on_hand, reserved, updated_at)
+		VALUES (?, ?, 0, ?)
+		ON CONFLICT(sku) DO UPDATE SET on_hand = excluded.on_hand, updated_at = excluded.updated_at
+	`, sku, onHand, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to set stock for %s: %w", sku, err)
+	}
+	return nil
+}
+
+// GetStock returns the on-hand and reserved quantities for a SKU
+func (m *SQLiteInventoryManager) GetStock(ctx context.Context, sku string) (onHand, reserved int32, err error) {
+	err = m.db.QueryRowContext(ctx,
+		"SELECT on_hand, reserved FROM inventory_stock WHERE sku = ?", sku,
+	).Scan(&onHand, &reserved)
+	if err == sql.ErrNoRows {
+		return 0, 0, nil
+	}
+	return onHand, reserved, err
+}
+
+// CheckAvailability returns the SKUs whose free stock cannot cover the items
+func (m *SQLiteInventoryManager) CheckAvailability(ctx context.Context, items []domain.OrderItem) ([]string, error) {
+	unavailable := []string{}
+	for _, item := range aggregateItems(items) {
+		var available int32
+		err := m.db.QueryRowContext(ctx,
+			"SELECT on_hand - reserved FROM inventory_stock WHERE sku = ?", item.SKU,
+		).Scan(&available)
+		if err == sql.ErrNoRows {
+			unavailable = append(unavailable, item.SKU)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to check stock for %s: %w", item.SKU, err)
+		}
+		if available < item.Quantity {
+			unavailable = append(unavailable, item.SKU)
+		}
+	}
+	return unavailable, nil
+}
+
+// Reserve reserves stock for every item or none of them. Reserving again
+// for an order with an active or committed reservation returns the existing
+// reservation.
+// The idempotency key is recorded with the reservation for auditing.
+func (m *SQLiteInventoryManager) Reserve(ctx context.Context, orderID, warehouse string, items []domain.OrderItem, idempotencyKey string) (string, error) {
+	reserved := aggregateItems(items)
+	res, err := domain.NewInventoryReservation(domain.ReservationID(orderID, warehouse), orderID, reserved)
+	if err != nil {
+		return "", err
+	}
+	res.CreatedAt = res.CreatedAt.UTC()
+	if m.reservationTTL > 0 {
+		res.ExpiresAt = res.CreatedAt.Add(m.reservationTTL)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin reservation: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, "SELECT status FROM inventory_reservations WHERE id = ?", res.ID).Scan(&status)
+	switch {
+	case err == nil && (domain.ReservationStatus(status) == domain.ReservationStatusActive ||
+		domain.ReservationStatus(status) == domain.ReservationStatusCommitted):
+		return res.ID, nil
+	case err == nil:
+		// A released, expired or restocked reservation is replaced by a fresh one
+		if _, err := tx.ExecContext(ctx, "DELETE FROM inventory_reservation_items WHERE reservation_id = ?", res.ID); err != nil {
+			return "", fmt.Errorf("failed to clear reservation items: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM inventory_reservation_transitions WHERE reservation_id = ?", res.ID); err != nil {
+			return "", fmt.Errorf("failed to clear reservation history: %w", err)
+		}
+	case err != sql.ErrNoRows:
+		return "", fmt.Errorf("failed to load reservation: %w", err)
+	}
+
+	for _, item := range reserved {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE inventory_stock SET reserved = reserved + ?, updated_at = ?
+			WHERE sku = ? AND on_hand - reserved >= ?
+		`, item.Quantity, res.CreatedAt, item.SKU, item.Quantity)
+		if err != nil {
+			return "", fmt.Errorf("failed to reserve %s: %w", item.SKU, err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return "", fmt.Errorf("%w: %s", ErrInsufficientStock, item.SKU)
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO inventory_reservation_items (reservation_id, sku, quantity) VALUES (?, ?, ?)",
+			res.ID, item.SKU, item.Quantity,
+		); err != nil {
+			return "", fmt.Errorf("failed to record reservation item %s: %w", item.SKU, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO inventory_reservations (id, order_id, status, idempotency_key, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET status = excluded.status, idempotency_key = excluded.idempotency_key,
+			created_at = excluded.created_at, expires_at = excluded.expires_at
+	`, res.ID, res.OrderID, string(res.Status), idempotencyKey, res.CreatedAt, res.ExpiresAt.UTC()); err != nil {
+		return "", fmt.Errorf("failed to record reservation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit reservation: %w", err)
+	}
+	return res.ID, nil
+}
+
+// Release returns a reservation's stock. Releasing a reservation that is no
+// longer active is a no-op, so compensations can safely be retried.
+func (m *SQLiteInventoryManager) Release(ctx context.Context, reservationID string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin release: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := loadReservation(ctx, tx, reservationID)
+	if err != nil {
+		return err
+	}
+	if res.Status != domain.ReservationStatusActive {
+		return nil
+	}
+
+	if err := res.MarkReleased(time.Now().UTC()); err != nil {
+		return err
+	}
+	if err := returnStock(ctx, tx, res.Items); err != nil {
+		return err
+	}
+	if err := saveStatus(ctx, tx, res); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReleaseItems returns part of a reservation's stock, such as the lines of
+// a partial return. The reservation keeps the remaining quantities and is
+// released once none are left. Releasing from a reservation that is no longer
+// active is a no-op.
+func (m *SQLiteInventoryManager) ReleaseItems(ctx context.Context, reservationID string, items []domain.OrderItem) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin release: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := loadReservation(ctx, tx, reservationID)
+	if err != nil {
+		return err
+	}
+	if res.Status != domain.ReservationStatusActive {
+		return nil
+	}
+
+	returned := aggregateItems(items)
+	if err := res.ReleaseItems(returned, time.Now().UTC()); err != nil {
+		return fmt.Errorf("%w: %v", ErrReleaseExceedsReserved, err)
+	}
+
+	if err := returnStock(ctx, tx, returned); err != nil {
+		return err
+	}
+	if err := saveStatus(ctx, tx, res); err != nil {
+		return err
+	}
+	// Rewrite the items still held so a later release returns only those
+	if err := saveItems(ctx, tx, res); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Commit takes a reservation's stock out of the warehouse once its order is
+// paid: on-hand and reserved quantities both drop and the reservation no
+// longer expires. Committing a committed reservation is a no-op; committing
+// one that was released or expired fails with ErrReservationNotActive.
+func (m *SQLiteInventoryManager) Commit(ctx context.Context, reservationID string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin commit: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := loadReservation(ctx, tx, reservationID)
+	if err != nil {
+		return err
+	}
+	switch res.Status {
+	case domain.ReservationStatusActive:
+	case domain.ReservationStatusCommitted, domain.ReservationStatusRestocked:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s is %s", ErrReservationNotActive, res.ID, res.Status)
+	}
+
+	now := time.Now().UTC()
+	if err := res.MarkCommitted(now); err != nil {
+		return err
+	}
+	for _, item := range res.Items {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE inventory_stock SET on_hand = on_hand - ?, reserved = MAX(reserved - ?, 0), updated_at = ? WHERE sku = ?",
+			item.Quantity, item.Quantity, now, item.SKU,
+		); err != nil {
+			return fmt.Errorf("failed to commit stock for %s: %w", item.SKU, err)
+		}
+	}
+	if err := saveStatus(ctx, tx, res); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Restock puts a committed reservation's stock back on hand after a refund or
+// cancellation: only the given quantities when items is set, otherwise
+// everything still committed. The reservation becomes restocked once nothing
+// remains. Restocking a reservation that is not committed is a no-op, so
+// compensations can safely be retried.
+func (m *SQLiteInventoryManager) Restock(ctx context.Context, reservationID string, items []domain.OrderItem) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin restock: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := loadReservation(ctx, tx, reservationID)
+	if err != nil {
+		return err
+	}
+	if res.Status != domain.ReservationStatusCommitted {
+		return nil
+	}
+
+	restocked := append([]domain.ReservedItem(nil), res.Items...)
+	if len(items) > 0 {
+		restocked = aggregateItems(items)
+	}
+	now := time.Now().UTC()
+	if err := res.RestockItems(restocked, now); err != nil {
+		return fmt.Errorf("%w: %v", ErrRestockExceedsCommitted, err)
+	}
+
+	for _, item := range restocked {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE inventory_stock SET on_hand = on_hand + ?, updated_at = ? WHERE sku = ?",
+			item.Quantity, now, item.SKU,
+		); err != nil {
+			return fmt.Errorf("failed to restock %s: %w", item.SKU, err)
+		}
+	}
+	if err := saveStatus(ctx, tx, res); err != nil {
+		return err
+	}
+	// Keep the committed items that remain so a later restock returns only those
+	if err := saveItems(ctx, tx, res); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CheckRestock returns ErrRestockExceedsCommitted if the reservation no
+// longer has items committed, such as lines already returned, without
+// changing anything
+func (m *SQLiteInventoryManager) CheckRestock(ctx context.Context, reservationID string, items []domain.OrderItem) error {
+	res, err := m.GetReservation(ctx, reservationID)
+	if err != nil {
+		return err
+	}
+	return checkRestock(res, aggregateItems(items))
+}
+
+// GetReservation loads a reservation and its items
+func (m *SQLiteInventoryManager) GetReservation(ctx context.Context, reservationID string) (*domain.InventoryReservation, error) {
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return loadReservation(ctx, tx, reservationID)
+}
+
+// ExpireReservations marks active reservations whose ExpiresAt is before now
+// as expired and returns their stock. It returns the number expired.
+func (m *SQLiteInventoryManager) ExpireReservations(ctx context.Context, now time.Time) (int, error) {
+	rows, err := m.db.QueryContext(ctx,
+		"SELECT id FROM inventory_reservations WHERE status = ? AND expires_at <= ?",
+		string(domain.ReservationStatusActive), now.UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query expired reservations: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	expired := 0
+	for _, id := range ids {
+		ok, err := m.expireReservation(ctx, id, now)
+		if err != nil {
+			return expired, err
+		}
+		if ok {
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// expireReservation expires a single reservation if it is still active and due
+func (m *SQLiteInventoryManager) expireReservation(ctx context.Context, id string, now time.Time) (bool, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin expiry: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := loadReservation(ctx, tx, id)
+	if err != nil {
+		return false, err
+	}
+	// Released between the scan and this transaction
+	if res.Status != domain.ReservationStatusActive || now.Before(res.ExpiresAt) {
+		return false, nil
+	}
+
+	if err := res.MarkExpired(now.UTC()); err != nil {
+		return false, err
+	}
+	if err := returnStock(ctx, tx, res.Items); err != nil {
+		return false, err
+	}
+	if err := saveStatus(ctx, tx, res); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// sweepWorker expires reservations on every tick until Close is called
+func (m *SQLiteInventoryManager) sweepWorker() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.sweepTick.C:
+			if _, err := m.ExpireReservations(context.Background(), time.Now()); err != nil && m.logger != nil {
+				m.logger.Error("reservation sweep failed", err)
+			}
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// Close stops the sweeper and closes the database
+func (m *SQLiteInventoryManager) Close() error {
+	close(m.done)
+	if m.sweepTick != nil {
+		m.sweepTick.Stop()
+	}
+	m.wg.Wait()
+	return m.db.Close()
+}
+
+// loadReservation reads a reservation and its items within a transaction
+func loadReservation(ctx context.Context, tx *sql.Tx, reservationID string) (*domain.InventoryReservation, error) {
+	res := &domain.InventoryReservation{ID: reservationID}
+	var status string
+	err := tx.QueryRowContext(ctx,
+		"SELECT order_id, status, created_at, expires_at FROM inventory_reservations WHERE id = ?", reservationID,
+	).Scan(&res.OrderID, &status, &res.CreatedAt, &res.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrReservationNotFound, reservationID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reservation: %w", err)
+	}
+	res.Status = domain.ReservationStatus(status)
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT sku, quantity FROM inventory_reservation_items WHERE reservation_id = ? ORDER BY sku", reservationID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reservation items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item domain.ReservedItem
+		if err := rows.Scan(&item.SKU, &item.Quantity); err != nil {
+			return nil, err
+		}
+		res.Items = append(res.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	history, err := tx.QueryContext(ctx,
+		"SELECT from_status, to_status, reason, at FROM inventory_reservation_transitions WHERE reservation_id = ? ORDER BY seq", reservationID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reservation history: %w", err)
+	}
+	defer history.Close()
+
+	for history.Next() {
+		var t domain.Transition
+		if err := history.Scan(&t.From, &t.To, &t.Reason, &t.At); err != nil {
+			return nil, err
+		}
+		res.History = append(res.History, t)
+	}
+	return res, history.Err()
+}
+
+// returnStock gives reserved quantities back to available stock
+func returnStock(ctx context.Context, tx *sql.Tx, items []domain.ReservedItem) error {
+	now := time.Now().UTC()
+	for _, item := range items {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE inventory_stock SET reserved = MAX(reserved - ?, 0), updated_at = ? WHERE sku = ?",
+			item.Quantity, now, item.SKU,
+		); err != nil {
+			return fmt.Errorf("failed to return stock for %s: %w", item.SKU, err)
+		}
+	}
+	return nil
+}
+
+// saveStatus persists a reservation's status and any history entries not
+// yet written
+func saveStatus(ctx context.Context, tx *sql.Tx, res *domain.InventoryReservation) error {
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE inventory_reservations SET status = ? WHERE id = ?", string(res.Status), res.ID,
+	); err != nil {
+		return fmt.Errorf("failed to update reservation status: %w", err)
+	}
+
+	for seq, t := range res.History {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT OR IGNORE INTO inventory_reservation_transitions (reservation_id, seq, from_status, to_status, reason, at) VALUES (?, ?, ?, ?, ?, ?)",
+			res.ID, seq, t.From, t.To, t.Reason, t.At.UTC(),
+		); err != nil {
+			return fmt.Errorf("failed to record reservation transition: %w", err)
+		}
+	}
+	return nil
+}
+
+// saveItems rewrites the items a reservation still holds
+func saveItems(ctx context.Context, tx *sql.Tx, res *domain.InventoryReservation) error {
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM inventory_reservation_items WHERE reservation_id = ?", res.ID,
+	); err != nil {
+		return fmt.Errorf("failed to update reservation items: %w", err)
+	}
+	for _, item := range res.Items {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO inventory_reservation_items (reservation_id, sku, quantity) VALUES (?, ?, ?)",
+			res.ID, item.SKU, item.Quantity,
+		); err != nil {
+			return fmt.Errorf("failed to update reservation items: %w", err)
+		}
+	}
+	return nil
+}
+
+// aggregateItems sums quantities per SKU in a stable order
+func aggregateItems(items []domain.OrderItem) []domain.ReservedItem {
+	totals := make(map[string]int32, len(items))
+	for _, item := range items {
+		totals[item.SKU] += item.Quantity
+	}
+
+	reserved := make([]domain.ReservedItem, 0, len(totals))
+	for sku, quantity := range totals {
+		reserved = append(reserved, domain.ReservedItem{SKU: sku, Quantity: quantity})
+	}
+	sort.Slice(reserved, func(i, j int) bool { return reserved[i].SKU < reserved[j].SKU })
+	return reserved
+}
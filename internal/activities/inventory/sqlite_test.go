@@ -0,0 +1,285 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+func newTestManager(t *testing.T, ttl time.Duration) *SQLiteInventoryManager {
+	m, err := NewSQLiteInventoryManager(t.TempDir()+"/inventory.db", ttl, 0, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { m.Close() })
+
+	ctx := context.Background()
+	require.NoError(t, m.SetStock(ctx, "ITEM-001", 10))
+	require.NoError(t, m.SetStock(ctx, "ITEM-002", 1))
+	return m
+}
+
+func TestSQLiteInventoryManager_ReserveAndRelease(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(t, time.Hour)
+
+	items := []domain.OrderItem{
+		{SKU: "ITEM-001", Quantity: 2},
+		{SKU: "ITEM-001", Quantity: 1},
+		{SKU: "ITEM-002", Quantity: 1},
+	}
+	id, err := m.Reserve(ctx, "ORD-1", "", items, "key-1")
+	require.NoError(t, err)
+
+	onHand, reserved, err := m.GetStock(ctx, "ITEM-001")
+	require.NoError(t, err)
+	assert.Equal(t, int32(10), onHand)
+	assert.Equal(t, int32(3), reserved)
+
+	// Reserving again for the same order does not double-count
+	again, err := m.Reserve(ctx, "ORD-1", "", items, "key-1")
+	require.NoError(t, err)
+	assert.Equal(t, id, again)
+	_, reserved, _ = m.GetStock(ctx, "ITEM-001")
+	assert.Equal(t, int32(3), reserved)
+
+	require.NoError(t, m.Release(ctx, id))
+	require.NoError(t, m.Release(ctx, id), "release is idempotent")
+
+	_, reserved, _ = m.GetStock(ctx, "ITEM-001")
+	assert.Equal(t, int32(0), reserved)
+
+	res, err := m.GetReservation(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ReservationStatusReleased, res.Status)
+	assert.Len(t, res.Items, 2)
+
+	// The transition is persisted once, however often release is retried
+	require.Len(t, res.History, 1)
+	assert.Equal(t, string(domain.ReservationStatusActive), res.History[0].From)
+	assert.Equal(t, string(domain.ReservationStatusReleased), res.History[0].To)
+
+	assert.ErrorIs(t, m.Release(ctx, "RES_missing"), ErrReservationNotFound)
+}
+
+func TestSQLiteInventoryManager_ReservesPerWarehouse(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(t, time.Hour)
+
+	def, err := m.Reserve(ctx, "ORD-1", "", []domain.OrderItem{{SKU: "ITEM-001", Quantity: 2}}, "key-1")
+	require.NoError(t, err)
+	east, err := m.Reserve(ctx, "ORD-1", "east", []domain.OrderItem{{SKU: "ITEM-001", Quantity: 3}}, "key-2")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ReservationID("ORD-1", ""), def)
+	assert.Equal(t, domain.ReservationID("ORD-1", "east"), east)
+
+	_, reserved, _ := m.GetStock(ctx, "ITEM-001")
+	assert.Equal(t, int32(5), reserved)
+
+	// Releasing one warehouse leaves the other held
+	require.NoError(t, m.Release(ctx, east))
+	_, reserved, _ = m.GetStock(ctx, "ITEM-001")
+	assert.Equal(t, int32(2), reserved)
+}
+
+func TestSQLiteInventoryManager_ReleaseItems(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(t, time.Hour)
+
+	id, err := m.Reserve(ctx, "ORD-1", "", []domain.OrderItem{
+		{SKU: "ITEM-001", Quantity: 3},
+		{SKU: "ITEM-002", Quantity: 1},
+	}, "key-1")
+	require.NoError(t, err)
+
+	// Returning one ITEM-001 leaves the rest held
+	require.NoError(t, m.ReleaseItems(ctx, id, []domain.OrderItem{{SKU: "ITEM-001", Quantity: 1}}))
+	_, reserved, _ := m.GetStock(ctx, "ITEM-001")
+	assert.Equal(t, int32(2), reserved)
+
+	res, err := m.GetReservation(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ReservationStatusActive, res.Status)
+	assert.Equal(t, []domain.ReservedItem{{SKU: "ITEM-001", Quantity: 2}, {SKU: "ITEM-002", Quantity: 1}}, res.Items)
+
+	// Releasing more than is held is rejected without touching stock
+	err = m.ReleaseItems(ctx, id, []domain.OrderItem{{SKU: "ITEM-002", Quantity: 2}})
+	assert.ErrorIs(t, err, ErrReleaseExceedsReserved)
+	_, reserved, _ = m.GetStock(ctx, "ITEM-002")
+	assert.Equal(t, int32(1), reserved)
+
+	// Returning everything left releases the reservation
+	require.NoError(t, m.ReleaseItems(ctx, id, []domain.OrderItem{
+		{SKU: "ITEM-001", Quantity: 2},
+		{SKU: "ITEM-002", Quantity: 1},
+	}))
+	res, err = m.GetReservation(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ReservationStatusReleased, res.Status)
+	_, reserved, _ = m.GetStock(ctx, "ITEM-001")
+	assert.Equal(t, int32(0), reserved)
+}
+
+func TestSQLiteInventoryManager_InsufficientStockIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(t, time.Hour)
+
+	_, err := m.Reserve(ctx, "ORD-1", "", []domain.OrderItem{
+		{SKU: "ITEM-001", Quantity: 5},
+		{SKU: "ITEM-002", Quantity: 2},
+	}, "key-1")
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+
+	// The ITEM-001 reservation was rolled back with the failed ITEM-002
+	_, reserved, _ := m.GetStock(ctx, "ITEM-001")
+	assert.Equal(t, int32(0), reserved)
+
+	unavailable, err := m.CheckAvailability(ctx, []domain.OrderItem{
+		{SKU: "ITEM-001", Quantity: 5},
+		{SKU: "ITEM-002", Quantity: 2},
+		{SKU: "ITEM-UNKNOWN", Quantity: 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ITEM-002", "ITEM-UNKNOWN"}, unavailable)
+}
+
+func TestSQLiteInventoryManager_ExpireReservations(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(t, time.Minute)
+
+	id, err := m.Reserve(ctx, "ORD-1", "", []domain.OrderItem{{SKU: "ITEM-002", Quantity: 1}}, "key-1")
+	require.NoError(t, err)
+
+	expired, err := m.ExpireReservations(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, expired)
+
+	expired, err = m.ExpireReservations(ctx, time.Now().Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+
+	res, err := m.GetReservation(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ReservationStatusExpired, res.Status)
+
+	_, reserved, _ := m.GetStock(ctx, "ITEM-002")
+	assert.Equal(t, int32(0), reserved)
+
+	// An expired reservation is replaced when the order reserves again
+	_, err = m.Reserve(ctx, "ORD-1", "", []domain.OrderItem{{SKU: "ITEM-002", Quantity: 1}}, "key-1")
+	require.NoError(t, err)
+	_, reserved, _ = m.GetStock(ctx, "ITEM-002")
+	assert.Equal(t, int32(1), reserved)
+}
+
+func TestSQLiteInventoryManager_CommitAndRestock(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(t, time.Minute)
+
+	id, err := m.Reserve(ctx, "ORD-1", "", []domain.OrderItem{
+		{SKU: "ITEM-001", Quantity: 3},
+		{SKU: "ITEM-002", Quantity: 1},
+	}, "key-1")
+	require.NoError(t, err)
+
+	// Committing takes the stock out of the warehouse
+	require.NoError(t, m.Commit(ctx, id))
+	require.NoError(t, m.Commit(ctx, id), "commit is idempotent")
+	onHand, reserved, _ := m.GetStock(ctx, "ITEM-001")
+	assert.Equal(t, int32(7), onHand)
+	assert.Equal(t, int32(0), reserved)
+
+	// Committed stock is exempt from the sweeper and from release
+	expired, err := m.ExpireReservations(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, expired)
+	require.NoError(t, m.Release(ctx, id))
+	onHand, reserved, _ = m.GetStock(ctx, "ITEM-001")
+	assert.Equal(t, int32(7), onHand)
+	assert.Equal(t, int32(0), reserved)
+
+	// Reserving again for the order keeps the committed reservation
+	again, err := m.Reserve(ctx, "ORD-1", "", []domain.OrderItem{{SKU: "ITEM-001", Quantity: 3}}, "key-1")
+	require.NoError(t, err)
+	assert.Equal(t, id, again)
+	_, reserved, _ = m.GetStock(ctx, "ITEM-001")
+	assert.Equal(t, int32(0), reserved)
+
+	// A returned line is restocked on its own
+	require.NoError(t, m.Restock(ctx, id, []domain.OrderItem{{SKU: "ITEM-001", Quantity: 1}}))
+	onHand, _, _ = m.GetStock(ctx, "ITEM-001")
+	assert.Equal(t, int32(8), onHand)
+	assert.ErrorIs(t, m.Restock(ctx, id, []domain.OrderItem{{SKU: "ITEM-002", Quantity: 2}}), ErrRestockExceedsCommitted)
+
+	// Checking a restock sees what is still committed and changes nothing
+	require.NoError(t, m.CheckRestock(ctx, id, []domain.OrderItem{{SKU: "ITEM-001", Quantity: 2}}))
+	assert.ErrorIs(t, m.CheckRestock(ctx, id, []domain.OrderItem{{SKU: "ITEM-001", Quantity: 3}}), ErrRestockExceedsCommitted)
+	onHand, _, _ = m.GetStock(ctx, "ITEM-001")
+	assert.Equal(t, int32(8), onHand)
+
+	// Restocking the rest returns everything still committed
+	require.NoError(t, m.Restock(ctx, id, nil))
+	require.NoError(t, m.Restock(ctx, id, nil), "restock is idempotent")
+	onHand, _, _ = m.GetStock(ctx, "ITEM-001")
+	assert.Equal(t, int32(10), onHand)
+	onHand, _, _ = m.GetStock(ctx, "ITEM-002")
+	assert.Equal(t, int32(1), onHand)
+
+	res, err := m.GetReservation(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ReservationStatusRestocked, res.Status)
+	assert.ErrorIs(t, m.CheckRestock(ctx, id, []domain.OrderItem{{SKU: "ITEM-001", Quantity: 1}}), ErrRestockExceedsCommitted)
+	require.Len(t, res.History, 2)
+	assert.Equal(t, string(domain.ReservationStatusCommitted), res.History[0].To)
+	assert.Equal(t, string(domain.ReservationStatusRestocked), res.History[1].To)
+}
+
+func TestSQLiteInventoryManager_CommitRequiresActiveReservation(t *testing.T) {
+	ctx := context.Background()
+	m := newTestManager(t, time.Minute)
+
+	id, err := m.Reserve(ctx, "ORD-1", "", []domain.OrderItem{{SKU: "ITEM-002", Quantity: 1}}, "key-1")
+	require.NoError(t, err)
+	_, err = m.ExpireReservations(ctx, time.Now().Add(2*time.Minute))
+	require.NoError(t, err)
+
+	// Expired stock went back to available, so it cannot be committed
+	assert.ErrorIs(t, m.Commit(ctx, id), ErrReservationNotActive)
+	onHand, _, _ := m.GetStock(ctx, "ITEM-002")
+	assert.Equal(t, int32(1), onHand)
+
+	commit := CommitInventoryActivity(m)
+	input, _ := json.Marshal(CommitInventoryInput{ReservationID: id})
+	_, err = commit(ctx, input)
+	require.Error(t, err)
+	assert.Equal(t, "RESERVATION_NOT_ACTIVE", errors.CodeOf(err))
+}
+
+func TestCheckAvailabilityActivity_ReportsUnavailableItems(t *testing.T) {
+	m := newTestManager(t, time.Hour)
+	activity := CheckAvailabilityActivity(m)
+
+	input, _ := json.Marshal(CheckAvailabilityInput{Items: []domain.OrderItem{
+		{SKU: "ITEM-001", Quantity: 1},
+		{SKU: "ITEM-002", Quantity: 3},
+	}})
+	result, err := activity(context.Background(), input)
+	require.NoError(t, err)
+
+	var output CheckAvailabilityOutput
+	require.NoError(t, json.Unmarshal(result, &output))
+	assert.False(t, output.Available)
+	assert.Equal(t, []string{"ITEM-002"}, output.UnavailableItems)
+
+	// Reserving past stock is a permanent INSUFFICIENT_STOCK failure
+	reserve := ReserveInventoryActivity(m)
+	input, _ = json.Marshal(ReserveInventoryInput{OrderID: "ORD-2", Items: []domain.OrderItem{{SKU: "ITEM-002", Quantity: 3}}})
+	_, err = reserve(context.Background(), input)
+	require.Error(t, err)
+	assert.Equal(t, "INSUFFICIENT_STOCK", errors.CodeOf(err))
+}
@@ -0,0 +1,195 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// Built-in channel names, used in notification routes and outbox messages
+const (
+	ChannelEmail   = "email"
+	ChannelSMS     = "sms"
+	ChannelWebhook = "webhook"
+)
+
+// Contact is how a customer can be reached
+type Contact struct {
+	Email string
+	Phone string
+}
+
+// Channel delivers rendered notifications over one medium
+type Channel interface {
+	// Name is the channel's name in routes, template file names and outbox
+	// messages
+	Name() string
+	// Recipient returns where the channel delivers to contact, or "" when it
+	// cannot reach them
+	Recipient(contact Contact) string
+	// Send delivers an outbox message and returns the provider's message ID
+	Send(ctx context.Context, msg *OutboxMessage) (string, error)
+}
+
+// Email is a message for an EmailService; HTML is optional
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// EmailService sends email notifications
+type EmailService interface {
+	SendEmail(ctx context.Context, email Email) (string, error)
+}
+
+// SMSService sends text messages
+type SMSService interface {
+	SendSMS(ctx context.Context, to, body string) (string, error)
+}
+
+// EmailChannel delivers notifications through an EmailService
+type EmailChannel struct {
+	service EmailService
+}
+
+// NewEmailChannel creates an email channel
+func NewEmailChannel(service EmailService) *EmailChannel {
+	return &EmailChannel{service: service}
+}
+
+// Name returns "email"
+func (c *EmailChannel) Name() string {
+	return ChannelEmail
+}
+
+// Recipient returns the customer's email address
+func (c *EmailChannel) Recipient(contact Contact) string {
+	return contact.Email
+}
+
+// Send emails the message, with its HTML body when it has one
+func (c *EmailChannel) Send(ctx context.Context, msg *OutboxMessage) (string, error) {
+	return c.service.SendEmail(ctx, Email{
+		To:      msg.Recipient,
+		Subject: msg.Subject,
+		Text:    msg.Body,
+		HTML:    msg.HTMLBody,
+	})
+}
+
+// SMSChannel delivers notifications through an SMSService
+type SMSChannel struct {
+	service SMSService
+}
+
+// NewSMSChannel creates an SMS channel
+func NewSMSChannel(service SMSService) *SMSChannel {
+	return &SMSChannel{service: service}
+}
+
+// Name returns "sms"
+func (c *SMSChannel) Name() string {
+	return ChannelSMS
+}
+
+// Recipient returns the customer's phone number
+func (c *SMSChannel) Recipient(contact Contact) string {
+	return contact.Phone
+}
+
+// Send texts the message body
+func (c *SMSChannel) Send(ctx context.Context, msg *OutboxMessage) (string, error) {
+	return c.service.SendSMS(ctx, msg.Recipient, msg.Body)
+}
+
+// WebhookPayload is the JSON body a WebhookChannel posts
+type WebhookPayload struct {
+	OrderID   string `json:"order_id"`
+	EventType string `json:"event_type"`
+	Subject   string `json:"subject,omitempty"`
+	Body      string `json:"body"`
+}
+
+// WebhookChannel posts notifications as JSON to a fixed URL, e.g. a
+// merchant's order feed. Every request carries an Idempotency-Key header
+// derived from the outbox key, so the receiver can drop redeliveries.
+// Failures are CustomErrors: 4xx replies are permanent, while 408, 429, 5xx
+// replies and network errors are transient.
+type WebhookChannel struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookChannel creates a webhook channel posting to url
+func NewWebhookChannel(url string, timeout time.Duration) *WebhookChannel {
+	return &WebhookChannel{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name returns "webhook"
+func (c *WebhookChannel) Name() string {
+	return ChannelWebhook
+}
+
+// Recipient returns the webhook URL; it does not depend on the customer
+func (c *WebhookChannel) Recipient(contact Contact) string {
+	return c.url
+}
+
+// Send posts the message and returns its idempotency key as the message ID
+func (c *WebhookChannel) Send(ctx context.Context, msg *OutboxMessage) (string, error) {
+	payload, err := json.Marshal(WebhookPayload{
+		OrderID:   msg.OrderID,
+		EventType: msg.EventType,
+		Subject:   msg.Subject,
+		Body:      msg.Body,
+	})
+	if err != nil {
+		return "", errors.NewPermanentError("INVALID_WEBHOOK_PAYLOAD", "failed to marshal webhook payload", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, msg.Recipient, bytes.NewReader(payload))
+	if err != nil {
+		return "", errors.NewPermanentError("INVALID_WEBHOOK_REQUEST", "failed to create webhook request", err)
+	}
+	key := msg.Key().String()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", errors.NewTransientError("WEBHOOK_UNAVAILABLE", "webhook request failed", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", classifyWebhookStatus(resp)
+	}
+	return key, nil
+}
+
+// classifyWebhookStatus maps a non-2xx webhook reply to a CustomError.
+// Timeouts, rate limiting and server errors may succeed later; any other 4xx
+// reply will not.
+func classifyWebhookStatus(resp *http.Response) error {
+	msg := fmt.Sprintf("webhook returned %s", resp.Status)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return errors.NewTransientError("WEBHOOK_RATE_LIMITED", msg, nil)
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= 500:
+		return errors.NewTransientError("WEBHOOK_UNAVAILABLE", msg, nil)
+	default:
+		return errors.NewPermanentError("WEBHOOK_REJECTED", msg, nil)
+	}
+}
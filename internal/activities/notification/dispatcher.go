@@ -0,0 +1,170 @@
+package notification
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// Dispatcher defaults used when DispatcherConfig leaves a field zero
+const (
+	DefaultDispatchBatch = 50
+	DefaultClaimLease    = time.Minute
+	maxDispatchBackoff   = time.Hour
+)
+
+// DispatcherConfig controls how a Dispatcher delivers outbox messages
+type DispatcherConfig struct {
+	Interval     time.Duration // How often the outbox is polled; zero disables the background loop
+	MaxAttempts  int           // Attempts before a message is marked failed
+	RetryBackoff time.Duration // Delay before the first retry, doubling after each
+	BatchSize    int           // Messages claimed per poll
+	ClaimLease   time.Duration // How long a claim lasts before another poll may retry it
+}
+
+// Dispatcher delivers outbox messages on their channels. Every message is
+// claimed before it is sent, so concurrent dispatchers never send the same
+// message at once; a claim that outlives its lease, e.g. after a crash, is
+// retried.
+type Dispatcher struct {
+	outbox   Outbox
+	notifier *Notifier
+	cfg      DispatcherConfig
+	logger   *observability.Logger
+	tick     *time.Ticker
+	done     chan struct{}
+	wg       sync.WaitGroup
+	stopped  sync.Once
+}
+
+// NewDispatcher creates a dispatcher; Start begins background delivery
+func NewDispatcher(outbox Outbox, notifier *Notifier, cfg DispatcherConfig, logger *observability.Logger) *Dispatcher {
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultDispatchBatch
+	}
+	if cfg.ClaimLease <= 0 {
+		cfg.ClaimLease = DefaultClaimLease
+	}
+	return &Dispatcher{
+		outbox:   outbox,
+		notifier: notifier,
+		cfg:      cfg,
+		logger:   logger,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start delivers due messages on every Interval until Close is called
+func (d *Dispatcher) Start() {
+	if d.cfg.Interval <= 0 {
+		return
+	}
+	d.tick = time.NewTicker(d.cfg.Interval)
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for {
+			select {
+			case <-d.tick.C:
+				if _, err := d.DispatchDue(context.Background(), time.Now()); err != nil && d.logger != nil {
+					d.logger.Error("outbox dispatch failed", err)
+				}
+			case <-d.done:
+				return
+			}
+		}
+	}()
+}
+
+// DispatchDue claims and sends the messages due at now and returns how many
+// were sent
+func (d *Dispatcher) DispatchDue(ctx context.Context, now time.Time) (int, error) {
+	claimed, err := d.outbox.ClaimDue(ctx, now, d.cfg.BatchSize, d.cfg.ClaimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, msg := range claimed {
+		var messageID string
+		var sendErr error
+		retryable := true
+		if ch, ok := d.notifier.Channel(msg.Channel); ok {
+			messageID, sendErr = ch.Send(ctx, msg)
+			// Permanent errors, e.g. an SMTP 5xx reply, will not succeed later
+			var custom *errors.CustomError
+			retryable = !stderrors.As(sendErr, &custom) || !custom.IsPermanent()
+		} else {
+			sendErr = fmt.Errorf("unknown channel %q", msg.Channel)
+			retryable = false
+		}
+
+		if sendErr == nil {
+			err := d.outbox.MarkSent(ctx, msg.Key(), msg.Attempts, messageID, time.Now())
+			if stderrors.Is(err, ErrOutboxClaimLost) {
+				d.claimLost(msg, err)
+				continue
+			}
+			if err != nil {
+				return sent, err
+			}
+			sent++
+			continue
+		}
+
+		// Attempts already counts this one
+		var retryAt time.Time
+		if retryable && msg.Attempts < d.cfg.MaxAttempts {
+			retryAt = now.Add(d.backoff(msg.Attempts))
+		} else if d.logger != nil {
+			d.logger.Logger.Error().Err(sendErr).Str("order_id", msg.OrderID).Str("event_type", msg.EventType).
+				Str("channel", msg.Channel).Int("attempts", msg.Attempts).Msg("notification delivery failed")
+		}
+		err := d.outbox.MarkFailed(ctx, msg.Key(), msg.Attempts, sendErr.Error(), retryAt)
+		if stderrors.Is(err, ErrOutboxClaimLost) {
+			d.claimLost(msg, err)
+			continue
+		}
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// claimLost logs an attempt whose claim expired before it was settled; the
+// dispatcher that took the message over records the outcome instead
+func (d *Dispatcher) claimLost(msg *OutboxMessage, err error) {
+	if d.logger != nil {
+		d.logger.Logger.Warn().Err(err).Str("order_id", msg.OrderID).Str("event_type", msg.EventType).
+			Str("channel", msg.Channel).Int("attempts", msg.Attempts).Msg("notification claim lost")
+	}
+}
+
+// backoff returns the delay after the given failed attempt
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.RetryBackoff
+	for i := 1; i < attempt && delay < maxDispatchBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDispatchBackoff)
+}
+
+// Close stops background delivery
+func (d *Dispatcher) Close() {
+	d.stopped.Do(func() {
+		close(d.done)
+		if d.tick != nil {
+			d.tick.Stop()
+		}
+		d.wg.Wait()
+	})
+}
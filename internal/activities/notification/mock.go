@@ -3,7 +3,9 @@ package notification
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 )
 
 // MockEmailService is a mock implementation of EmailService for testing
@@ -11,6 +13,7 @@ type MockEmailService struct {
 	mu       sync.RWMutex
 	messages map[string]*EmailMessage
 	counter  int
+	sendErr  error
 }
 
 // EmailMessage represents a sent email
@@ -19,6 +22,7 @@ type EmailMessage struct {
 	To      string
 	Subject string
 	Body    string
+	HTML    string
 }
 
 // NewMockEmailService creates a new mock email service
@@ -29,23 +33,35 @@ func NewMockEmailService() *MockEmailService {
 }
 
 // SendEmail simulates sending an email
-func (m *MockEmailService) SendEmail(ctx context.Context, to, subject, body string) (string, error) {
+func (m *MockEmailService) SendEmail(ctx context.Context, email Email) (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.sendErr != nil {
+		return "", m.sendErr
+	}
+
 	m.counter++
 	messageID := fmt.Sprintf("MSG_%d", m.counter)
 
 	m.messages[messageID] = &EmailMessage{
 		ID:      messageID,
-		To:      to,
-		Subject: subject,
-		Body:    body,
+		To:      email.To,
+		Subject: email.Subject,
+		Body:    email.Text,
+		HTML:    email.HTML,
 	}
 
 	return messageID, nil
 }
 
+// SetSendError makes every subsequent SendEmail fail with err (nil to reset)
+func (m *MockEmailService) SetSendError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sendErr = err
+}
+
 // GetMessage retrieves a sent message
 func (m *MockEmailService) GetMessage(messageID string) (*EmailMessage, bool) {
 	m.mu.RLock()
@@ -66,3 +82,208 @@ func (m *MockEmailService) GetAllMessages() []*EmailMessage {
 	}
 	return messages
 }
+
+// MockSMSService is a mock implementation of SMSService for testing
+type MockSMSService struct {
+	mu       sync.RWMutex
+	messages []*SMSMessage
+	sendErr  error
+}
+
+// SMSMessage represents a sent text message
+type SMSMessage struct {
+	ID   string
+	To   string
+	Body string
+}
+
+// NewMockSMSService creates a new mock SMS service
+func NewMockSMSService() *MockSMSService {
+	return &MockSMSService{}
+}
+
+// SendSMS simulates sending a text message
+func (m *MockSMSService) SendSMS(ctx context.Context, to, body string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sendErr != nil {
+		return "", m.sendErr
+	}
+
+	msg := &SMSMessage{
+		ID:   fmt.Sprintf("SMS_%d", len(m.messages)+1),
+		To:   to,
+		Body: body,
+	}
+	m.messages = append(m.messages, msg)
+	return msg.ID, nil
+}
+
+// SetSendError makes every subsequent SendSMS fail with err (nil to reset)
+func (m *MockSMSService) SetSendError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sendErr = err
+}
+
+// GetAllMessages returns all sent text messages, oldest first
+func (m *MockSMSService) GetAllMessages() []*SMSMessage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]*SMSMessage(nil), m.messages...)
+}
+
+// MockOutbox is an in-memory implementation of Outbox for testing
+type MockOutbox struct {
+	mu         sync.Mutex
+	messages   map[OutboxKey]*OutboxMessage
+	enqueueErr error
+}
+
+// NewMockOutbox creates a new mock outbox
+func NewMockOutbox() *MockOutbox {
+	return &MockOutbox{
+		messages: make(map[OutboxKey]*OutboxMessage),
+	}
+}
+
+// Enqueue records a pending message unless one with the same key exists
+func (m *MockOutbox) Enqueue(ctx context.Context, msg OutboxMessage) (*OutboxMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.enqueueErr != nil {
+		return nil, m.enqueueErr
+	}
+
+	key := msg.Key()
+	if existing, ok := m.messages[key]; ok {
+		stored := *existing
+		return &stored, nil
+	}
+
+	now := time.Now().UTC()
+	stored := OutboxMessage{
+		OrderID:       msg.OrderID,
+		EventType:     msg.EventType,
+		Channel:       msg.Channel,
+		Recipient:     msg.Recipient,
+		Subject:       msg.Subject,
+		Body:          msg.Body,
+		HTMLBody:      msg.HTMLBody,
+		Status:        OutboxStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	m.messages[key] = &stored
+	result := stored
+	return &result, nil
+}
+
+// ClaimDue claims due messages for sending
+func (m *MockOutbox) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*OutboxMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due []*OutboxMessage
+	for _, msg := range m.messages {
+		if (msg.Status == OutboxStatusPending || msg.Status == OutboxStatusSending) && !msg.NextAttemptAt.After(now) {
+			due = append(due, msg)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*OutboxMessage, len(due))
+	for i, msg := range due {
+		msg.Status = OutboxStatusSending
+		msg.Attempts++
+		msg.NextAttemptAt = now.Add(lease)
+		result := *msg
+		claimed[i] = &result
+	}
+	return claimed, nil
+}
+
+// MarkSent records a message delivered under the claim for attempt
+func (m *MockOutbox) MarkSent(ctx context.Context, key OutboxKey, attempt int, messageID string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg, err := m.claimed(key, attempt)
+	if err != nil {
+		return err
+	}
+	msg.Status = OutboxStatusSent
+	msg.MessageID = messageID
+	msg.SentAt = at.UTC()
+	msg.LastError = ""
+	return nil
+}
+
+// MarkFailed records the failure of the claim for attempt, scheduling a
+// retry unless retryAt is zero
+func (m *MockOutbox) MarkFailed(ctx context.Context, key OutboxKey, attempt int, lastError string, retryAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg, err := m.claimed(key, attempt)
+	if err != nil {
+		return err
+	}
+	msg.LastError = lastError
+	if retryAt.IsZero() {
+		msg.Status = OutboxStatusFailed
+		return nil
+	}
+	msg.Status = OutboxStatusPending
+	msg.NextAttemptAt = retryAt.UTC()
+	return nil
+}
+
+// claimed returns the message if it is still sending under the claim for
+// attempt; the caller holds the lock
+func (m *MockOutbox) claimed(key OutboxKey, attempt int) (*OutboxMessage, error) {
+	msg, ok := m.messages[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrOutboxMessageNotFound, key)
+	}
+	if msg.Status != OutboxStatusSending || msg.Attempts != attempt {
+		return nil, fmt.Errorf("%w: %s attempt %d", ErrOutboxClaimLost, key, attempt)
+	}
+	return msg, nil
+}
+
+// ListByOrder returns an order's messages, oldest first
+func (m *MockOutbox) ListByOrder(ctx context.Context, orderID string) ([]*OutboxMessage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	messages := []*OutboxMessage{}
+	for _, msg := range m.messages {
+		if msg.OrderID == orderID {
+			result := *msg
+			messages = append(messages, &result)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		if !messages[i].CreatedAt.Equal(messages[j].CreatedAt) {
+			return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+		}
+		if messages[i].EventType != messages[j].EventType {
+			return messages[i].EventType < messages[j].EventType
+		}
+		return messages[i].Channel < messages[j].Channel
+	})
+	return messages, nil
+}
+
+// SetEnqueueError makes every subsequent Enqueue fail with err (nil to reset)
+func (m *MockOutbox) SetEnqueueError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enqueueErr = err
+}
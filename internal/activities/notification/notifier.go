@@ -0,0 +1,105 @@
+package notification
+
+import (
+	stderrors "errors"
+	"fmt"
+)
+
+// ErrNoRecipient is returned when none of an event's channels can reach the
+// customer
+var ErrNoRecipient = stderrors.New("customer cannot be reached on any channel")
+
+// DefaultRoute is the channels of events without a route
+var DefaultRoute = []string{ChannelEmail}
+
+// Notifier renders notifications from templates and fans each event out to
+// the channels it is routed to
+type Notifier struct {
+	templates *Templates
+	channels  map[string]Channel
+	routes    map[string][]string
+}
+
+// NewNotifier creates a notifier. Routes map template names (event types) to
+// channel names; events without a route use DefaultRoute. Every routed
+// channel must be one of channels.
+func NewNotifier(templates *Templates, routes map[string][]string, channels ...Channel) (*Notifier, error) {
+	n := &Notifier{
+		templates: templates,
+		channels:  make(map[string]Channel, len(channels)),
+		routes:    routes,
+	}
+	for _, ch := range channels {
+		n.channels[ch.Name()] = ch
+	}
+
+	for event, names := range routes {
+		for _, name := range names {
+			if _, ok := n.channels[name]; !ok {
+				return nil, fmt.Errorf("route for %s uses unknown channel %q", event, name)
+			}
+		}
+	}
+	return n, nil
+}
+
+// Channel returns the named channel
+func (n *Notifier) Channel(name string) (Channel, bool) {
+	ch, ok := n.channels[name]
+	return ch, ok
+}
+
+// Route returns the channel names an event type is delivered on
+func (n *Notifier) Route(eventType string) []string {
+	if names, ok := n.routes[TemplateName(eventType)]; ok {
+		return names
+	}
+	return DefaultRoute
+}
+
+// Compose renders a notification for every channel the event is routed to
+// that can reach the customer, ready to be queued in the outbox
+func (n *Notifier) Compose(inp NotificationInput) ([]OutboxMessage, error) {
+	contact := Contact{Email: inp.CustomerEmail, Phone: inp.CustomerPhone}
+	data := TemplateData{
+		OrderID:   inp.OrderID,
+		EventType: inp.EventType,
+		Items:     inp.Items,
+		Total:     inp.Total,
+		PaymentID: inp.PaymentID,
+		RefundID:  inp.RefundID,
+		Amount:    inp.Amount,
+		Reason:    inp.Reason,
+	}
+
+	var messages []OutboxMessage
+	for _, name := range n.Route(inp.EventType) {
+		ch, ok := n.channels[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown channel %q", name)
+		}
+		recipient := ch.Recipient(contact)
+		if recipient == "" {
+			continue
+		}
+
+		content, err := n.templates.Render(TemplateName(inp.EventType), name, inp.Locale, data)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, OutboxMessage{
+			OrderID:   inp.OrderID,
+			EventType: inp.EventType,
+			Channel:   name,
+			Recipient: recipient,
+			Subject:   content.Subject,
+			Body:      content.Body,
+			HTMLBody:  content.HTML,
+		})
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("%w: %s for order %s", ErrNoRecipient, inp.EventType, inp.OrderID)
+	}
+	return messages, nil
+}
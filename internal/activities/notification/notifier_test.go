@@ -0,0 +1,127 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+func newTestNotifier(t *testing.T, routes map[string][]string, channels ...Channel) *Notifier {
+	t.Helper()
+	templates, err := LoadTemplates(BuiltinTemplates(), "en")
+	require.NoError(t, err)
+	notifier, err := NewNotifier(templates, routes, channels...)
+	require.NoError(t, err)
+	return notifier
+}
+
+func TestNotifierCompose(t *testing.T) {
+	email := NewEmailChannel(NewMockEmailService())
+	sms := NewSMSChannel(NewMockSMSService())
+	webhook := NewWebhookChannel("http://orders.example.com/hook", 0)
+	notifier := newTestNotifier(t, map[string][]string{
+		EventOrderConfirmed: {ChannelEmail, ChannelSMS, ChannelWebhook},
+	}, email, sms, webhook)
+
+	inp := NotificationInput{
+		CustomerEmail: "customer@example.com",
+		CustomerPhone: "+15550100",
+		Locale:        "fr-FR",
+		OrderID:       "ORD-1",
+		EventType:     EventOrderConfirmed,
+		Total:         decimal.RequireFromString("25.50"),
+	}
+	messages, err := notifier.Compose(inp)
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+	assert.Equal(t, ChannelEmail, messages[0].Channel)
+	assert.Equal(t, "customer@example.com", messages[0].Recipient)
+	assert.Equal(t, "Commande ORD-1 confirmée", messages[0].Subject)
+	assert.NotEmpty(t, messages[0].HTMLBody)
+	assert.Equal(t, ChannelSMS, messages[1].Channel)
+	assert.Equal(t, "+15550100", messages[1].Recipient)
+	assert.Equal(t, "Commande ORD-1 confirmée. Total 25.50.", messages[1].Body)
+	assert.Equal(t, "http://orders.example.com/hook", messages[2].Recipient)
+
+	// Channels that cannot reach the customer are skipped
+	inp.CustomerPhone = ""
+	messages, err = notifier.Compose(inp)
+	require.NoError(t, err)
+	assert.Len(t, messages, 2)
+
+	// Unrouted events use the default route; partial refund suffixes select
+	// the refund_issued template
+	inp.EventType = "refund_issued:RF-1"
+	inp.Amount = decimal.RequireFromString("10.00")
+	inp.Locale = ""
+	messages, err = notifier.Compose(inp)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "Refund Issued for Order ORD-1", messages[0].Subject)
+	assert.Contains(t, messages[0].Body, "10.00")
+
+	inp.CustomerEmail = ""
+	_, err = notifier.Compose(inp)
+	assert.ErrorIs(t, err, ErrNoRecipient)
+
+	_, err = NewNotifier(notifier.templates, map[string][]string{EventOrderFailed: {"pager"}}, email)
+	assert.Error(t, err)
+}
+
+func TestWebhookChannel(t *testing.T) {
+	var payload WebhookPayload
+	var key string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key = r.Header.Get("Idempotency-Key")
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhookChannel(server.URL, 0)
+	msg := &OutboxMessage{
+		OrderID:   "ORD-1",
+		EventType: EventOrderConfirmed,
+		Channel:   ChannelWebhook,
+		Recipient: webhook.Recipient(Contact{}),
+		Subject:   "Order ORD-1 Confirmed",
+		Body:      "confirmed",
+	}
+
+	messageID, err := webhook.Send(context.Background(), msg)
+	require.NoError(t, err)
+	assert.Equal(t, "ORD-1/order_confirmed/webhook", messageID)
+	assert.Equal(t, messageID, key)
+	assert.Equal(t, "ORD-1", payload.OrderID)
+	assert.Equal(t, "confirmed", payload.Body)
+
+	// Only replies that may succeed later are retried
+	tests := []struct {
+		status    int
+		code      string
+		permanent bool
+	}{
+		{http.StatusBadRequest, "WEBHOOK_REJECTED", true},
+		{http.StatusNotFound, "WEBHOOK_REJECTED", true},
+		{http.StatusRequestTimeout, "WEBHOOK_UNAVAILABLE", false},
+		{http.StatusTooManyRequests, "WEBHOOK_RATE_LIMITED", false},
+		{http.StatusInternalServerError, "WEBHOOK_UNAVAILABLE", false},
+		{http.StatusServiceUnavailable, "WEBHOOK_UNAVAILABLE", false},
+	}
+	for _, tt := range tests {
+		status = tt.status
+		_, err = webhook.Send(context.Background(), msg)
+		var custom *errors.CustomError
+		require.ErrorAs(t, err, &custom, "status %d", tt.status)
+		assert.Equal(t, tt.code, custom.Code, "status %d", tt.status)
+		assert.Equal(t, tt.permanent, custom.IsPermanent(), "status %d", tt.status)
+	}
+}
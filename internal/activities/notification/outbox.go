@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+)
+
+// OutboxStatus is the delivery state of an outbox message
+type OutboxStatus string
+
+const (
+	OutboxStatusPending OutboxStatus = "pending" // Waiting for its first or next attempt
+	OutboxStatusSending OutboxStatus = "sending" // Claimed by a dispatcher
+	OutboxStatusSent    OutboxStatus = "sent"
+	OutboxStatusFailed  OutboxStatus = "failed" // Gave up after the last attempt
+)
+
+// ErrOutboxMessageNotFound is returned when updating an unknown message
+var ErrOutboxMessageNotFound = stderrors.New("outbox message not found")
+
+// ErrOutboxClaimLost is returned when settling a claim that expired and was
+// taken over, or already settled, by another dispatcher
+var ErrOutboxClaimLost = stderrors.New("outbox claim lost")
+
+// OutboxKey identifies an outbox message: each order gets at most one
+// message per event and channel
+type OutboxKey struct {
+	OrderID   string
+	EventType string
+	Channel   string
+}
+
+func (k OutboxKey) String() string {
+	return k.OrderID + "/" + k.EventType + "/" + k.Channel
+}
+
+// OutboxMessage is a notification waiting for, or done with, delivery on one
+// channel
+type OutboxMessage struct {
+	OrderID       string       `json:"order_id"`
+	EventType     string       `json:"event_type"`
+	Channel       string       `json:"channel"`
+	Recipient     string       `json:"recipient"`
+	Subject       string       `json:"subject,omitempty"`
+	Body          string       `json:"body"`
+	HTMLBody      string       `json:"html_body,omitempty"`
+	Status        OutboxStatus `json:"status"`
+	Attempts      int          `json:"attempts"`
+	LastError     string       `json:"last_error,omitempty"`
+	MessageID     string       `json:"message_id,omitempty"` // Set by the channel once sent
+	NextAttemptAt time.Time    `json:"next_attempt_at"`      // Retry time, or claim expiry while sending
+	CreatedAt     time.Time    `json:"created_at"`
+	SentAt        time.Time    `json:"sent_at"`
+}
+
+// Key returns the message's outbox key
+func (m *OutboxMessage) Key() OutboxKey {
+	return OutboxKey{OrderID: m.OrderID, EventType: m.EventType, Channel: m.Channel}
+}
+
+// Outbox stores notification intents until a Dispatcher delivers them
+type Outbox interface {
+	// Enqueue records a pending message and returns the stored one. If the
+	// outbox already has a message with the same key, that message is
+	// returned unchanged.
+	Enqueue(ctx context.Context, msg OutboxMessage) (*OutboxMessage, error)
+	// ClaimDue marks up to limit pending messages due at now, and sending
+	// messages whose claim expired, as sending until now+lease, counting an
+	// attempt for each, and returns them
+	ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*OutboxMessage, error)
+	// MarkSent records a message delivered by the claim that counted
+	// attempt. It returns ErrOutboxClaimLost, changing nothing, if the
+	// message is no longer sending under that claim.
+	MarkSent(ctx context.Context, key OutboxKey, attempt int, messageID string, at time.Time) error
+	// MarkFailed records the failure of the claim that counted attempt, like
+	// MarkSent. The message is retried at retryAt, or marked failed for good
+	// when retryAt is zero.
+	MarkFailed(ctx context.Context, key OutboxKey, attempt int, lastError string, retryAt time.Time) error
+	// ListByOrder returns an order's messages, oldest first
+	ListByOrder(ctx context.Context, orderID string) ([]*OutboxMessage, error)
+}
@@ -0,0 +1,107 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// NotificationInput is the input for sending customer notifications
+type NotificationInput struct {
+	CustomerEmail string
+	CustomerPhone string
+	Locale        string // Customer locale, e.g. "fr-CA"; empty uses the default
+	OrderID       string
+	EventType     string // "order_confirmed", "order_failed", "refund_issued:<refund ID>", "order_cancelled"
+	Items         []domain.OrderItem
+	Total         decimal.Decimal
+	PaymentID     string
+	RefundID      string
+	Amount        decimal.Decimal // Refunded amount
+	Reason        string
+}
+
+// NotificationOutput is the output of queuing a notification
+type NotificationOutput struct {
+	Deliveries []Delivery
+}
+
+// Delivery is the outbox state of a notification on one channel
+type Delivery struct {
+	Channel   string
+	MessageID string // Empty until the dispatcher has sent the message
+	Status    string // The outbox status, e.g. "pending" or "sent"
+}
+
+// Default event types, used when NotificationInput leaves EventType empty
+const (
+	EventOrderConfirmed = "order_confirmed"
+	EventOrderFailed    = "order_failed"
+	EventRefundIssued   = "refund_issued"
+)
+
+// SendOrderConfirmationActivity queues an order confirmation notification
+func SendOrderConfirmationActivity(outbox Outbox, notifier *Notifier) func(ctx context.Context, input []byte) ([]byte, error) {
+	return enqueueNotificationActivity(outbox, notifier, EventOrderConfirmed)
+}
+
+// SendOrderFailureActivity queues an order failure notification
+func SendOrderFailureActivity(outbox Outbox, notifier *Notifier) func(ctx context.Context, input []byte) ([]byte, error) {
+	return enqueueNotificationActivity(outbox, notifier, EventOrderFailed)
+}
+
+// SendRefundNotificationActivity queues a refund or cancellation notification
+func SendRefundNotificationActivity(outbox Outbox, notifier *Notifier) func(ctx context.Context, input []byte) ([]byte, error) {
+	return enqueueNotificationActivity(outbox, notifier, EventRefundIssued)
+}
+
+// enqueueNotificationActivity renders a notification for each of its
+// channels and writes them to the outbox instead of sending them. The outbox
+// keeps one message per order, event and channel, so a retried activity
+// never queues, or sends, the same notification twice.
+func enqueueNotificationActivity(outbox Outbox, notifier *Notifier, defaultEvent string) func(ctx context.Context, input []byte) ([]byte, error) {
+	return func(ctx context.Context, input []byte) ([]byte, error) {
+		var inp NotificationInput
+		if err := json.Unmarshal(input, &inp); err != nil {
+			return nil, errors.NewPermanentError("INVALID_INPUT", "failed to unmarshal notification input", err)
+		}
+
+		if inp.EventType == "" {
+			inp.EventType = defaultEvent
+		}
+
+		messages, err := notifier.Compose(inp)
+		if stderrors.Is(err, ErrNoRecipient) {
+			return nil, errors.NewPermanentError("NO_RECIPIENT", "customer has no address for any notification channel", err)
+		}
+		if err != nil {
+			return nil, errors.NewPermanentError("TEMPLATE_ERROR", fmt.Sprintf("failed to render %s notification", inp.EventType), err)
+		}
+
+		var output NotificationOutput
+		for _, msg := range messages {
+			stored, err := outbox.Enqueue(ctx, msg)
+			if err != nil {
+				return nil, errors.NewTransientError("OUTBOX_WRITE_FAILED", fmt.Sprintf("failed to queue %s %s notification", inp.EventType, msg.Channel), err)
+			}
+			output.Deliveries = append(output.Deliveries, Delivery{
+				Channel:   stored.Channel,
+				MessageID: stored.MessageID,
+				Status:    string(stored.Status),
+			})
+		}
+
+		result, err := json.Marshal(output)
+		if err != nil {
+			return nil, errors.NewPermanentError("SERIALIZATION_ERROR", "failed to marshal notification output", err)
+		}
+
+		return result, nil
+	}
+}
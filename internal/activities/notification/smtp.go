@@ -0,0 +1,380 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// STARTTLS modes for SMTPOptions.StartTLS
+const (
+	StartTLSRequired      = "required"      // Fail unless the server offers STARTTLS
+	StartTLSOpportunistic = "opportunistic" // Upgrade when the server offers STARTTLS
+	StartTLSDisabled      = "disabled"      // Never upgrade
+)
+
+// SMTP defaults used when SMTPOptions leaves a field zero
+const (
+	DefaultSMTPPoolSize    = 4
+	DefaultSMTPIdleTimeout = 30 * time.Second
+	DefaultSMTPTimeout     = 30 * time.Second
+)
+
+// errSMTPClosed is returned by SendEmail after Close
+var errSMTPClosed = stderrors.New("smtp service closed")
+
+// SMTPOptions configures an SMTPService
+type SMTPOptions struct {
+	Host               string
+	Port               int
+	From               string // Envelope sender and From header
+	Username           string // Enables AUTH PLAIN when set
+	Password           string
+	StartTLS           string // StartTLSRequired (default), StartTLSOpportunistic or StartTLSDisabled
+	InsecureSkipVerify bool   // Accept any server certificate; for testing only
+	PoolSize           int    // Idle connections kept open between sends
+	IdleTimeout        time.Duration
+	Timeout            time.Duration // Per send, including dialing, unless the context ends sooner
+	LocalName          string        // Sent in EHLO; defaults to "localhost"
+}
+
+// SMTPService sends email through an SMTP relay. Connections are reused
+// across sends, up to PoolSize idle at a time. Replies in the 4xx range are
+// returned as transient errors and 5xx replies as permanent ones, so the
+// dispatcher retries only what the relay may accept later.
+type SMTPService struct {
+	opts SMTPOptions
+	pool chan *smtpConn
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// smtpConn is a pooled SMTP connection
+type smtpConn struct {
+	conn     net.Conn
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+// NewSMTPService creates an SMTP email service; connections are opened on
+// first use
+func NewSMTPService(opts SMTPOptions) (*SMTPService, error) {
+	if opts.Host == "" {
+		return nil, fmt.Errorf("smtp host is required")
+	}
+	if opts.From == "" {
+		return nil, fmt.Errorf("smtp from address is required")
+	}
+	if opts.Port == 0 {
+		opts.Port = 587
+	}
+	switch opts.StartTLS {
+	case "":
+		opts.StartTLS = StartTLSRequired
+	case StartTLSRequired, StartTLSOpportunistic, StartTLSDisabled:
+	default:
+		return nil, fmt.Errorf("unsupported smtp startTLS mode: %s", opts.StartTLS)
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = DefaultSMTPPoolSize
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = DefaultSMTPIdleTimeout
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultSMTPTimeout
+	}
+	if opts.LocalName == "" {
+		opts.LocalName = "localhost"
+	}
+
+	return &SMTPService{
+		opts: opts,
+		pool: make(chan *smtpConn, opts.PoolSize),
+	}, nil
+}
+
+// SendEmail sends a plain-text email, or a multipart/alternative one when it
+// has an HTML body, and returns its Message-ID
+func (s *SMTPService) SendEmail(ctx context.Context, email Email) (string, error) {
+	messageID, err := s.newMessageID()
+	if err != nil {
+		return "", errors.NewTransientError("SMTP_UNAVAILABLE", "failed to generate message ID", err)
+	}
+	msg, err := buildMIMEMessage(s.opts.From, email, messageID, time.Now())
+	if err != nil {
+		return "", errors.NewPermanentError("INVALID_EMAIL", "failed to build email", err)
+	}
+
+	c, err := s.acquire(ctx)
+	if err != nil {
+		return "", classifySMTPError("failed to connect to smtp server", err)
+	}
+
+	deadline := time.Now().Add(s.opts.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+
+	if err := send(c.client, s.opts.From, email.To, msg); err != nil {
+		s.release(c, err)
+		return "", classifySMTPError("failed to send email", err)
+	}
+	s.release(c, nil)
+	return messageID, nil
+}
+
+// Close closes every idle connection; later sends fail
+func (s *SMTPService) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.pool)
+	for c := range s.pool {
+		c.client.Quit()
+		c.client.Close()
+	}
+	return nil
+}
+
+// send runs one SMTP transaction
+func send(client *smtp.Client, from, to string, msg []byte) error {
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// acquire returns an idle pooled connection that still answers, or dials a
+// new one
+func (s *SMTPService) acquire(ctx context.Context) (*smtpConn, error) {
+	for {
+		select {
+		case c, ok := <-s.pool:
+			if !ok {
+				return nil, errSMTPClosed
+			}
+			if time.Since(c.lastUsed) < s.opts.IdleTimeout {
+				c.conn.SetDeadline(time.Now().Add(s.opts.Timeout))
+				if c.client.Noop() == nil {
+					return c, nil
+				}
+			}
+			c.client.Close()
+		default:
+			return s.dial(ctx)
+		}
+	}
+}
+
+// release returns a connection to the pool after a send. After an SMTP reply
+// error the transaction is reset so the connection can be reused; any other
+// error closes it.
+func (s *SMTPService) release(c *smtpConn, sendErr error) {
+	if sendErr != nil {
+		var reply *textproto.Error
+		if !stderrors.As(sendErr, &reply) || c.client.Reset() != nil {
+			c.client.Close()
+			return
+		}
+	}
+	c.lastUsed = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		c.client.Close()
+		return
+	}
+	select {
+	case s.pool <- c:
+	default:
+		c.client.Quit()
+		c.client.Close()
+	}
+}
+
+// dial opens and prepares a new connection: EHLO, STARTTLS and AUTH
+func (s *SMTPService) dial(ctx context.Context) (*smtpConn, error) {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return nil, errSMTPClosed
+	}
+
+	addr := net.JoinHostPort(s.opts.Host, strconv.Itoa(s.opts.Port))
+	dialer := net.Dialer{Timeout: s.opts.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(s.opts.Timeout))
+
+	client, err := smtp.NewClient(conn, s.opts.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := s.prepare(client); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &smtpConn{conn: conn, client: client, lastUsed: time.Now()}, nil
+}
+
+// prepare greets the server, upgrades to TLS and authenticates as configured
+func (s *SMTPService) prepare(client *smtp.Client) error {
+	if err := client.Hello(s.opts.LocalName); err != nil {
+		return err
+	}
+
+	if s.opts.StartTLS != StartTLSDisabled {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			tlsConfig := &tls.Config{
+				ServerName:         s.opts.Host,
+				InsecureSkipVerify: s.opts.InsecureSkipVerify,
+			}
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return err
+			}
+		} else if s.opts.StartTLS == StartTLSRequired {
+			return errors.NewPermanentError("SMTP_TLS_UNAVAILABLE", "smtp server does not offer STARTTLS", nil)
+		}
+	}
+
+	if s.opts.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.NewPermanentError("SMTP_AUTH_UNAVAILABLE", "smtp server does not offer AUTH", nil)
+		}
+		auth := smtp.PlainAuth("", s.opts.Username, s.opts.Password, s.opts.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newMessageID returns a unique Message-ID in the From address's domain
+func (s *SMTPService) newMessageID() (string, error) {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	domain := s.opts.Host
+	if _, d, ok := strings.Cut(s.opts.From, "@"); ok {
+		domain = strings.TrimSuffix(d, ">")
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b[:]), domain), nil
+}
+
+// classifySMTPError maps SMTP replies to CustomErrors: 4xx replies are
+// transient and 5xx permanent. Network errors are transient.
+func classifySMTPError(msg string, err error) error {
+	var custom *errors.CustomError
+	if stderrors.As(err, &custom) {
+		return err
+	}
+
+	var reply *textproto.Error
+	if stderrors.As(err, &reply) {
+		if reply.Code >= 500 {
+			return errors.NewPermanentError("SMTP_REJECTED", msg, err)
+		}
+		return errors.NewTransientError("SMTP_TEMPORARY_FAILURE", msg, err)
+	}
+	return errors.NewTransientError("SMTP_UNAVAILABLE", msg, err)
+}
+
+// buildMIMEMessage renders an email as a MIME message with CRLF line endings
+func buildMIMEMessage(from string, email Email, messageID string, date time.Time) ([]byte, error) {
+	if strings.ContainsAny(email.To, "\r\n") || strings.ContainsAny(from, "\r\n") {
+		return nil, fmt.Errorf("address contains a line break")
+	}
+
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", from)
+	header("To", email.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", email.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	header("MIME-Version", "1.0")
+
+	if email.HTML == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, email.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", email.Text},
+		{"text/html; charset=utf-8", email.HTML},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable writes body quoted-printable encoded with CRLF line
+// breaks
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
@@ -0,0 +1,154 @@
+package notification
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+	"github.com/Youmanvi/taskorchestrator/test/fakes"
+)
+
+func newTestSMTP(t *testing.T, server *fakes.SMTPServer, opts SMTPOptions) *SMTPService {
+	t.Helper()
+	opts.Host = server.Host()
+	opts.Port = server.Port()
+	opts.From = "orders@example.com"
+	if opts.StartTLS == "" {
+		opts.StartTLS = StartTLSDisabled
+	}
+	svc, err := NewSMTPService(opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { svc.Close() })
+	return svc
+}
+
+func newTestSMTPServer(t *testing.T) *fakes.SMTPServer {
+	t.Helper()
+	server, err := fakes.NewSMTPServer()
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+func TestSMTPServiceSendsMultipartEmail(t *testing.T) {
+	server := newTestSMTPServer(t)
+	svc := newTestSMTP(t, server, SMTPOptions{})
+
+	messageID, err := svc.SendEmail(context.Background(), Email{
+		To:      "customer@example.com",
+		Subject: "Commande ORD-1 confirmée",
+		Text:    "Merci pour votre commande.\nTotal 25.50",
+		HTML:    "<p>Merci pour votre commande.</p>",
+	})
+	require.NoError(t, err)
+
+	messages := server.Messages()
+	require.Len(t, messages, 1)
+	msg := messages[0]
+	assert.Equal(t, "orders@example.com", msg.From)
+	assert.Equal(t, []string{"customer@example.com"}, msg.To)
+	assert.Equal(t, "Commande ORD-1 confirmée", msg.Subject)
+	assert.Equal(t, "Merci pour votre commande.\nTotal 25.50", msg.Text)
+	assert.Equal(t, "<p>Merci pour votre commande.</p>", msg.HTML)
+	assert.Equal(t, messageID, msg.Header.Get("Message-ID"))
+	assert.Contains(t, msg.Header.Get("Content-Type"), "multipart/alternative")
+
+	// Without HTML the message is plain text
+	_, err = svc.SendEmail(context.Background(), Email{To: "customer@example.com", Subject: "Hi", Text: "Plain"})
+	require.NoError(t, err)
+	msg = server.Messages()[1]
+	assert.Equal(t, "Plain", msg.Text)
+	assert.Empty(t, msg.HTML)
+	assert.Contains(t, msg.Header.Get("Content-Type"), "text/plain")
+}
+
+func TestSMTPServiceReusesConnections(t *testing.T) {
+	server := newTestSMTPServer(t)
+	svc := newTestSMTP(t, server, SMTPOptions{PoolSize: 1})
+
+	for i := 0; i < 3; i++ {
+		_, err := svc.SendEmail(context.Background(), Email{To: "customer@example.com", Subject: "Hi", Text: "Hello"})
+		require.NoError(t, err)
+	}
+	assert.Len(t, server.Messages(), 3)
+	assert.Equal(t, 1, server.Connections())
+}
+
+func TestSMTPServiceClassifiesReplies(t *testing.T) {
+	server := newTestSMTPServer(t)
+	svc := newTestSMTP(t, server, SMTPOptions{PoolSize: 1})
+	email := Email{To: "customer@example.com", Subject: "Hi", Text: "Hello"}
+
+	server.RejectNext(451, "4.3.0 Try again later")
+	_, err := svc.SendEmail(context.Background(), email)
+	var custom *errors.CustomError
+	require.ErrorAs(t, err, &custom)
+	assert.True(t, custom.IsTransient())
+	assert.Equal(t, "SMTP_TEMPORARY_FAILURE", custom.Code)
+
+	server.RejectNext(550, "5.1.1 No such user")
+	_, err = svc.SendEmail(context.Background(), email)
+	require.ErrorAs(t, err, &custom)
+	assert.True(t, custom.IsPermanent())
+	assert.Equal(t, "SMTP_REJECTED", custom.Code)
+
+	// Rejected transactions are reset and the connection is kept
+	_, err = svc.SendEmail(context.Background(), email)
+	require.NoError(t, err)
+	assert.Equal(t, 1, server.Connections())
+
+	// An unreachable server is transient
+	server.Close()
+	svc = newTestSMTP(t, server, SMTPOptions{})
+	_, err = svc.SendEmail(context.Background(), email)
+	require.ErrorAs(t, err, &custom)
+	assert.True(t, custom.IsTransient())
+	assert.Equal(t, "SMTP_UNAVAILABLE", custom.Code)
+}
+
+func TestSMTPServiceStartTLSAndAuth(t *testing.T) {
+	server := newTestSMTPServer(t)
+	require.NoError(t, server.EnableTLS())
+	server.RequireAuth("mailer", "secret")
+
+	svc := newTestSMTP(t, server, SMTPOptions{
+		StartTLS:           StartTLSRequired,
+		InsecureSkipVerify: true,
+		Username:           "mailer",
+		Password:           "secret",
+	})
+	_, err := svc.SendEmail(context.Background(), Email{To: "customer@example.com", Subject: "Hi", Text: "Hello"})
+	require.NoError(t, err)
+	require.Len(t, server.Messages(), 1)
+	assert.True(t, server.Messages()[0].TLS)
+
+	// Wrong credentials are rejected permanently
+	svc = newTestSMTP(t, server, SMTPOptions{
+		StartTLS:           StartTLSRequired,
+		InsecureSkipVerify: true,
+		Username:           "mailer",
+		Password:           "wrong",
+	})
+	_, err = svc.SendEmail(context.Background(), Email{To: "customer@example.com", Subject: "Hi", Text: "Hello"})
+	var custom *errors.CustomError
+	require.ErrorAs(t, err, &custom)
+	assert.True(t, custom.IsPermanent())
+}
+
+func TestSMTPServiceRequiresStartTLS(t *testing.T) {
+	server := newTestSMTPServer(t)
+	svc := newTestSMTP(t, server, SMTPOptions{StartTLS: StartTLSRequired})
+
+	_, err := svc.SendEmail(context.Background(), Email{To: "customer@example.com", Subject: "Hi", Text: "Hello"})
+	assert.Equal(t, "SMTP_TLS_UNAVAILABLE", errors.CodeOf(err))
+	assert.Empty(t, server.Messages())
+
+	// Opportunistic mode sends in the clear when STARTTLS is not offered
+	svc = newTestSMTP(t, server, SMTPOptions{StartTLS: StartTLSOpportunistic})
+	_, err = svc.SendEmail(context.Background(), Email{To: "customer@example.com", Subject: "Hi", Text: "Hello"})
+	require.NoError(t, err)
+	assert.False(t, server.Messages()[0].TLS)
+}
@@ -0,0 +1,232 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteOutbox keeps outbox messages in SQLite. Claims run in an immediate
+// transaction, so dispatchers sharing the file never claim the same message.
+type SQLiteOutbox struct {
+	db *sql.DB
+}
+
+// NewSQLiteOutbox opens the outbox database
+func NewSQLiteOutbox(dbPath string) (*SQLiteOutbox, error) {
+	db, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping outbox database: %w", err)
+	}
+
+	o := &SQLiteOutbox{db: db}
+	if err := o.initSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// initSchema creates the notification_outbox table
+func (o *SQLiteOutbox) initSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS notification_outbox (
+		order_id TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		channel TEXT NOT NULL,
+		recipient TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		body TEXT NOT NULL,
+		html_body TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		message_id TEXT NOT NULL DEFAULT '',
+		next_attempt_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL,
+		sent_at DATETIME,
+		PRIMARY KEY (order_id, event_type, channel)
+	);
+
+	-- Dispatchers scan for due messages
+	CREATE INDEX IF NOT EXISTS idx_outbox_status_next_attempt
+		ON notification_outbox(status, next_attempt_at);
+	`
+
+	_, err := o.db.Exec(schema)
+	return err
+}
+
+// Enqueue records a pending message unless one with the same key exists
+func (o *SQLiteOutbox) Enqueue(ctx context.Context, msg OutboxMessage) (*OutboxMessage, error) {
+	now := time.Now().UTC()
+	if _, err := o.db.ExecContext(ctx, `
+		INSERT OR IGNORE INTO notification_outbox
+			(order_id, event_type, channel, recipient, subject, body, html_body, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, msg.OrderID, msg.EventType, msg.Channel, msg.Recipient, msg.Subject, msg.Body, msg.HTMLBody,
+		string(OutboxStatusPending), now, now); err != nil {
+		return nil, fmt.Errorf("failed to enqueue %s: %w", msg.Key(), err)
+	}
+
+	return o.get(ctx, o.db, msg.Key())
+}
+
+// ClaimDue claims due messages for sending
+func (o *SQLiteOutbox) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*OutboxMessage, error) {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin outbox claim: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT order_id, event_type, channel FROM notification_outbox
+		WHERE status IN (?, ?) AND next_attempt_at <= ?
+		ORDER BY next_attempt_at LIMIT ?
+	`, string(OutboxStatusPending), string(OutboxStatusSending), now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due outbox messages: %w", err)
+	}
+	var due []OutboxKey
+	for rows.Next() {
+		var k OutboxKey
+		if err := rows.Scan(&k.OrderID, &k.EventType, &k.Channel); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	claimed := make([]*OutboxMessage, 0, len(due))
+	for _, k := range due {
+		if _, err := tx.ExecContext(ctx,
+			"UPDATE notification_outbox SET status = ?, attempts = attempts + 1, next_attempt_at = ? WHERE order_id = ? AND event_type = ? AND channel = ?",
+			string(OutboxStatusSending), now.Add(lease).UTC(), k.OrderID, k.EventType, k.Channel,
+		); err != nil {
+			return nil, fmt.Errorf("failed to claim outbox message: %w", err)
+		}
+		msg, err := o.get(ctx, tx, k)
+		if err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, msg)
+	}
+
+	return claimed, tx.Commit()
+}
+
+// MarkSent records a message delivered under the claim for attempt
+func (o *SQLiteOutbox) MarkSent(ctx context.Context, key OutboxKey, attempt int, messageID string, at time.Time) error {
+	return o.settle(ctx, key, attempt,
+		"status = ?, message_id = ?, sent_at = ?, last_error = ''",
+		string(OutboxStatusSent), messageID, at.UTC(),
+	)
+}
+
+// MarkFailed records the failure of the claim for attempt, scheduling a retry
+// unless retryAt is zero
+func (o *SQLiteOutbox) MarkFailed(ctx context.Context, key OutboxKey, attempt int, lastError string, retryAt time.Time) error {
+	if retryAt.IsZero() {
+		return o.settle(ctx, key, attempt, "status = ?, last_error = ?", string(OutboxStatusFailed), lastError)
+	}
+	return o.settle(ctx, key, attempt,
+		"status = ?, last_error = ?, next_attempt_at = ?",
+		string(OutboxStatusPending), lastError, retryAt.UTC(),
+	)
+}
+
+// ListByOrder returns an order's messages, oldest first
+func (o *SQLiteOutbox) ListByOrder(ctx context.Context, orderID string) ([]*OutboxMessage, error) {
+	rows, err := o.db.QueryContext(ctx,
+		"SELECT "+outboxColumns+" FROM notification_outbox WHERE order_id = ? ORDER BY created_at, event_type, channel", orderID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*OutboxMessage{}
+	for rows.Next() {
+		msg, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// Close closes the database
+func (o *SQLiteOutbox) Close() error {
+	return o.db.Close()
+}
+
+// settle applies set to one message if it is still sending under the claim
+// that counted attempt, so a dispatcher whose lease expired cannot overwrite
+// the outcome of the dispatcher that took the message over
+func (o *SQLiteOutbox) settle(ctx context.Context, key OutboxKey, attempt int, set string, args ...any) error {
+	args = append(args, key.OrderID, key.EventType, key.Channel, string(OutboxStatusSending), attempt)
+	result, err := o.db.ExecContext(ctx,
+		"UPDATE notification_outbox SET "+set+" WHERE order_id = ? AND event_type = ? AND channel = ? AND status = ? AND attempts = ?", args...,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update outbox message: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		return nil
+	}
+	if _, err := o.get(ctx, o.db, key); err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s attempt %d", ErrOutboxClaimLost, key, attempt)
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const outboxColumns = "order_id, event_type, channel, recipient, subject, body, html_body, status, attempts, last_error, message_id, next_attempt_at, created_at, sent_at"
+
+// get loads one message
+func (o *SQLiteOutbox) get(ctx context.Context, q queryRower, key OutboxKey) (*OutboxMessage, error) {
+	msg, err := scanOutboxMessage(q.QueryRowContext(ctx,
+		"SELECT "+outboxColumns+" FROM notification_outbox WHERE order_id = ? AND event_type = ? AND channel = ?",
+		key.OrderID, key.EventType, key.Channel,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrOutboxMessageNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load outbox message: %w", err)
+	}
+	return msg, nil
+}
+
+// scanOutboxMessage reads one notification_outbox row
+func scanOutboxMessage(row interface{ Scan(...any) error }) (*OutboxMessage, error) {
+	var msg OutboxMessage
+	var status string
+	var sentAt sql.NullTime
+	if err := row.Scan(&msg.OrderID, &msg.EventType, &msg.Channel, &msg.Recipient, &msg.Subject, &msg.Body, &msg.HTMLBody, &status,
+		&msg.Attempts, &msg.LastError, &msg.MessageID, &msg.NextAttemptAt, &msg.CreatedAt, &sentAt); err != nil {
+		return nil, err
+	}
+	msg.Status = OutboxStatus(status)
+	msg.SentAt = sentAt.Time
+	return &msg, nil
+}
@@ -0,0 +1,234 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteOutbox(t *testing.T) {
+	ctx := context.Background()
+	outbox, err := NewSQLiteOutbox(t.TempDir() + "/outbox.db")
+	require.NoError(t, err)
+	t.Cleanup(func() { outbox.Close() })
+
+	msg := OutboxMessage{
+		OrderID:   "ORD-1",
+		EventType: EventOrderConfirmed,
+		Channel:   ChannelEmail,
+		Recipient: "customer@example.com",
+		Subject:   "Order ORD-1 Confirmed",
+		Body:      "Your order ORD-1 has been confirmed and is being processed.",
+		HTMLBody:  "<p>Your order <strong>ORD-1</strong> has been confirmed.</p>",
+	}
+	stored, err := outbox.Enqueue(ctx, msg)
+	require.NoError(t, err)
+	assert.Equal(t, OutboxStatusPending, stored.Status)
+	assert.Equal(t, msg.HTMLBody, stored.HTMLBody)
+
+	// Queuing the same order, event and channel again returns the first message
+	dup := msg
+	dup.Subject = "changed"
+	again, err := outbox.Enqueue(ctx, dup)
+	require.NoError(t, err)
+	assert.Equal(t, msg.Subject, again.Subject)
+
+	now := time.Now().Add(time.Second)
+	claimed, err := outbox.ClaimDue(ctx, now, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, OutboxStatusSending, claimed[0].Status)
+	assert.Equal(t, 1, claimed[0].Attempts)
+
+	// A claimed message is not claimed again until its lease expires
+	claimed, err = outbox.ClaimDue(ctx, now, 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+	claimed, err = outbox.ClaimDue(ctx, now.Add(2*time.Minute), 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, 2, claimed[0].Attempts)
+
+	// The first claim expired, so it can no longer settle the message
+	key := msg.Key()
+	assert.ErrorIs(t, outbox.MarkSent(ctx, key, 1, "MSG_STALE", now), ErrOutboxClaimLost)
+	assert.ErrorIs(t, outbox.MarkFailed(ctx, key, 1, "stale", time.Time{}), ErrOutboxClaimLost)
+
+	retryAt := now.Add(time.Hour)
+	require.NoError(t, outbox.MarkFailed(ctx, key, 2, "smtp unavailable", retryAt))
+	assert.ErrorIs(t, outbox.MarkFailed(ctx, key, 2, "smtp unavailable", retryAt), ErrOutboxClaimLost, "a claim settles once")
+	claimed, err = outbox.ClaimDue(ctx, retryAt.Add(-time.Second), 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	claimed, err = outbox.ClaimDue(ctx, retryAt, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.NoError(t, outbox.MarkSent(ctx, key, claimed[0].Attempts, "MSG_1", now))
+	missing := OutboxKey{OrderID: "ORD-2", EventType: EventOrderConfirmed, Channel: ChannelEmail}
+	assert.ErrorIs(t, outbox.MarkSent(ctx, missing, 1, "MSG_2", now), ErrOutboxMessageNotFound)
+
+	// Another channel for the same event is a separate message
+	sms := msg
+	sms.Channel = ChannelSMS
+	sms.Recipient = "+15550100"
+	sms.HTMLBody = ""
+	_, err = outbox.Enqueue(ctx, sms)
+	require.NoError(t, err)
+
+	messages, err := outbox.ListByOrder(ctx, "ORD-1")
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, ChannelEmail, messages[0].Channel)
+	assert.Equal(t, OutboxStatusSent, messages[0].Status)
+	assert.Equal(t, "MSG_1", messages[0].MessageID)
+	assert.Empty(t, messages[0].LastError)
+	assert.False(t, messages[0].SentAt.IsZero())
+	assert.Equal(t, ChannelSMS, messages[1].Channel)
+	assert.Equal(t, OutboxStatusPending, messages[1].Status)
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+	outbox, err := NewSQLiteOutbox(t.TempDir() + "/outbox.db")
+	require.NoError(t, err)
+	t.Cleanup(func() { outbox.Close() })
+
+	email := NewMockEmailService()
+	notifier := newTestNotifier(t, nil, NewEmailChannel(email))
+	dispatcher := NewDispatcher(outbox, notifier, DispatcherConfig{MaxAttempts: 2, RetryBackoff: time.Minute}, nil)
+
+	for _, orderID := range []string{"ORD-1", "ORD-2"} {
+		_, err := outbox.Enqueue(ctx, OutboxMessage{
+			OrderID:   orderID,
+			EventType: EventOrderConfirmed,
+			Channel:   ChannelEmail,
+			Recipient: "customer@example.com",
+			Subject:   fmt.Sprintf("Order %s Confirmed", orderID),
+			Body:      "confirmed",
+		})
+		require.NoError(t, err)
+	}
+
+	// A failed send is retried after the backoff
+	email.SetSendError(fmt.Errorf("smtp unavailable"))
+	now := time.Now().Add(time.Second)
+	sent, err := dispatcher.DispatchDue(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	messages, err := outbox.ListByOrder(ctx, "ORD-1")
+	require.NoError(t, err)
+	assert.Equal(t, OutboxStatusPending, messages[0].Status)
+	assert.Equal(t, "smtp unavailable", messages[0].LastError)
+
+	sent, err = dispatcher.DispatchDue(ctx, now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent, "nothing is due before the backoff")
+
+	// The last attempt failing marks the message failed
+	sent, err = dispatcher.DispatchDue(ctx, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	messages, err = outbox.ListByOrder(ctx, "ORD-1")
+	require.NoError(t, err)
+	assert.Equal(t, OutboxStatusFailed, messages[0].Status)
+	assert.Equal(t, 2, messages[0].Attempts)
+
+	email.SetSendError(nil)
+	_, err = outbox.Enqueue(ctx, OutboxMessage{
+		OrderID:   "ORD-3",
+		EventType: EventRefundIssued,
+		Channel:   ChannelEmail,
+		Recipient: "customer@example.com",
+		Subject:   "Refund Issued for Order ORD-3",
+		Body:      "refunded",
+	})
+	require.NoError(t, err)
+
+	// A message for a channel nobody registered fails without retries
+	_, err = outbox.Enqueue(ctx, OutboxMessage{
+		OrderID:   "ORD-3",
+		EventType: EventRefundIssued,
+		Channel:   "pager",
+		Recipient: "ops",
+		Body:      "refunded",
+	})
+	require.NoError(t, err)
+
+	sent, err = dispatcher.DispatchDue(ctx, now.Add(3*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	messages, err = outbox.ListByOrder(ctx, "ORD-3")
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, OutboxStatusSent, messages[0].Status)
+	assert.Equal(t, OutboxStatusFailed, messages[1].Status)
+	assert.Equal(t, 1, messages[1].Attempts)
+
+	// Sent messages are never sent again
+	sent, err = dispatcher.DispatchDue(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Len(t, email.GetAllMessages(), 1)
+}
+
+// takeoverChannel sends successfully, but only after another dispatcher has
+// taken the message over because the first claim's lease expired
+type takeoverChannel struct {
+	outbox   Outbox
+	takeover time.Time
+}
+
+func (c *takeoverChannel) Name() string                     { return "slow" }
+func (c *takeoverChannel) Recipient(contact Contact) string { return contact.Email }
+
+func (c *takeoverChannel) Send(ctx context.Context, msg *OutboxMessage) (string, error) {
+	if msg.Attempts == 1 {
+		if _, err := c.outbox.ClaimDue(ctx, c.takeover, 10, time.Minute); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("MSG_%d", msg.Attempts), nil
+}
+
+func TestDispatcher_ExpiredClaimDoesNotSettle(t *testing.T) {
+	ctx := context.Background()
+	outbox, err := NewSQLiteOutbox(t.TempDir() + "/outbox.db")
+	require.NoError(t, err)
+	t.Cleanup(func() { outbox.Close() })
+
+	now := time.Now().Add(time.Second)
+	channel := &takeoverChannel{outbox: outbox, takeover: now.Add(2 * time.Minute)}
+	notifier := newTestNotifier(t, nil, channel)
+	dispatcher := NewDispatcher(outbox, notifier, DispatcherConfig{MaxAttempts: 3, ClaimLease: time.Minute}, nil)
+
+	_, err = outbox.Enqueue(ctx, OutboxMessage{
+		OrderID:   "ORD-1",
+		EventType: EventOrderConfirmed,
+		Channel:   "slow",
+		Recipient: "customer@example.com",
+		Body:      "confirmed",
+	})
+	require.NoError(t, err)
+
+	// The first dispatcher's send finishes after its claim was taken over,
+	// so it leaves the message to the dispatcher holding the new claim
+	sent, err := dispatcher.DispatchDue(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	messages, err := outbox.ListByOrder(ctx, "ORD-1")
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, OutboxStatusSending, messages[0].Status)
+	assert.Equal(t, 2, messages[0].Attempts)
+	assert.Empty(t, messages[0].MessageID)
+
+	require.NoError(t, outbox.MarkSent(ctx, messages[0].Key(), 2, "MSG_2", now))
+}
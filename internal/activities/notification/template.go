@@ -0,0 +1,259 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	stderrors "errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+)
+
+// ErrTemplateNotFound is returned when no locale has a template for an event
+var ErrTemplateNotFound = stderrors.New("notification template not found")
+
+//go:embed templates
+var builtinTemplates embed.FS
+
+// BuiltinTemplates returns the templates shipped with the service, laid out
+// as LoadTemplates expects
+func BuiltinTemplates() fs.FS {
+	sub, err := fs.Sub(builtinTemplates, "templates")
+	if err != nil {
+		panic(err) // The embedded directory always exists
+	}
+	return sub
+}
+
+// TemplateData is what notification templates render from
+type TemplateData struct {
+	OrderID   string
+	EventType string
+	Locale    string
+	Items     []domain.OrderItem
+	Total     decimal.Decimal
+	PaymentID string
+	RefundID  string
+	Amount    decimal.Decimal // Refunded amount
+	Reason    string
+}
+
+// Content is a notification rendered for one channel
+type Content struct {
+	Subject string
+	Body    string
+	HTML    string // Empty when the template has no HTML part
+}
+
+// templateSet holds the parts of one named template in one locale
+type templateSet struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+	bodies  map[string]*texttemplate.Template // Channel-specific bodies, by channel name
+}
+
+// Templates holds named notification templates for every locale.
+//
+// Templates are read from <locale>/<name>.<part>.tmpl, where name is the
+// event type and part is one of:
+//
+//	subject   the subject line (required)
+//	txt       the plain-text body (required)
+//	html      the HTML body, rendered with html/template
+//	<channel> a body for that channel only, e.g. "sms"
+type Templates struct {
+	defaultLocale string
+	locales       map[string]map[string]*templateSet
+}
+
+// templateFuncs are available to every template
+var templateFuncs = map[string]any{
+	"money": func(d decimal.Decimal) string { return d.StringFixed(2) },
+}
+
+// LoadTemplates parses every template in fsys. Renders fall back to
+// defaultLocale, which must have templates.
+func LoadTemplates(fsys fs.FS, defaultLocale string) (*Templates, error) {
+	t := &Templates{
+		defaultLocale: normalizeLocale(defaultLocale),
+		locales:       make(map[string]map[string]*templateSet),
+	}
+
+	dirs, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template directory: %w", err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		if err := t.loadLocale(fsys, dir.Name()); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := t.locales[t.defaultLocale]; !ok {
+		return nil, fmt.Errorf("no templates for default locale %q", defaultLocale)
+	}
+	for locale, sets := range t.locales {
+		for name, set := range sets {
+			if set.subject == nil || set.text == nil {
+				return nil, fmt.Errorf("template %s/%s needs both a subject and a txt part", locale, name)
+			}
+		}
+	}
+	return t, nil
+}
+
+// loadLocale parses the templates of one locale directory
+func (t *Templates) loadLocale(fsys fs.FS, dir string) error {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read templates for %s: %w", dir, err)
+	}
+
+	locale := normalizeLocale(dir)
+	sets := make(map[string]*templateSet)
+	for _, file := range files {
+		base, ok := strings.CutSuffix(file.Name(), ".tmpl")
+		if file.IsDir() || !ok {
+			continue
+		}
+		name, part, ok := cutLast(base, ".")
+		if !ok || name == "" || part == "" {
+			return fmt.Errorf("template %s/%s is not named <name>.<part>.tmpl", dir, file.Name())
+		}
+
+		src, err := fs.ReadFile(fsys, path.Join(dir, file.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read template %s/%s: %w", dir, file.Name(), err)
+		}
+
+		set := sets[name]
+		if set == nil {
+			set = &templateSet{bodies: make(map[string]*texttemplate.Template)}
+			sets[name] = set
+		}
+
+		id := locale + "/" + base
+		if part == "html" {
+			set.html, err = htmltemplate.New(id).Funcs(templateFuncs).Parse(string(src))
+		} else {
+			var tmpl *texttemplate.Template
+			tmpl, err = texttemplate.New(id).Funcs(templateFuncs).Parse(string(src))
+			switch part {
+			case "subject":
+				set.subject = tmpl
+			case "txt":
+				set.text = tmpl
+			default:
+				set.bodies[part] = tmpl
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse template %s/%s: %w", dir, file.Name(), err)
+		}
+	}
+
+	t.locales[locale] = sets
+	return nil
+}
+
+// Render renders the named template for a channel in the closest locale:
+// the exact locale, then its language ("fr" for "fr-CA"), then the default
+func (t *Templates) Render(name, channel, locale string, data TemplateData) (Content, error) {
+	for _, l := range t.localeChain(locale) {
+		set, ok := t.locales[l][name]
+		if !ok {
+			continue
+		}
+		data.Locale = l
+		return set.render(channel, data)
+	}
+	return Content{}, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+}
+
+// render executes the subject, the channel's body and the HTML part
+func (s *templateSet) render(channel string, data TemplateData) (Content, error) {
+	var content Content
+	var err error
+
+	if content.Subject, err = execute(s.subject, data); err != nil {
+		return Content{}, err
+	}
+
+	body := s.text
+	if b, ok := s.bodies[channel]; ok {
+		body = b
+	}
+	if content.Body, err = execute(body, data); err != nil {
+		return Content{}, err
+	}
+
+	if s.html != nil {
+		var buf bytes.Buffer
+		if err := s.html.Execute(&buf, data); err != nil {
+			return Content{}, fmt.Errorf("failed to render template %s: %w", s.html.Name(), err)
+		}
+		content.HTML = strings.TrimSpace(buf.String())
+	}
+	return content, nil
+}
+
+// execute renders a text template, trimming surrounding whitespace
+func execute(tmpl *texttemplate.Template, data TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", tmpl.Name(), err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// localeChain returns the locales to try for locale, most specific first
+func (t *Templates) localeChain(locale string) []string {
+	var chain []string
+	add := func(l string) {
+		if !slices.Contains(chain, l) {
+			chain = append(chain, l)
+		}
+	}
+
+	if locale = normalizeLocale(locale); locale != "" {
+		add(locale)
+		if language, _, ok := strings.Cut(locale, "-"); ok {
+			add(language)
+		}
+	}
+	add(t.defaultLocale)
+	return chain
+}
+
+// normalizeLocale lower-cases a locale and uses "-" as its separator, so
+// "fr_CA" and "fr-ca" name the same directory
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
+
+// TemplateName returns the template rendered for an event type. Suffixes
+// after ":" only keep outbox keys distinct, e.g. "refund_issued:RF-1".
+func TemplateName(eventType string) string {
+	name, _, _ := strings.Cut(eventType, ":")
+	return name
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,98 @@
+package notification
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+)
+
+func confirmedData() TemplateData {
+	return TemplateData{
+		OrderID: "ORD-1",
+		Items: []domain.OrderItem{
+			{SKU: "ITEM-001", Quantity: 2, Price: decimal.RequireFromString("10.00")},
+			{SKU: "ITEM-<B>", Quantity: 1, Price: decimal.RequireFromString("5.50")},
+		},
+		Total:     decimal.RequireFromString("25.50"),
+		PaymentID: "PAY-1",
+	}
+}
+
+func TestBuiltinTemplates(t *testing.T) {
+	templates, err := LoadTemplates(BuiltinTemplates(), "en")
+	require.NoError(t, err)
+
+	content, err := templates.Render(EventOrderConfirmed, ChannelEmail, "", confirmedData())
+	require.NoError(t, err)
+	assert.Equal(t, "Order ORD-1 Confirmed", content.Subject)
+	assert.Contains(t, content.Body, "2 x ITEM-001  20.00")
+	assert.Contains(t, content.Body, "Total: 25.50")
+	assert.Contains(t, content.Body, "PAY-1")
+	// The HTML part escapes order data
+	assert.Contains(t, content.HTML, "ITEM-&lt;B&gt;")
+
+	// Channels with their own part get it instead of the plain-text body
+	content, err = templates.Render(EventOrderConfirmed, ChannelSMS, "en-US", confirmedData())
+	require.NoError(t, err)
+	assert.Equal(t, "Order ORD-1 confirmed. Total 25.50.", content.Body)
+}
+
+func TestTemplatesLocaleFallback(t *testing.T) {
+	templates, err := LoadTemplates(BuiltinTemplates(), "en")
+	require.NoError(t, err)
+
+	tests := []struct {
+		locale  string
+		subject string
+	}{
+		{"fr", "Commande ORD-1 confirmée"},
+		{"fr_CA", "Commande ORD-1 confirmée"}, // Falls back to the language
+		{"FR-be", "Commande ORD-1 confirmée"},
+		{"de-DE", "Order ORD-1 Confirmed"}, // Falls back to the default locale
+		{"", "Order ORD-1 Confirmed"},
+	}
+	for _, tt := range tests {
+		content, err := templates.Render(EventOrderConfirmed, ChannelEmail, tt.locale, confirmedData())
+		require.NoError(t, err, tt.locale)
+		assert.Equal(t, tt.subject, content.Subject, tt.locale)
+	}
+
+	// A locale without a channel part falls back to its own plain-text body
+	content, err := templates.Render(EventOrderFailed, ChannelSMS, "fr", TemplateData{OrderID: "ORD-1"})
+	require.NoError(t, err)
+	assert.Contains(t, content.Body, "Malheureusement")
+
+	_, err = templates.Render("unknown_event", ChannelEmail, "fr", TemplateData{})
+	assert.ErrorIs(t, err, ErrTemplateNotFound)
+}
+
+func TestLoadTemplatesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"no default locale", fstest.MapFS{
+			"fr/order_failed.subject.tmpl": {Data: []byte("Échec")},
+			"fr/order_failed.txt.tmpl":     {Data: []byte("Échec")},
+		}},
+		{"missing txt part", fstest.MapFS{
+			"en/order_failed.subject.tmpl": {Data: []byte("Failed")},
+		}},
+		{"bad syntax", fstest.MapFS{
+			"en/order_failed.subject.tmpl": {Data: []byte("{{.OrderID")},
+			"en/order_failed.txt.tmpl":     {Data: []byte("Failed")},
+		}},
+		{"unnamed part", fstest.MapFS{
+			"en/order_failed.tmpl": {Data: []byte("Failed")},
+		}},
+	}
+	for _, tt := range tests {
+		_, err := LoadTemplates(tt.fsys, "en")
+		assert.Error(t, err, tt.name)
+	}
+}
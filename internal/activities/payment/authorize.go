@@ -0,0 +1,69 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/middleware"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// AuthorizePaymentInput is the input for authorizing a payment
+type AuthorizePaymentInput struct {
+	OrderID       string
+	Amount        decimal.Decimal
+	PaymentMethod domain.PaymentMethod
+	CustomerID    string
+	Currency      string // Empty uses DefaultCurrency
+}
+
+// AuthorizePaymentOutput is the output of authorizing a payment
+type AuthorizePaymentOutput struct {
+	PaymentID     string
+	TransactionID string
+	Status        string
+}
+
+// AuthorizePaymentActivity holds the order amount on the customer's payment
+// method without capturing it
+func AuthorizePaymentActivity(gateway PaymentGateway) func(ctx context.Context, input []byte) ([]byte, error) {
+	return func(ctx context.Context, input []byte) ([]byte, error) {
+		var inp AuthorizePaymentInput
+		if err := json.Unmarshal(input, &inp); err != nil {
+			return nil, errors.NewPermanentError("INVALID_INPUT", "failed to unmarshal authorize input", err)
+		}
+
+		idempotencyKey := middleware.IdempotencyKeyFromContext(ctx)
+		if idempotencyKey == "" {
+			idempotencyKey = fmt.Sprintf("authorize/%s", inp.OrderID)
+		}
+
+		transactionID, err := gateway.Authorize(ctx, PaymentRequest{
+			OrderID:        inp.OrderID,
+			CustomerID:     inp.CustomerID,
+			Amount:         inp.Amount,
+			Currency:       inp.Currency,
+			Method:         inp.PaymentMethod,
+			IdempotencyKey: idempotencyKey,
+		})
+		if err != nil {
+			return nil, gatewayError("PAYMENT_AUTHORIZATION_FAILED", "failed to authorize payment", err)
+		}
+
+		output := AuthorizePaymentOutput{
+			PaymentID:     fmt.Sprintf("PAY_%s", inp.OrderID),
+			TransactionID: transactionID,
+			Status:        string(domain.PaymentStatusAuthorized),
+		}
+
+		result, err := json.Marshal(output)
+		if err != nil {
+			return nil, errors.NewPermanentError("SERIALIZATION_ERROR", "failed to marshal authorize output", err)
+		}
+
+		return result, nil
+	}
+}
@@ -0,0 +1,76 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/middleware"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// CapturePaymentInput is the input for capturing an authorized payment
+type CapturePaymentInput struct {
+	PaymentID     string
+	TransactionID string
+	Amount        decimal.Decimal
+}
+
+// CapturePaymentOutput is the output of capturing an authorized payment
+type CapturePaymentOutput struct {
+	PaymentID     string
+	TransactionID string
+	Amount        decimal.Decimal
+	Status        string
+}
+
+// CapturePaymentActivity captures a previously authorized payment
+func CapturePaymentActivity(gateway PaymentGateway) func(ctx context.Context, input []byte) ([]byte, error) {
+	return func(ctx context.Context, input []byte) ([]byte, error) {
+		var inp CapturePaymentInput
+		if err := json.Unmarshal(input, &inp); err != nil {
+			return nil, errors.NewPermanentError("INVALID_INPUT", "failed to unmarshal capture input", err)
+		}
+
+		if inp.TransactionID == "" {
+			return nil, errors.NewPermanentError("MISSING_TRANSACTION_ID", "transaction ID is required", nil)
+		}
+		if inp.Amount.LessThanOrEqual(decimal.Zero) {
+			return nil, errors.NewPermanentError("INVALID_AMOUNT", "capture amount must be greater than zero", nil)
+		}
+
+		idempotencyKey := middleware.IdempotencyKeyFromContext(ctx)
+		if idempotencyKey == "" {
+			idempotencyKey = fmt.Sprintf("capture/%s/%s", inp.TransactionID, inp.Amount)
+		}
+
+		err := gateway.Capture(ctx, inp.TransactionID, inp.Amount, idempotencyKey)
+		switch {
+		case stderrors.Is(err, ErrTransactionNotFound):
+			return nil, errors.NewPermanentError("TRANSACTION_NOT_FOUND", fmt.Sprintf("transaction %s not found", inp.TransactionID), err)
+		case stderrors.Is(err, ErrNotAuthorized):
+			return nil, errors.NewPermanentError("PAYMENT_NOT_AUTHORIZED", fmt.Sprintf("transaction %s is not authorized", inp.TransactionID), err)
+		case stderrors.Is(err, ErrCaptureExceedsAuthorized):
+			return nil, errors.NewPermanentError("CAPTURE_EXCEEDS_AUTHORIZED", fmt.Sprintf("capture of %s exceeds authorized amount", inp.Amount), err)
+		case err != nil:
+			return nil, gatewayError("CAPTURE_FAILED", "failed to capture payment", err)
+		}
+
+		output := CapturePaymentOutput{
+			PaymentID:     inp.PaymentID,
+			TransactionID: inp.TransactionID,
+			Amount:        inp.Amount,
+			Status:        string(domain.PaymentStatusCompleted),
+		}
+
+		result, err := json.Marshal(output)
+		if err != nil {
+			return nil, errors.NewPermanentError("SERIALIZATION_ERROR", "failed to marshal capture output", err)
+		}
+
+		return result, nil
+	}
+}
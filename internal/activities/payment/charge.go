@@ -4,10 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math/rand"
 
 	"github.com/shopspring/decimal"
 	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/middleware"
 	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
 )
 
@@ -17,6 +17,7 @@ type ChargePaymentInput struct {
 	Amount        decimal.Decimal
 	PaymentMethod domain.PaymentMethod
 	CustomerID    string
+	Currency      string // Empty uses DefaultCurrency
 }
 
 // ChargePaymentOutput is the output of charging a payment
@@ -26,11 +27,6 @@ type ChargePaymentOutput struct {
 	Status        string
 }
 
-// PaymentGateway simulates an external payment processor
-type PaymentGateway interface {
-	Charge(ctx context.Context, amount decimal.Decimal, method domain.PaymentMethod) (string, error)
-}
-
 // ChargePaymentActivity charges a payment for an order
 func ChargePaymentActivity(gateway PaymentGateway) func(ctx context.Context, input []byte) ([]byte, error) {
 	return func(ctx context.Context, input []byte) ([]byte, error) {
@@ -39,29 +35,27 @@ func ChargePaymentActivity(gateway PaymentGateway) func(ctx context.Context, inp
 			return nil, errors.NewPermanentError("INVALID_INPUT", "failed to unmarshal payment input", err)
 		}
 
-		// Simulate occasional payment gateway failures
-		if rand.Float64() < 0.1 { // 10% chance of transient failure
-			return nil, errors.NewTransientError(
-				"PAYMENT_GATEWAY_UNAVAILABLE",
-				"payment gateway temporarily unavailable",
-				fmt.Errorf("network timeout"),
-			)
+		idempotencyKey := middleware.IdempotencyKeyFromContext(ctx)
+		if idempotencyKey == "" {
+			idempotencyKey = fmt.Sprintf("charge/%s", inp.OrderID)
 		}
 
-		transactionID, err := gateway.Charge(ctx, inp.Amount, inp.PaymentMethod)
+		transactionID, err := gateway.Charge(ctx, PaymentRequest{
+			OrderID:        inp.OrderID,
+			CustomerID:     inp.CustomerID,
+			Amount:         inp.Amount,
+			Currency:       inp.Currency,
+			Method:         inp.PaymentMethod,
+			IdempotencyKey: idempotencyKey,
+		})
 		if err != nil {
-			// Classify error based on type
-			return nil, errors.NewTransientError(
-				"PAYMENT_PROCESSING_ERROR",
-				fmt.Sprintf("failed to process payment: %v", err),
-				err,
-			)
+			return nil, gatewayError("PAYMENT_PROCESSING_ERROR", "failed to process payment", err)
 		}
 
 		output := ChargePaymentOutput{
 			PaymentID:     fmt.Sprintf("PAY_%s", inp.OrderID),
 			TransactionID: transactionID,
-			Status:        "completed",
+			Status:        string(domain.PaymentStatusCompleted),
 		}
 
 		result, err := json.Marshal(output)
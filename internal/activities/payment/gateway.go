@@ -0,0 +1,86 @@
+package payment
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// DefaultCurrency is charged when a PaymentRequest has no currency
+const DefaultCurrency = "USD"
+
+// Errors returned by PaymentGateway implementations. Implementations may wrap
+// them in a *errors.CustomError that already classifies the failure.
+var (
+	ErrTransactionNotFound      = stderrors.New("transaction not found")
+	ErrRefundExceedsCaptured    = stderrors.New("refund exceeds captured amount")
+	ErrCaptureExceedsAuthorized = stderrors.New("capture exceeds authorized amount")
+	ErrNotAuthorized            = stderrors.New("transaction is not authorized")
+	ErrPaymentDeclined          = stderrors.New("payment declined")
+	ErrIdempotencyConflict      = stderrors.New("idempotency key reused with a different request")
+)
+
+// TransactionStatus is the payment processor's state of a transaction
+type TransactionStatus string
+
+const (
+	TransactionAuthorized TransactionStatus = "authorized" // Held, not yet captured
+	TransactionCaptured   TransactionStatus = "captured"
+	TransactionRefunded   TransactionStatus = "refunded" // Captured amount fully refunded
+	TransactionVoided     TransactionStatus = "voided"   // Authorization released without capturing
+)
+
+// PaymentRequest describes a charge or an authorization
+type PaymentRequest struct {
+	OrderID        string
+	CustomerID     string
+	Amount         decimal.Decimal
+	Currency       string // ISO 4217 code; empty uses DefaultCurrency
+	Method         domain.PaymentMethod
+	IdempotencyKey string // Requests repeated with the same key must not charge again
+}
+
+// Transaction is the payment processor's record of a transaction
+type Transaction struct {
+	ID         string
+	Status     TransactionStatus
+	Currency   string
+	Authorized decimal.Decimal // Amount held when the transaction was authorized
+	Amount     decimal.Decimal // Captured amount
+	Refunded   decimal.Decimal // Cumulative amount refunded so far
+}
+
+// PaymentGateway is an external payment processor
+type PaymentGateway interface {
+	// Charge authorizes and captures req.Amount in one step and returns the
+	// transaction ID
+	Charge(ctx context.Context, req PaymentRequest) (string, error)
+	// Authorize holds req.Amount on the customer's payment method without
+	// capturing it and returns the transaction ID
+	Authorize(ctx context.Context, req PaymentRequest) (string, error)
+	// Capture captures amount of an authorized transaction; captures
+	// repeated with the same idempotencyKey must not capture again
+	Capture(ctx context.Context, transactionID string, amount decimal.Decimal, idempotencyKey string) error
+	// Void releases an authorized transaction without capturing it; voids
+	// repeated with the same idempotencyKey must succeed
+	Void(ctx context.Context, transactionID string, idempotencyKey string) error
+	// Refund returns amount of a captured transaction and returns the refund
+	// ID; refunds repeated with the same idempotencyKey must not refund again
+	Refund(ctx context.Context, transactionID string, amount decimal.Decimal, idempotencyKey string) (string, error)
+	// GetStatus looks up a transaction
+	GetStatus(ctx context.Context, transactionID string) (Transaction, error)
+}
+
+// gatewayError returns err as is when the gateway already classified it, and
+// otherwise as a transient error with code
+func gatewayError(code, msg string, err error) error {
+	var custom *errors.CustomError
+	if stderrors.As(err, &custom) {
+		return err
+	}
+	return errors.NewTransientError(code, fmt.Sprintf("%s: %v", msg, err), err)
+}
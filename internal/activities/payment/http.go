@@ -0,0 +1,279 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// DefaultHTTPGatewayTimeout bounds each provider request when
+// HTTPGatewayOptions.Timeout is zero
+const DefaultHTTPGatewayTimeout = 10 * time.Second
+
+// HTTPGatewayOptions configures an HTTPGateway
+type HTTPGatewayOptions struct {
+	BaseURL  string // e.g. https://api.provider.example
+	APIKey   string // Sent as a bearer token
+	Timeout  time.Duration
+	Currency string // Used for requests without a currency; empty uses DefaultCurrency
+	Client   *http.Client
+}
+
+// HTTPGateway is a PaymentGateway for a REST-style payment provider:
+//
+//	POST /v1/payments               create a payment, captured unless "capture" is false
+//	POST /v1/payments/{id}/capture  capture an authorized payment
+//	POST /v1/payments/{id}/void     release an authorized payment
+//	POST /v1/payments/{id}/refunds  refund part of a captured payment
+//	GET  /v1/payments/{id}          look up a payment
+//
+// Every POST carries an Idempotency-Key header, so a request retried after a
+// timeout returns the original result instead of charging twice. Errors are
+// returned as CustomErrors: timeouts, 408, 429 and 5xx replies are transient,
+// other 4xx replies permanent.
+type HTTPGateway struct {
+	baseURL  string
+	apiKey   string
+	currency string
+	client   *http.Client
+}
+
+// NewHTTPGateway creates an HTTP payment gateway
+func NewHTTPGateway(opts HTTPGatewayOptions) (*HTTPGateway, error) {
+	u, err := url.Parse(opts.BaseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid payment gateway URL %q", opts.BaseURL)
+	}
+	if opts.Currency == "" {
+		opts.Currency = DefaultCurrency
+	}
+	client := opts.Client
+	if client == nil {
+		timeout := opts.Timeout
+		if timeout <= 0 {
+			timeout = DefaultHTTPGatewayTimeout
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	return &HTTPGateway{
+		baseURL:  strings.TrimSuffix(opts.BaseURL, "/"),
+		apiKey:   opts.APIKey,
+		currency: opts.Currency,
+		client:   client,
+	}, nil
+}
+
+// paymentRequest is the body of POST /v1/payments
+type paymentRequest struct {
+	OrderID    string               `json:"order_id,omitempty"`
+	CustomerID string               `json:"customer_id,omitempty"`
+	Amount     decimal.Decimal      `json:"amount"`
+	Currency   string               `json:"currency"`
+	Method     domain.PaymentMethod `json:"method,omitempty"`
+	Capture    bool                 `json:"capture"`
+}
+
+// amountRequest is the body of capture and refund requests
+type amountRequest struct {
+	Amount decimal.Decimal `json:"amount"`
+}
+
+// paymentResource is the provider's representation of a payment
+type paymentResource struct {
+	ID         string          `json:"id"`
+	Status     string          `json:"status"`
+	Currency   string          `json:"currency"`
+	Authorized decimal.Decimal `json:"authorized"`
+	Captured   decimal.Decimal `json:"captured"`
+	Refunded   decimal.Decimal `json:"refunded"`
+}
+
+// refundResource is the provider's representation of a refund
+type refundResource struct {
+	ID string `json:"id"`
+}
+
+// errorResponse is the body of a provider error reply
+type errorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Charge creates a captured payment
+func (g *HTTPGateway) Charge(ctx context.Context, req PaymentRequest) (string, error) {
+	return g.createPayment(ctx, req, true)
+}
+
+// Authorize creates an uncaptured payment
+func (g *HTTPGateway) Authorize(ctx context.Context, req PaymentRequest) (string, error) {
+	return g.createPayment(ctx, req, false)
+}
+
+func (g *HTTPGateway) createPayment(ctx context.Context, req PaymentRequest, capture bool) (string, error) {
+	currency := req.Currency
+	if currency == "" {
+		currency = g.currency
+	}
+	body := paymentRequest{
+		OrderID:    req.OrderID,
+		CustomerID: req.CustomerID,
+		Amount:     req.Amount,
+		Currency:   currency,
+		Method:     req.Method,
+		Capture:    capture,
+	}
+
+	var payment paymentResource
+	if err := g.do(ctx, http.MethodPost, "/v1/payments", req.IdempotencyKey, body, &payment); err != nil {
+		return "", err
+	}
+	return payment.ID, nil
+}
+
+// Capture captures amount of an authorized payment
+func (g *HTTPGateway) Capture(ctx context.Context, transactionID string, amount decimal.Decimal, idempotencyKey string) error {
+	path := "/v1/payments/" + url.PathEscape(transactionID) + "/capture"
+	return g.do(ctx, http.MethodPost, path, idempotencyKey, amountRequest{Amount: amount}, nil)
+}
+
+// Void releases an authorized payment
+func (g *HTTPGateway) Void(ctx context.Context, transactionID string, idempotencyKey string) error {
+	path := "/v1/payments/" + url.PathEscape(transactionID) + "/void"
+	return g.do(ctx, http.MethodPost, path, idempotencyKey, struct{}{}, nil)
+}
+
+// Refund refunds amount of a captured payment
+func (g *HTTPGateway) Refund(ctx context.Context, transactionID string, amount decimal.Decimal, idempotencyKey string) (string, error) {
+	path := "/v1/payments/" + url.PathEscape(transactionID) + "/refunds"
+	var refund refundResource
+	if err := g.do(ctx, http.MethodPost, path, idempotencyKey, amountRequest{Amount: amount}, &refund); err != nil {
+		return "", err
+	}
+	return refund.ID, nil
+}
+
+// GetStatus looks up a payment
+func (g *HTTPGateway) GetStatus(ctx context.Context, transactionID string) (Transaction, error) {
+	var payment paymentResource
+	if err := g.do(ctx, http.MethodGet, "/v1/payments/"+url.PathEscape(transactionID), "", nil, &payment); err != nil {
+		return Transaction{}, err
+	}
+	return Transaction{
+		ID:         payment.ID,
+		Status:     TransactionStatus(payment.Status),
+		Currency:   payment.Currency,
+		Authorized: payment.Authorized,
+		Amount:     payment.Captured,
+		Refunded:   payment.Refunded,
+	}, nil
+}
+
+// do sends a request and decodes a successful reply into out
+func (g *HTTPGateway) do(ctx context.Context, method, path, idempotencyKey string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return errors.NewPermanentError("INVALID_INPUT", "failed to marshal payment request", err)
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+path, body)
+	if err != nil {
+		return errors.NewPermanentError("INVALID_INPUT", "failed to create payment request", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if g.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+g.apiKey)
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		// The provider may have processed the request; a retry with the
+		// same idempotency key returns its result
+		return errors.NewTransientError("PAYMENT_GATEWAY_UNAVAILABLE", "payment gateway request failed", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.NewTransientError("PAYMENT_GATEWAY_UNAVAILABLE", "failed to read payment gateway response", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return classifyHTTPError(resp.StatusCode, data)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return errors.NewTransientError("PAYMENT_GATEWAY_UNAVAILABLE", "invalid payment gateway response", err)
+	}
+	return nil
+}
+
+// providerErrors maps provider error codes on 422 replies to gateway errors
+var providerErrors = map[string]struct {
+	code string
+	err  error
+}{
+	"refund_exceeds_captured":    {"REFUND_EXCEEDS_CAPTURED", ErrRefundExceedsCaptured},
+	"capture_exceeds_authorized": {"CAPTURE_EXCEEDS_AUTHORIZED", ErrCaptureExceedsAuthorized},
+	"not_authorized":             {"PAYMENT_NOT_AUTHORIZED", ErrNotAuthorized},
+}
+
+// classifyHTTPError maps a provider error reply to a CustomError. Its cause
+// wraps the matching gateway error, e.g. ErrPaymentDeclined, when there is
+// one.
+func classifyHTTPError(status int, body []byte) error {
+	var reply errorResponse
+	json.Unmarshal(body, &reply)
+	detail := reply.Error.Message
+	if detail == "" {
+		detail = http.StatusText(status)
+	}
+	cause := func(err error) error {
+		return fmt.Errorf("%w: %s", err, detail)
+	}
+	msg := fmt.Sprintf("payment gateway returned %d", status)
+
+	switch {
+	case status == http.StatusPaymentRequired:
+		return errors.NewPermanentError("PAYMENT_DECLINED", "payment declined", cause(ErrPaymentDeclined))
+	case status == http.StatusNotFound:
+		return errors.NewPermanentError("TRANSACTION_NOT_FOUND", msg, cause(ErrTransactionNotFound))
+	case status == http.StatusConflict:
+		return errors.NewPermanentError("IDEMPOTENCY_CONFLICT", msg, cause(ErrIdempotencyConflict))
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return errors.NewPermanentError("PAYMENT_GATEWAY_UNAUTHORIZED", msg, stderrors.New(detail))
+	case status == http.StatusTooManyRequests:
+		return errors.NewTransientError("PAYMENT_RATE_LIMITED", msg, stderrors.New(detail))
+	case status == http.StatusRequestTimeout || status >= 500:
+		return errors.NewTransientError("PAYMENT_GATEWAY_UNAVAILABLE", msg, stderrors.New(detail))
+	case status == http.StatusUnprocessableEntity:
+		if known, ok := providerErrors[reply.Error.Code]; ok {
+			return errors.NewPermanentError(known.code, msg, cause(known.err))
+		}
+	}
+	return errors.NewPermanentError("PAYMENT_REJECTED", msg, stderrors.New(detail))
+}
@@ -0,0 +1,271 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+	"github.com/Youmanvi/taskorchestrator/test/fakes"
+)
+
+func newTestHTTPGateway(t *testing.T, timeout time.Duration) (*HTTPGateway, *fakes.PaymentProvider) {
+	t.Helper()
+	provider := fakes.NewPaymentProvider("sk_test")
+	t.Cleanup(provider.Close)
+	gateway, err := NewHTTPGateway(HTTPGatewayOptions{
+		BaseURL:  provider.URL(),
+		APIKey:   "sk_test",
+		Timeout:  timeout,
+		Currency: "EUR",
+	})
+	require.NoError(t, err)
+	return gateway, provider
+}
+
+func testPaymentRequest(key string) PaymentRequest {
+	return PaymentRequest{
+		OrderID:        "ORD-1",
+		CustomerID:     "CUST-1",
+		Amount:         decimal.RequireFromString("25.50"),
+		Method:         domain.PaymentMethodCard,
+		IdempotencyKey: key,
+	}
+}
+
+func requireCode(t *testing.T, err error, code string, permanent bool) {
+	t.Helper()
+	var custom *errors.CustomError
+	require.ErrorAs(t, err, &custom)
+	assert.Equal(t, code, custom.Code)
+	assert.Equal(t, permanent, custom.IsPermanent())
+}
+
+func TestHTTPGatewayChargeRefundAndStatus(t *testing.T) {
+	gateway, provider := newTestHTTPGateway(t, time.Second)
+	ctx := context.Background()
+
+	txnID, err := gateway.Charge(ctx, testPaymentRequest("charge-1"))
+	require.NoError(t, err)
+
+	payments := provider.Payments()
+	require.Len(t, payments, 1)
+	assert.Equal(t, "CUST-1", payments[0].CustomerID)
+	assert.Equal(t, "EUR", payments[0].Currency)
+
+	refundID, err := gateway.Refund(ctx, txnID, decimal.RequireFromString("5.50"), "refund-1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, refundID)
+
+	txn, err := gateway.GetStatus(ctx, txnID)
+	require.NoError(t, err)
+	assert.Equal(t, TransactionCaptured, txn.Status)
+	assert.True(t, decimal.RequireFromString("25.50").Equal(txn.Amount))
+	assert.True(t, decimal.RequireFromString("5.50").Equal(txn.Refunded))
+
+	_, err = gateway.Refund(ctx, txnID, decimal.RequireFromString("30"), "refund-2")
+	requireCode(t, err, "REFUND_EXCEEDS_CAPTURED", true)
+	assert.True(t, stderrors.Is(err, ErrRefundExceedsCaptured))
+
+	_, err = gateway.GetStatus(ctx, "pay_missing")
+	requireCode(t, err, "TRANSACTION_NOT_FOUND", true)
+	assert.True(t, stderrors.Is(err, ErrTransactionNotFound))
+}
+
+func TestHTTPGatewayAuthorizeAndCapture(t *testing.T) {
+	gateway, _ := newTestHTTPGateway(t, time.Second)
+	ctx := context.Background()
+
+	txnID, err := gateway.Authorize(ctx, testPaymentRequest("auth-1"))
+	require.NoError(t, err)
+	txn, err := gateway.GetStatus(ctx, txnID)
+	require.NoError(t, err)
+	assert.Equal(t, TransactionAuthorized, txn.Status)
+	assert.True(t, txn.Amount.IsZero())
+
+	// Nothing captured, nothing to refund
+	_, err = gateway.Refund(ctx, txnID, decimal.RequireFromString("1"), "refund-1")
+	requireCode(t, err, "REFUND_EXCEEDS_CAPTURED", true)
+
+	err = gateway.Capture(ctx, txnID, decimal.RequireFromString("30"), "capture-1")
+	requireCode(t, err, "CAPTURE_EXCEEDS_AUTHORIZED", true)
+
+	require.NoError(t, gateway.Capture(ctx, txnID, decimal.RequireFromString("25.50"), "capture-2"))
+	// A retried capture is replayed rather than rejected
+	require.NoError(t, gateway.Capture(ctx, txnID, decimal.RequireFromString("25.50"), "capture-2"))
+	txn, err = gateway.GetStatus(ctx, txnID)
+	require.NoError(t, err)
+	assert.Equal(t, TransactionCaptured, txn.Status)
+
+	err = gateway.Capture(ctx, txnID, decimal.RequireFromString("25.50"), "capture-3")
+	requireCode(t, err, "PAYMENT_NOT_AUTHORIZED", true)
+	assert.True(t, stderrors.Is(err, ErrNotAuthorized))
+}
+
+func TestHTTPGatewayDecline(t *testing.T) {
+	gateway, provider := newTestHTTPGateway(t, time.Second)
+
+	provider.DeclineNext()
+	_, err := gateway.Charge(context.Background(), testPaymentRequest("charge-1"))
+	requireCode(t, err, "PAYMENT_DECLINED", true)
+	assert.True(t, stderrors.Is(err, ErrPaymentDeclined))
+	assert.Empty(t, provider.Payments())
+}
+
+func TestHTTPGatewayTransientFailures(t *testing.T) {
+	gateway, provider := newTestHTTPGateway(t, time.Second)
+	ctx := context.Background()
+
+	provider.FailNext(http.StatusServiceUnavailable)
+	_, err := gateway.Charge(ctx, testPaymentRequest("charge-1"))
+	requireCode(t, err, "PAYMENT_GATEWAY_UNAVAILABLE", false)
+
+	provider.FailNext(http.StatusTooManyRequests)
+	_, err = gateway.Charge(ctx, testPaymentRequest("charge-1"))
+	requireCode(t, err, "PAYMENT_RATE_LIMITED", false)
+
+	// The retry goes through once the provider recovers
+	_, err = gateway.Charge(ctx, testPaymentRequest("charge-1"))
+	require.NoError(t, err)
+	assert.Len(t, provider.Payments(), 1)
+}
+
+func TestHTTPGatewayTimeoutRetryDoesNotChargeTwice(t *testing.T) {
+	gateway, provider := newTestHTTPGateway(t, 50*time.Millisecond)
+	ctx := context.Background()
+
+	// The provider charges but replies after the client gave up
+	provider.DelayNext(200 * time.Millisecond)
+	_, err := gateway.Charge(ctx, testPaymentRequest("charge-1"))
+	requireCode(t, err, "PAYMENT_GATEWAY_UNAVAILABLE", false)
+
+	// Wait for the delayed charge to land, then retry with the same key
+	require.Eventually(t, func() bool { return len(provider.Payments()) == 1 }, time.Second, 10*time.Millisecond)
+	txnID, err := gateway.Charge(ctx, testPaymentRequest("charge-1"))
+	require.NoError(t, err)
+	assert.Equal(t, provider.Payments()[0].ID, txnID)
+	assert.Len(t, provider.Payments(), 1)
+}
+
+func TestHTTPGatewayDuplicateRequests(t *testing.T) {
+	gateway, provider := newTestHTTPGateway(t, time.Second)
+	ctx := context.Background()
+
+	first, err := gateway.Charge(ctx, testPaymentRequest("charge-1"))
+	require.NoError(t, err)
+	second, err := gateway.Charge(ctx, testPaymentRequest("charge-1"))
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Len(t, provider.Payments(), 1)
+
+	// The same key with a different amount is a conflict, not a new charge
+	req := testPaymentRequest("charge-1")
+	req.Amount = decimal.RequireFromString("99")
+	_, err = gateway.Charge(ctx, req)
+	requireCode(t, err, "IDEMPOTENCY_CONFLICT", true)
+	assert.True(t, stderrors.Is(err, ErrIdempotencyConflict))
+	assert.Len(t, provider.Payments(), 1)
+}
+
+func TestHTTPGatewayRejectsBadCredentials(t *testing.T) {
+	provider := fakes.NewPaymentProvider("sk_test")
+	defer provider.Close()
+	gateway, err := NewHTTPGateway(HTTPGatewayOptions{BaseURL: provider.URL(), APIKey: "sk_wrong"})
+	require.NoError(t, err)
+
+	_, err = gateway.Charge(context.Background(), testPaymentRequest("charge-1"))
+	requireCode(t, err, "PAYMENT_GATEWAY_UNAUTHORIZED", true)
+
+	_, err = NewHTTPGateway(HTTPGatewayOptions{BaseURL: "not a url"})
+	assert.Error(t, err)
+}
+
+func TestChargeActivityPassesThroughGatewayErrors(t *testing.T) {
+	gateway, provider := newTestHTTPGateway(t, time.Second)
+	charge := ChargePaymentActivity(gateway)
+
+	provider.DeclineNext()
+	_, err := charge(context.Background(), []byte(`{"OrderID":"ORD-1","Amount":"25.50","PaymentMethod":"card","CustomerID":"CUST-1"}`))
+	requireCode(t, err, "PAYMENT_DECLINED", true)
+
+	// Errors the gateway did not classify stay transient
+	mock := NewMockPaymentGateway()
+	mock.SetFailureRate(0)
+	mock.SetChargeError(stderrors.New("connection reset"))
+	_, err = ChargePaymentActivity(mock)(context.Background(), []byte(`{"OrderID":"ORD-1","Amount":"25.50"}`))
+	requireCode(t, err, "PAYMENT_PROCESSING_ERROR", false)
+}
+
+func TestHTTPGatewayVoid(t *testing.T) {
+	gateway, _ := newTestHTTPGateway(t, time.Second)
+	ctx := context.Background()
+
+	txnID, err := gateway.Authorize(ctx, testPaymentRequest("auth-1"))
+	require.NoError(t, err)
+	require.NoError(t, gateway.Void(ctx, txnID, "void-1"))
+	// A retried void is replayed rather than rejected
+	require.NoError(t, gateway.Void(ctx, txnID, "void-1"))
+
+	txn, err := gateway.GetStatus(ctx, txnID)
+	require.NoError(t, err)
+	assert.Equal(t, TransactionVoided, txn.Status)
+
+	err = gateway.Capture(ctx, txnID, decimal.RequireFromString("25.50"), "capture-1")
+	requireCode(t, err, "PAYMENT_NOT_AUTHORIZED", true)
+
+	// A captured payment can only be refunded
+	txnID, err = gateway.Charge(ctx, testPaymentRequest("charge-1"))
+	require.NoError(t, err)
+	err = gateway.Void(ctx, txnID, "void-2")
+	requireCode(t, err, "PAYMENT_NOT_AUTHORIZED", true)
+}
+
+func TestAuthorizeCaptureAndVoidActivities(t *testing.T) {
+	gateway, _ := newTestHTTPGateway(t, time.Second)
+	ctx := context.Background()
+	authorize := AuthorizePaymentActivity(gateway)
+	capture := CapturePaymentActivity(gateway)
+	void := VoidPaymentActivity(gateway)
+
+	result, err := authorize(ctx, []byte(`{"OrderID":"ORD-1","Amount":"25.50","PaymentMethod":"card","CustomerID":"CUST-1"}`))
+	require.NoError(t, err)
+	var authorized AuthorizePaymentOutput
+	require.NoError(t, json.Unmarshal(result, &authorized))
+	assert.Equal(t, "PAY_ORD-1", authorized.PaymentID)
+	assert.Equal(t, string(domain.PaymentStatusAuthorized), authorized.Status)
+
+	input, _ := json.Marshal(CapturePaymentInput{
+		PaymentID:     authorized.PaymentID,
+		TransactionID: authorized.TransactionID,
+		Amount:        decimal.RequireFromString("30"),
+	})
+	_, err = capture(ctx, input)
+	requireCode(t, err, "CAPTURE_EXCEEDS_AUTHORIZED", true)
+
+	input, _ = json.Marshal(VoidPaymentInput{PaymentID: authorized.PaymentID, TransactionID: authorized.TransactionID})
+	result, err = void(ctx, input)
+	require.NoError(t, err)
+	var voided VoidPaymentOutput
+	require.NoError(t, json.Unmarshal(result, &voided))
+	assert.Equal(t, string(domain.PaymentStatusVoided), voided.Status)
+
+	// Nothing is left to capture once the authorization is voided
+	input, _ = json.Marshal(CapturePaymentInput{
+		PaymentID:     authorized.PaymentID,
+		TransactionID: authorized.TransactionID,
+		Amount:        decimal.RequireFromString("25.50"),
+	})
+	_, err = capture(ctx, input)
+	requireCode(t, err, "PAYMENT_NOT_AUTHORIZED", true)
+
+	_, err = void(ctx, []byte(`{"PaymentID":"PAY_ORD-1"}`))
+	requireCode(t, err, "MISSING_TRANSACTION_ID", true)
+}
@@ -3,37 +3,249 @@ package payment
 import (
 	"context"
 	"fmt"
+	"math/rand"
+	"sync"
 
 	"github.com/shopspring/decimal"
-	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
 )
 
+// DefaultMockFailureRate is the share of charges and authorizations the mock
+// fails with a transient error, simulating an unreliable network
+const DefaultMockFailureRate = 0.1
+
 // MockPaymentGateway is a mock implementation of PaymentGateway for testing
 type MockPaymentGateway struct {
-	transactions map[string]decimal.Decimal
+	mu           sync.Mutex
+	transactions map[string]*Transaction
+	idempotency  map[string]string // idempotency key -> transaction ID
+	refunds      map[string]string // idempotency key -> refund ID
+	captures     map[string]bool   // idempotency keys of completed captures
+	voids        map[string]bool   // idempotency keys of completed voids
+	refundCount  int
+	chargeErr    error
+	captureErr   error
+	failureRate  float64
 }
 
 // NewMockPaymentGateway creates a new mock payment gateway
 func NewMockPaymentGateway() *MockPaymentGateway {
 	return &MockPaymentGateway{
-		transactions: make(map[string]decimal.Decimal),
+		transactions: make(map[string]*Transaction),
+		idempotency:  make(map[string]string),
+		refunds:      make(map[string]string),
+		captures:     make(map[string]bool),
+		voids:        make(map[string]bool),
+		failureRate:  DefaultMockFailureRate,
+	}
+}
+
+// Charge simulates authorizing and capturing a payment. A repeated
+// idempotency key returns the original transaction.
+func (m *MockPaymentGateway) Charge(ctx context.Context, req PaymentRequest) (string, error) {
+	return m.open(req, TransactionCaptured)
+}
+
+// Authorize simulates holding a payment. A repeated idempotency key returns
+// the original transaction.
+func (m *MockPaymentGateway) Authorize(ctx context.Context, req PaymentRequest) (string, error) {
+	return m.open(req, TransactionAuthorized)
+}
+
+// open records a new transaction in status
+func (m *MockPaymentGateway) open(req PaymentRequest, status TransactionStatus) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.chargeErr != nil {
+		return "", m.chargeErr
+	}
+
+	if txnID, ok := m.idempotency[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		return txnID, nil
+	}
+
+	if rand.Float64() < m.failureRate {
+		return "", errors.NewTransientError(
+			"PAYMENT_GATEWAY_UNAVAILABLE",
+			"payment gateway temporarily unavailable",
+			fmt.Errorf("network timeout"),
+		)
+	}
+
+	if req.Amount.LessThanOrEqual(decimal.Zero) {
+		return "", fmt.Errorf("invalid amount")
+	}
+
+	currency := req.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	txn := &Transaction{
+		ID:         fmt.Sprintf("TXN_%d", len(m.transactions)+1),
+		Status:     status,
+		Currency:   currency,
+		Authorized: req.Amount,
+	}
+	if status == TransactionCaptured {
+		txn.Amount = req.Amount
+	}
+	m.transactions[txn.ID] = txn
+	if req.IdempotencyKey != "" {
+		m.idempotency[req.IdempotencyKey] = txn.ID
+	}
+
+	return txn.ID, nil
+}
+
+// Capture simulates capturing part or all of an authorization. A repeated
+// idempotency key does not capture again.
+func (m *MockPaymentGateway) Capture(ctx context.Context, transactionID string, amount decimal.Decimal, idempotencyKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.captureErr != nil {
+		return m.captureErr
+	}
+
+	if m.captures[idempotencyKey] && idempotencyKey != "" {
+		return nil
+	}
+
+	txn, exists := m.transactions[transactionID]
+	if !exists {
+		return ErrTransactionNotFound
+	}
+	if txn.Status != TransactionAuthorized {
+		return ErrNotAuthorized
+	}
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return fmt.Errorf("invalid amount")
 	}
+	if amount.GreaterThan(txn.Authorized) {
+		return ErrCaptureExceedsAuthorized
+	}
+
+	txn.Amount = amount
+	txn.Status = TransactionCaptured
+	if idempotencyKey != "" {
+		m.captures[idempotencyKey] = true
+	}
+	return nil
 }
 
-// Charge simulates charging a payment
-func (m *MockPaymentGateway) Charge(ctx context.Context, amount decimal.Decimal, method domain.PaymentMethod) (string, error) {
+// Void simulates releasing an authorization. A repeated idempotency key
+// does not fail.
+func (m *MockPaymentGateway) Void(ctx context.Context, transactionID string, idempotencyKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.voids[idempotencyKey] && idempotencyKey != "" {
+		return nil
+	}
+
+	txn, exists := m.transactions[transactionID]
+	if !exists {
+		return ErrTransactionNotFound
+	}
+	if txn.Status != TransactionAuthorized {
+		return ErrNotAuthorized
+	}
+
+	txn.Status = TransactionVoided
+	if idempotencyKey != "" {
+		m.voids[idempotencyKey] = true
+	}
+	return nil
+}
+
+// Refund simulates refunding part or all of a transaction. A repeated
+// idempotency key returns the original refund.
+func (m *MockPaymentGateway) Refund(ctx context.Context, transactionID string, amount decimal.Decimal, idempotencyKey string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if refundID, ok := m.refunds[idempotencyKey]; ok && idempotencyKey != "" {
+		return refundID, nil
+	}
+
+	txn, exists := m.transactions[transactionID]
+	if !exists {
+		return "", ErrTransactionNotFound
+	}
 	if amount.LessThanOrEqual(decimal.Zero) {
 		return "", fmt.Errorf("invalid amount")
 	}
 
-	transactionID := fmt.Sprintf("TXN_%d", len(m.transactions)+1)
-	m.transactions[transactionID] = amount
+	refunded := txn.Refunded.Add(amount)
+	if refunded.GreaterThan(txn.Amount) {
+		return "", ErrRefundExceedsCaptured
+	}
+	txn.Refunded = refunded
+	if refunded.Equal(txn.Amount) {
+		txn.Status = TransactionRefunded
+	}
+
+	m.refundCount++
+	refundID := fmt.Sprintf("REFUND_%s_%d", transactionID, m.refundCount)
+	if idempotencyKey != "" {
+		m.refunds[idempotencyKey] = refundID
+	}
+
+	return refundID, nil
+}
+
+// GetStatus returns the status and amounts of a transaction
+func (m *MockPaymentGateway) GetStatus(ctx context.Context, transactionID string) (Transaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	txn, exists := m.transactions[transactionID]
+	if !exists {
+		return Transaction{}, ErrTransactionNotFound
+	}
+	return *txn, nil
+}
+
+// SetChargeError makes every subsequent Charge and Authorize fail with err
+// (nil to reset)
+func (m *MockPaymentGateway) SetChargeError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chargeErr = err
+}
+
+// SetCaptureError makes every subsequent Capture fail with err (nil to
+// reset)
+func (m *MockPaymentGateway) SetCaptureError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.captureErr = err
+}
+
+// SetFailureRate sets the share of charges and authorizations that fail with
+// a transient error (0 to disable)
+func (m *MockPaymentGateway) SetFailureRate(rate float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failureRate = rate
+}
 
-	return transactionID, nil
+// TransactionCount returns the number of distinct charges and authorizations
+// made
+func (m *MockPaymentGateway) TransactionCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.transactions)
 }
 
-// GetTransaction retrieves a transaction
+// GetTransaction retrieves a transaction's captured amount
 func (m *MockPaymentGateway) GetTransaction(txnID string) (decimal.Decimal, bool) {
-	amount, exists := m.transactions[txnID]
-	return amount, exists
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	txn, exists := m.transactions[txnID]
+	if !exists {
+		return decimal.Zero, false
+	}
+	return txn.Amount, true
 }
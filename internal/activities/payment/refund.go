@@ -3,25 +3,29 @@ package payment
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 
 	"github.com/shopspring/decimal"
+	"github.com/Youmanvi/taskorchestrator/internal/middleware"
 	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
 )
 
 // RefundPaymentInput is the input for refunding a payment
 type RefundPaymentInput struct {
-	PaymentID string
-	Amount    decimal.Decimal
+	PaymentID     string
+	TransactionID string
+	Amount        decimal.Decimal
 }
 
 // RefundPaymentOutput is the output of refunding a payment
 type RefundPaymentOutput struct {
 	RefundID string
+	Amount   decimal.Decimal
 	Status   string
 }
 
-// RefundPaymentActivity refunds a previously charged payment
+// RefundPaymentActivity refunds part or all of a previously charged payment
 func RefundPaymentActivity(gateway PaymentGateway) func(ctx context.Context, input []byte) ([]byte, error) {
 	return func(ctx context.Context, input []byte) ([]byte, error) {
 		var inp RefundPaymentInput
@@ -32,12 +36,31 @@ func RefundPaymentActivity(gateway PaymentGateway) func(ctx context.Context, inp
 		if inp.PaymentID == "" {
 			return nil, errors.NewPermanentError("MISSING_PAYMENT_ID", "payment ID is required", nil)
 		}
+		if inp.TransactionID == "" {
+			return nil, errors.NewPermanentError("MISSING_TRANSACTION_ID", "transaction ID is required", nil)
+		}
+		if inp.Amount.LessThanOrEqual(decimal.Zero) {
+			return nil, errors.NewPermanentError("INVALID_AMOUNT", "refund amount must be greater than zero", nil)
+		}
 
-		// Simulate refund processing
-		refundID := fmt.Sprintf("REFUND_%s", inp.PaymentID)
+		idempotencyKey := middleware.IdempotencyKeyFromContext(ctx)
+		if idempotencyKey == "" {
+			idempotencyKey = fmt.Sprintf("refund/%s/%s", inp.PaymentID, inp.Amount)
+		}
+
+		refundID, err := gateway.Refund(ctx, inp.TransactionID, inp.Amount, idempotencyKey)
+		switch {
+		case stderrors.Is(err, ErrTransactionNotFound):
+			return nil, errors.NewPermanentError("TRANSACTION_NOT_FOUND", fmt.Sprintf("transaction %s not found", inp.TransactionID), err)
+		case stderrors.Is(err, ErrRefundExceedsCaptured):
+			return nil, errors.NewPermanentError("REFUND_EXCEEDS_CAPTURED", fmt.Sprintf("refund of %s exceeds captured amount", inp.Amount), err)
+		case err != nil:
+			return nil, gatewayError("REFUND_FAILED", "failed to refund payment", err)
+		}
 
 		output := RefundPaymentOutput{
 			RefundID: refundID,
+			Amount:   inp.Amount,
 			Status:   "completed",
 		}
 
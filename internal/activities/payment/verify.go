@@ -3,24 +3,29 @@ package payment
 import (
 	"context"
 	"encoding/json"
+	stderrors "errors"
+	"fmt"
 
 	"github.com/shopspring/decimal"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
 	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
 )
 
 // VerifyPaymentInput is the input for verifying a payment
 type VerifyPaymentInput struct {
-	PaymentID string
+	PaymentID     string
+	TransactionID string
 }
 
 // VerifyPaymentOutput is the output of verifying a payment
 type VerifyPaymentOutput struct {
-	PaymentID string
-	Status    string
-	Amount    decimal.Decimal
+	PaymentID      string
+	Status         string // domain.PaymentStatusCompleted, Refunded once fully refunded, Authorized or Voided
+	Amount         decimal.Decimal
+	RefundedAmount decimal.Decimal
 }
 
-// VerifyPaymentActivity verifies the status of a payment
+// VerifyPaymentActivity verifies the status of a payment with the gateway
 func VerifyPaymentActivity(gateway PaymentGateway) func(ctx context.Context, input []byte) ([]byte, error) {
 	return func(ctx context.Context, input []byte) ([]byte, error) {
 		var inp VerifyPaymentInput
@@ -31,12 +36,37 @@ func VerifyPaymentActivity(gateway PaymentGateway) func(ctx context.Context, inp
 		if inp.PaymentID == "" {
 			return nil, errors.NewPermanentError("MISSING_PAYMENT_ID", "payment ID is required", nil)
 		}
+		if inp.TransactionID == "" {
+			return nil, errors.NewPermanentError("MISSING_TRANSACTION_ID", "transaction ID is required", nil)
+		}
+
+		txn, err := gateway.GetStatus(ctx, inp.TransactionID)
+		if stderrors.Is(err, ErrTransactionNotFound) {
+			return nil, errors.NewPermanentError("TRANSACTION_NOT_FOUND", fmt.Sprintf("transaction %s not found", inp.TransactionID), err)
+		}
+		if err != nil {
+			return nil, gatewayError("PAYMENT_VERIFY_FAILED", "failed to verify payment", err)
+		}
+
+		var status domain.PaymentStatus
+		switch txn.Status {
+		case TransactionAuthorized:
+			status = domain.PaymentStatusAuthorized
+		case TransactionVoided:
+			status = domain.PaymentStatusVoided
+		case TransactionCaptured:
+			status = domain.PaymentStatusCompleted
+		case TransactionRefunded:
+			status = domain.PaymentStatusRefunded
+		default:
+			return nil, errors.NewPermanentError("UNKNOWN_PAYMENT_STATUS", fmt.Sprintf("transaction %s has unknown status %q", inp.TransactionID, txn.Status), nil)
+		}
 
-		// Simulate verification
 		output := VerifyPaymentOutput{
-			PaymentID: inp.PaymentID,
-			Status:    "completed",
-			Amount:    decimal.Zero,
+			PaymentID:      inp.PaymentID,
+			Status:         string(status),
+			Amount:         txn.Amount,
+			RefundedAmount: txn.Refunded,
 		}
 
 		result, err := json.Marshal(output)
@@ -0,0 +1,69 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/middleware"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// VoidPaymentInput is the input for voiding an authorized payment
+type VoidPaymentInput struct {
+	PaymentID     string
+	TransactionID string
+}
+
+// VoidPaymentOutput is the output of voiding an authorized payment
+type VoidPaymentOutput struct {
+	PaymentID     string
+	TransactionID string
+	Status        string
+}
+
+// VoidPaymentActivity releases an authorization that was never captured. It
+// compensates payment:authorize; once a payment is captured it must be
+// refunded instead.
+func VoidPaymentActivity(gateway PaymentGateway) func(ctx context.Context, input []byte) ([]byte, error) {
+	return func(ctx context.Context, input []byte) ([]byte, error) {
+		var inp VoidPaymentInput
+		if err := json.Unmarshal(input, &inp); err != nil {
+			return nil, errors.NewPermanentError("INVALID_INPUT", "failed to unmarshal void input", err)
+		}
+
+		if inp.TransactionID == "" {
+			return nil, errors.NewPermanentError("MISSING_TRANSACTION_ID", "transaction ID is required", nil)
+		}
+
+		idempotencyKey := middleware.IdempotencyKeyFromContext(ctx)
+		if idempotencyKey == "" {
+			idempotencyKey = fmt.Sprintf("void/%s", inp.TransactionID)
+		}
+
+		err := gateway.Void(ctx, inp.TransactionID, idempotencyKey)
+		switch {
+		case stderrors.Is(err, ErrTransactionNotFound):
+			return nil, errors.NewPermanentError("TRANSACTION_NOT_FOUND", fmt.Sprintf("transaction %s not found", inp.TransactionID), err)
+		case stderrors.Is(err, ErrNotAuthorized):
+			return nil, errors.NewPermanentError("PAYMENT_NOT_AUTHORIZED", fmt.Sprintf("transaction %s is not authorized", inp.TransactionID), err)
+		case err != nil:
+			return nil, gatewayError("VOID_FAILED", "failed to void payment", err)
+		}
+
+		output := VoidPaymentOutput{
+			PaymentID:     inp.PaymentID,
+			TransactionID: inp.TransactionID,
+			Status:        string(domain.PaymentStatusVoided),
+		}
+
+		result, err := json.Marshal(output)
+		if err != nil {
+			return nil, errors.NewPermanentError("SERIALIZATION_ERROR", "failed to marshal void output", err)
+		}
+
+		return result, nil
+	}
+}
@@ -1,36 +1,65 @@
 package activities
 
 import (
-	"time"
+	"context"
+	"encoding/json"
 
 	"github.com/microsoft/durabletask-go/task"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/approval"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/deadletter"
 	"github.com/Youmanvi/taskorchestrator/internal/activities/inventory"
 	"github.com/Youmanvi/taskorchestrator/internal/activities/notification"
 	"github.com/Youmanvi/taskorchestrator/internal/activities/payment"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/config"
 	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
 	"github.com/Youmanvi/taskorchestrator/internal/middleware"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ActivityDeps contains dependencies for all activities
 type ActivityDeps struct {
-	Logger          *observability.Logger
-	Metrics         *observability.Metrics
-	PaymentGateway  payment.PaymentGateway
-	InventoryMgr    inventory.InventoryManager
-	EmailService    notification.EmailService
-	RetryPolicy     middleware.RetryPolicy
-	TimeoutDuration time.Duration
+	Logger         *observability.Logger
+	Metrics        *observability.Metrics
+	PaymentGateway payment.PaymentGateway
+	InventoryMgr   inventory.InventoryManager
+	Outbox         notification.Outbox    // Queues notifications for the dispatcher
+	Notifier       *notification.Notifier // Renders notifications and routes them to channels
+	Approvals      approval.ApprovalStore
+	DeadLetters    deadletter.DeadLetterStore
+	Config         config.ActivitiesConfig     // Defaults and per-activity overrides
+	Breakers       *middleware.BreakerRegistry // Shared per-dependency circuit breakers
+	Idempotency    middleware.IdempotencyStore // Records completed step outputs; nil disables
+	Tracer         trace.Tracer                // Records activity spans; nil uses the global provider
 }
 
 // NewActivityRegistry creates and registers all activities with middleware
 func NewActivityRegistry(deps *ActivityDeps) *task.TaskRegistry {
 	registry := task.NewTaskRegistry()
+	if deps.Breakers == nil {
+		deps.Breakers = middleware.NewBreakerRegistry(nil, deps.Metrics)
+	}
+	if deps.Tracer == nil {
+		deps.Tracer = observability.GetTracer(observability.TracerName)
+	}
 
 	// Payment activities
 	registerActivity(registry, "payment:charge",
 		payment.ChargePaymentActivity(deps.PaymentGateway),
 		deps,
 	)
+	registerActivity(registry, "payment:authorize",
+		payment.AuthorizePaymentActivity(deps.PaymentGateway),
+		deps,
+	)
+	registerActivity(registry, "payment:capture",
+		payment.CapturePaymentActivity(deps.PaymentGateway),
+		deps,
+	)
+	registerActivity(registry, "payment:void",
+		payment.VoidPaymentActivity(deps.PaymentGateway),
+		deps,
+	)
 	registerActivity(registry, "payment:refund",
 		payment.RefundPaymentActivity(deps.PaymentGateway),
 		deps,
@@ -49,52 +78,131 @@ func NewActivityRegistry(deps *ActivityDeps) *task.TaskRegistry {
 		inventory.ReleaseInventoryActivity(deps.InventoryMgr),
 		deps,
 	)
+	registerActivity(registry, "inventory:commit",
+		inventory.CommitInventoryActivity(deps.InventoryMgr),
+		deps,
+	)
+	registerActivity(registry, "inventory:restock",
+		inventory.RestockInventoryActivity(deps.InventoryMgr),
+		deps,
+	)
+	registerActivity(registry, "inventory:check_restock",
+		inventory.CheckRestockActivity(deps.InventoryMgr),
+		deps,
+	)
 	registerActivity(registry, "inventory:check",
 		inventory.CheckAvailabilityActivity(deps.InventoryMgr),
 		deps,
 	)
 
+	// Approval activities
+	registerActivity(registry, "approval:request",
+		approval.RequestApprovalActivity(deps.Approvals),
+		deps,
+	)
+	registerActivity(registry, "approval:resolve",
+		approval.ResolveApprovalActivity(deps.Approvals),
+		deps,
+	)
+
+	// Dead-letter activities
+	registerActivity(registry, "deadletter:record",
+		deadletter.RecordActivity(deps.DeadLetters),
+		deps,
+	)
+	registerActivity(registry, "deadletter:resolve",
+		deadletter.ResolveActivity(deps.DeadLetters),
+		deps,
+	)
+
 	// Notification activities
 	registerActivity(registry, "notification:order_confirmation",
-		notification.SendOrderConfirmationActivity(deps.EmailService),
+		notification.SendOrderConfirmationActivity(deps.Outbox, deps.Notifier),
 		deps,
 	)
 	registerActivity(registry, "notification:order_failure",
-		notification.SendOrderFailureActivity(deps.EmailService),
+		notification.SendOrderFailureActivity(deps.Outbox, deps.Notifier),
 		deps,
 	)
 	registerActivity(registry, "notification:refund",
-		notification.SendRefundNotificationActivity(deps.EmailService),
+		notification.SendRefundNotificationActivity(deps.Outbox, deps.Notifier),
 		deps,
 	)
 
 	return registry
 }
 
-// registerActivity registers an activity with middleware
+// registerActivity registers an activity with the middleware chain built from
+// its resolved policy
+//
+// Retries are not applied here: orchestrations retry failed activities with
+// durable timers (see workflows.CallActivityWithRetry), so a worker crash
+// does not lose the retry schedule.
 func registerActivity(registry *task.TaskRegistry, name string, activity middleware.ActivityFunc, deps *ActivityDeps) {
+	policy := deps.Config.PolicyFor(name)
+
 	// Apply middleware chain (order matters - innermost to outermost)
-	wrapped := middleware.ApplyMiddleware(
-		activity,
+	chain := []middleware.ActivityMiddleware{
+		middleware.WithTracing(deps.Tracer, name),
 		middleware.WithLogging(deps.Logger, name),
-		middleware.WithTimeout(deps.TimeoutDuration),
-		// gRPC error handling BEFORE retry so transient errors are classified correctly
-		middleware.WithGRPCErrorHandling(),
-		middleware.WithRetry(deps.Logger, deps.RetryPolicy),
-	)
+	}
+	if deps.Metrics != nil {
+		chain = append(chain, middleware.WithMetrics(deps.Metrics, name))
+	}
+	if deps.Idempotency != nil {
+		chain = append(chain, middleware.WithIdempotency(deps.Idempotency, deps.Logger, name))
+	}
+	chain = append(chain, middleware.WithTimeout(policy.Timeout))
+	if policy.CircuitBreakerEnabled {
+		breaker := deps.Breakers.Register(policy.CircuitBreakerName, name, policy.CircuitBreakerThreshold, policy.CircuitBreakerTimeout)
+		chain = append(chain, middleware.WithSharedCircuitBreaker(breaker, name))
+	}
+	if policy.RateLimitPerSecond > 0 {
+		chain = append(chain, middleware.WithRateLimit(name, policy.RateLimitPerSecond, policy.RateLimitBurst))
+	}
+	// gRPC error handling classifies errors so orchestration retry policies see the right code
+	chain = append(chain, middleware.WithGRPCErrorHandling())
+
+	wrapped := middleware.ApplyMiddleware(activity, chain...)
 
 	// Adapt middleware.ActivityFunc to task.Activity
 	taskActivity := func(ctx task.ActivityContext) (any, error) {
-		// Serialize input
-		var input []byte
-		if err := ctx.GetInput(&input); err != nil {
+		// Unwrap the invocation envelope sent by the orchestrator
+		var inv middleware.Invocation
+		if err := ctx.GetInput(&inv); err != nil {
 			return nil, err
 		}
 
 		// Call the middleware-wrapped activity
-		output, err := wrapped(ctx.Context(), input)
-		return output, err
+		output, err := wrapped(invocationContext(ctx, &inv), inv.Input)
+		if err != nil {
+			// Only the message reaches the orchestration, so carry the error type in it
+			return nil, errors.Annotate(err)
+		}
+
+		// Output is already JSON; pass it through without re-encoding
+		return json.RawMessage(output), nil
 	}
 
 	registry.AddActivityN(name, taskActivity)
 }
+
+// invocationContext places the orchestration instance ID and trace context of
+// an activity execution on its context. The traceparent sent by the
+// orchestration becomes the remote parent so activity spans nest under the
+// orchestration span; the span started by the worker is the fallback.
+func invocationContext(ctx task.ActivityContext, inv *middleware.Invocation) context.Context {
+	actCtx := ctx.Context()
+
+	sc := middleware.ParseTraceParent(inv.TraceParent)
+	if sc.IsValid() {
+		actCtx = trace.ContextWithRemoteSpanContext(actCtx, sc)
+	} else {
+		sc = trace.SpanContextFromContext(actCtx)
+	}
+	if sc.IsValid() {
+		inv.TraceParent = middleware.FormatTraceParent(sc)
+	}
+
+	return middleware.WithInvocation(actCtx, *inv)
+}
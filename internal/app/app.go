@@ -0,0 +1,581 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	dtbackend "github.com/microsoft/durabletask-go/backend"
+	"github.com/microsoft/durabletask-go/task"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/shopspring/decimal"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/Youmanvi/taskorchestrator/internal/activities"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/approval"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/deadletter"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/inventory"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/notification"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/payment"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/backend"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/config"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/idempotency"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+	"github.com/Youmanvi/taskorchestrator/internal/middleware"
+	"github.com/Youmanvi/taskorchestrator/internal/workflows"
+)
+
+// App holds every long-lived component of an orchestrator or worker process
+type App struct {
+	Config   *config.Config
+	Logger   *observability.Logger
+	Metrics  *observability.Metrics
+	Registry *prometheus.Registry
+	Backend  dtbackend.Backend
+	Client   dtbackend.TaskHubClient
+	Worker   dtbackend.TaskHubWorker
+
+	// Breakers holds the per-dependency circuit breakers used by activities
+	Breakers *middleware.BreakerRegistry
+	// Approvals lists and resolves approval requests for high-value orders
+	Approvals approval.ApprovalStore
+	// DeadLetters holds non-critical activity calls that failed after retries
+	DeadLetters deadletter.DeadLetterStore
+	// Outbox holds queued notifications and their delivery status
+	Outbox notification.Outbox
+	// Idempotency records completed activity steps; nil when disabled
+	Idempotency middleware.IdempotencyStore
+
+	logRepo     *observability.LogRepository
+	eventRepo   *observability.TaskEventRepository
+	tracer      *sdktrace.TracerProvider
+	inventory   *inventory.SQLiteInventoryManager
+	approvals   *approval.SQLiteApprovalStore
+	deadLetters *deadletter.SQLiteDeadLetterStore
+	outbox      *notification.SQLiteOutbox
+	dispatcher  *notification.Dispatcher
+	smtp        *notification.SMTPService
+	idemStore   *idempotency.SQLiteStore
+	obsServer   *observability.Server
+	running     atomic.Bool
+}
+
+// New wires configuration, telemetry persistence, tracing, the SQLite backend
+// and the activity/workflow registries into a ready-to-start App. On error
+// everything opened so far is released.
+func New(ctx context.Context, cfg *config.Config) (_ *App, err error) {
+	a := &App{Config: cfg}
+	defer func() {
+		if err != nil {
+			a.release(ctx)
+		}
+	}()
+
+	a.Logger = observability.NewLogger(&cfg.Observability)
+	a.Registry = prometheus.NewRegistry()
+	a.Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	a.Metrics = observability.NewMetrics(a.Registry)
+
+	// Telemetry repositories share a dedicated SQLite file
+	if err := os.MkdirAll(filepath.Dir(cfg.Observability.TelemetryFile), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create telemetry directory: %w", err)
+	}
+
+	logRepo, err := observability.NewLogRepository(cfg.Observability.TelemetryFile, cfg.Observability.TelemetryBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log repository: %w", err)
+	}
+	a.logRepo = logRepo
+	a.Logger.SetLogRepository(logRepo)
+
+	eventRepo, err := observability.NewTaskEventRepository(cfg.Observability.TelemetryFile, cfg.Observability.TelemetryBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open task event repository: %w", err)
+	}
+	a.eventRepo = eventRepo
+	a.Logger.SetTaskEventRepository(eventRepo)
+
+	tp, err := observability.InitializeTracing(ctx, &cfg.Observability, cfg.App.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	a.tracer = tp
+
+	be, err := backend.NewBackend(&cfg.Backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backend: %w", err)
+	}
+	// Orchestration metrics are recorded when work items commit, so replays
+	// of orchestrator code cannot double count
+	a.Backend = backend.WithLifecycleHooks(be, backend.NewMetricsHook(a.Metrics))
+
+	inventoryMgr, err := a.newInventoryManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	idemStore, err := a.newIdempotencyStore()
+	if err != nil {
+		return nil, err
+	}
+
+	approvalThreshold, err := a.newApprovalStore()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.newDeadLetterStore(); err != nil {
+		return nil, err
+	}
+
+	if err := a.newOutbox(); err != nil {
+		return nil, err
+	}
+	notifier, err := a.newNotifier()
+	if err != nil {
+		return nil, err
+	}
+	a.dispatcher = notification.NewDispatcher(a.Outbox, notifier, notification.DispatcherConfig{
+		Interval:     cfg.Notification.DispatchInterval,
+		MaxAttempts:  cfg.Notification.MaxAttempts,
+		RetryBackoff: cfg.Notification.RetryBackoff,
+	}, a.Logger)
+
+	paymentGateway, err := a.newPaymentGateway()
+	if err != nil {
+		return nil, err
+	}
+
+	a.Breakers = middleware.NewBreakerRegistry(middleware.ObserveBreakerState(a.Logger, a.Metrics, a.eventRepo), a.Metrics)
+
+	activityRegistry := activities.NewActivityRegistry(&activities.ActivityDeps{
+		Logger:         a.Logger,
+		Metrics:        a.Metrics,
+		PaymentGateway: paymentGateway,
+		InventoryMgr:   inventoryMgr,
+		Outbox:         a.Outbox,
+		Notifier:       notifier,
+		Approvals:      a.Approvals,
+		DeadLetters:    a.DeadLetters,
+		Config:         cfg.Activities,
+		Breakers:       a.Breakers,
+		Idempotency:    idemStore,
+		Tracer:         tp.Tracer(observability.TracerName),
+	})
+	workflowRegistry := workflows.NewWorkflowRegistry(&workflows.WorkflowDeps{
+		Logger:             a.Logger,
+		Metrics:            a.Metrics,
+		RetryPolicies:      workflows.RetryPoliciesFromConfig(cfg.Activities),
+		CompensationPolicy: workflows.DefaultCompensationPolicy(),
+		Tracer:             tp.Tracer(observability.TracerName),
+		MaxParallelism:     cfg.Inventory.MaxParallelism,
+		ApprovalThreshold:  approvalThreshold,
+		ApprovalTimeout:    cfg.Approval.Timeout,
+	})
+
+	// Orchestrations and activities are registered separately, so each worker
+	// gets an executor over its own registry
+	dtLogger := dtbackend.DefaultLogger()
+	orchestrationWorker := dtbackend.NewOrchestrationWorker(a.Backend, task.NewTaskExecutor(workflowRegistry), dtLogger)
+	activityWorker := dtbackend.NewActivityTaskWorker(a.Backend, task.NewTaskExecutor(activityRegistry), dtLogger)
+
+	a.Worker = dtbackend.NewTaskHubWorker(a.Backend, orchestrationWorker, activityWorker, dtLogger)
+	a.Client = dtbackend.NewTaskHubClient(a.Backend)
+
+	if cfg.Observability.MetricsEnabled {
+		a.obsServer = observability.NewServer(a.Registry, cfg.Observability.MetricsPort, cfg.Observability.PprofEnabled, a.ReadinessChecks()...)
+	}
+
+	return a, nil
+}
+
+// Start starts the task hub worker, then the notification dispatcher and the
+// observability server. If the worker fails to start, neither of the others
+// is started.
+func (a *App) Start(ctx context.Context) error {
+	if err := a.Worker.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start worker: %w", err)
+	}
+	a.running.Store(true)
+	a.Logger.Info("task hub worker started")
+
+	a.dispatcher.Start()
+
+	if a.obsServer != nil {
+		go func() {
+			if err := a.obsServer.ListenAndServe(); err != nil {
+				a.Logger.Error("observability server failed", err)
+			}
+		}()
+		a.Logger.Logger.Info().Int("port", a.Config.Observability.MetricsPort).Msg("observability server started")
+	}
+	return nil
+}
+
+// ReadinessChecks returns the checks behind /readyz: the orchestration
+// backend, both telemetry repositories and the worker loop
+func (a *App) ReadinessChecks() []observability.ReadinessCheck {
+	return []observability.ReadinessCheck{
+		{Name: "backend", Check: func(ctx context.Context) error {
+			return backend.Ping(ctx, a.Backend)
+		}},
+		{Name: "log_repository", Check: func(ctx context.Context) error {
+			if a.logRepo == nil {
+				return errors.New("log repository closed")
+			}
+			return a.logRepo.Ping(ctx)
+		}},
+		{Name: "task_event_repository", Check: func(ctx context.Context) error {
+			if a.eventRepo == nil {
+				return errors.New("task event repository closed")
+			}
+			return a.eventRepo.Ping(ctx)
+		}},
+		{Name: "worker", Check: func(ctx context.Context) error {
+			if !a.running.Load() {
+				return errors.New("worker not running")
+			}
+			return nil
+		}},
+	}
+}
+
+// Shutdown stops accepting new work, waits for in-flight activities to drain,
+// then flushes telemetry and shuts down the tracer provider
+func (a *App) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	// Fail readiness first so supervisors stop routing work here
+	a.running.Store(false)
+
+	a.Logger.Info("shutting down task hub worker")
+	if err := a.Worker.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("worker shutdown: %w", err))
+	}
+
+	if a.obsServer != nil {
+		if err := a.obsServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("observability server shutdown: %w", err))
+		}
+	}
+
+	errs = append(errs, a.closeStores()...)
+	errs = append(errs, a.closeRepositories()...)
+
+	if a.tracer != nil {
+		if err := observability.ShutdownTracing(ctx, a.tracer); err != nil {
+			errs = append(errs, fmt.Errorf("tracer shutdown: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// newPaymentGateway creates the configured payment gateway
+func (a *App) newPaymentGateway() (payment.PaymentGateway, error) {
+	cfg := a.Config.Payment
+	switch cfg.Backend {
+	case "", "mock":
+		return payment.NewMockPaymentGateway(), nil
+	case "http":
+	default:
+		return nil, fmt.Errorf("unsupported payment backend: %s", cfg.Backend)
+	}
+
+	gateway, err := payment.NewHTTPGateway(payment.HTTPGatewayOptions{
+		BaseURL:  cfg.BaseURL,
+		APIKey:   cfg.APIKey,
+		Timeout:  cfg.Timeout,
+		Currency: cfg.Currency,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment configuration: %w", err)
+	}
+	return gateway, nil
+}
+
+// newInventoryManager creates the configured inventory manager, seeding stock
+// levels for the sqlite backend
+func (a *App) newInventoryManager(ctx context.Context) (inventory.InventoryManager, error) {
+	cfg := a.Config.Inventory
+	switch cfg.Backend {
+	case "", "mock":
+		return inventory.NewMockInventoryManager(), nil
+	case "sqlite":
+	default:
+		return nil, fmt.Errorf("unsupported inventory backend: %s", cfg.Backend)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.SQLiteFile), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create inventory directory: %w", err)
+	}
+
+	mgr, err := inventory.NewSQLiteInventoryManager(cfg.SQLiteFile, cfg.ReservationTTL, cfg.SweepInterval, a.Logger)
+	if err != nil {
+		return nil, err
+	}
+	for _, level := range cfg.Stock {
+		if err := mgr.SetStock(ctx, level.SKU, level.Quantity); err != nil {
+			mgr.Close()
+			return nil, err
+		}
+	}
+
+	a.inventory = mgr
+	return mgr, nil
+}
+
+// newIdempotencyStore opens the idempotency store, or returns nil when it is
+// disabled in configuration
+func (a *App) newIdempotencyStore() (middleware.IdempotencyStore, error) {
+	path := a.Config.Activities.IdempotencyFile
+	if path == "" {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create idempotency directory: %w", err)
+	}
+	store, err := idempotency.NewSQLiteStore(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open idempotency store: %w", err)
+	}
+
+	a.idemStore = store
+	a.Idempotency = store
+	return store, nil
+}
+
+// newApprovalStore opens the configured approval store and returns the
+// parsed approval threshold, which is zero when approvals are disabled
+func (a *App) newApprovalStore() (decimal.Decimal, error) {
+	cfg := a.Config.Approval
+	threshold := decimal.Zero
+	if cfg.Threshold != "" {
+		parsed, err := decimal.NewFromString(cfg.Threshold)
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("invalid approval threshold %q: %w", cfg.Threshold, err)
+		}
+		if parsed.IsPositive() && cfg.Timeout <= 0 {
+			return decimal.Zero, fmt.Errorf("approval timeout must be positive when a threshold is set")
+		}
+		threshold = parsed
+	}
+
+	switch cfg.Backend {
+	case "", "mock":
+		a.Approvals = approval.NewMockApprovalStore()
+		return threshold, nil
+	case "sqlite":
+	default:
+		return decimal.Zero, fmt.Errorf("unsupported approval backend: %s", cfg.Backend)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.SQLiteFile), 0755); err != nil {
+		return decimal.Zero, fmt.Errorf("failed to create approval directory: %w", err)
+	}
+	store, err := approval.NewSQLiteApprovalStore(cfg.SQLiteFile)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	a.approvals = store
+	a.Approvals = store
+	return threshold, nil
+}
+
+// newDeadLetterStore opens the dead-letter store, falling back to memory when
+// no file is configured
+func (a *App) newDeadLetterStore() error {
+	path := a.Config.Activities.DeadLetterFile
+	if path == "" {
+		a.DeadLetters = deadletter.NewMockDeadLetterStore()
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+	store, err := deadletter.NewSQLiteDeadLetterStore(path)
+	if err != nil {
+		return err
+	}
+
+	a.deadLetters = store
+	a.DeadLetters = store
+	return nil
+}
+
+// newOutbox opens the notification outbox, falling back to memory when no
+// file is configured
+func (a *App) newOutbox() error {
+	path := a.Config.Notification.OutboxFile
+	if path == "" {
+		a.Outbox = notification.NewMockOutbox()
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+	outbox, err := notification.NewSQLiteOutbox(path)
+	if err != nil {
+		return err
+	}
+
+	a.outbox = outbox
+	a.Outbox = outbox
+	return nil
+}
+
+// newEmailService creates the configured email service
+func (a *App) newEmailService() (notification.EmailService, error) {
+	cfg := a.Config.Notification
+	switch cfg.EmailBackend {
+	case "", "mock":
+		return notification.NewMockEmailService(), nil
+	case "smtp":
+	default:
+		return nil, fmt.Errorf("unsupported email backend: %s", cfg.EmailBackend)
+	}
+
+	svc, err := notification.NewSMTPService(notification.SMTPOptions{
+		Host:               cfg.SMTP.Host,
+		Port:               cfg.SMTP.Port,
+		From:               cfg.SMTP.From,
+		Username:           cfg.SMTP.Username,
+		Password:           cfg.SMTP.Password,
+		StartTLS:           cfg.SMTP.StartTLS,
+		InsecureSkipVerify: cfg.SMTP.InsecureSkipVerify,
+		PoolSize:           cfg.SMTP.PoolSize,
+		IdleTimeout:        cfg.SMTP.IdleTimeout,
+		Timeout:            cfg.SMTP.Timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp configuration: %w", err)
+	}
+	a.smtp = svc
+	return svc, nil
+}
+
+// newNotifier loads the notification templates and registers the configured
+// email channel and the SMS channel, plus the webhook channel when a URL is
+// configured
+func (a *App) newNotifier() (*notification.Notifier, error) {
+	cfg := a.Config.Notification
+
+	fsys := notification.BuiltinTemplates()
+	if cfg.TemplateDir != "" {
+		fsys = os.DirFS(cfg.TemplateDir)
+	}
+	templates, err := notification.LoadTemplates(fsys, cfg.DefaultLocale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification templates: %w", err)
+	}
+
+	email, err := a.newEmailService()
+	if err != nil {
+		return nil, err
+	}
+	channels := []notification.Channel{
+		notification.NewEmailChannel(email),
+		notification.NewSMSChannel(notification.NewMockSMSService()),
+	}
+	if cfg.WebhookURL != "" {
+		channels = append(channels, notification.NewWebhookChannel(cfg.WebhookURL, cfg.WebhookTimeout))
+	}
+
+	notifier, err := notification.NewNotifier(templates, cfg.Routes, channels...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification routes: %w", err)
+	}
+	return notifier, nil
+}
+
+// closeStores stops the notification dispatcher, closes its SMTP connections
+// and closes the inventory, approval, dead-letter, outbox and idempotency
+// stores
+func (a *App) closeStores() []error {
+	var errs []error
+	if a.dispatcher != nil {
+		a.dispatcher.Close()
+		a.dispatcher = nil
+	}
+	if a.smtp != nil {
+		if err := a.smtp.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("smtp close: %w", err))
+		}
+		a.smtp = nil
+	}
+	if a.inventory != nil {
+		if err := a.inventory.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("inventory close: %w", err))
+		}
+		a.inventory = nil
+	}
+	if a.approvals != nil {
+		if err := a.approvals.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("approval store close: %w", err))
+		}
+		a.approvals = nil
+	}
+	if a.deadLetters != nil {
+		if err := a.deadLetters.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("dead-letter store close: %w", err))
+		}
+		a.deadLetters = nil
+	}
+	if a.outbox != nil {
+		if err := a.outbox.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("outbox close: %w", err))
+		}
+		a.outbox = nil
+	}
+	if a.idemStore != nil {
+		if err := a.idemStore.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("idempotency store close: %w", err))
+		}
+		a.idemStore = nil
+	}
+	return errs
+}
+
+// release closes everything New opened when it fails part way: the stores,
+// the telemetry repositories, the backend and the tracer provider
+func (a *App) release(ctx context.Context) {
+	a.closeStores()
+	a.closeRepositories()
+	if a.Backend != nil {
+		a.Backend.Stop(ctx)
+		a.Backend = nil
+	}
+	if a.tracer != nil {
+		observability.ShutdownTracing(ctx, a.tracer)
+		a.tracer = nil
+	}
+}
+
+// closeRepositories flushes and closes the telemetry repositories
+func (a *App) closeRepositories() []error {
+	var errs []error
+	if a.logRepo != nil {
+		if err := a.logRepo.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("log repository close: %w", err))
+		}
+		a.logRepo = nil
+	}
+	if a.eventRepo != nil {
+		if err := a.eventRepo.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("task event repository close: %w", err))
+		}
+		a.eventRepo = nil
+	}
+	return errs
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ApprovalStatus represents the status of an approval request
+type ApprovalStatus string
+
+const (
+	ApprovalStatusPending  ApprovalStatus = "pending"
+	ApprovalStatusApproved ApprovalStatus = "approved"
+	ApprovalStatusRejected ApprovalStatus = "rejected"
+	ApprovalStatusExpired  ApprovalStatus = "expired"
+)
+
+// Approval is a request for a person to approve an order before it is charged
+type Approval struct {
+	OrderID     string
+	CustomerID  string
+	Amount      decimal.Decimal
+	Status      ApprovalStatus
+	RequestedAt time.Time
+	Deadline    time.Time // The order is rejected automatically after this
+	Approver    string
+	Comment     string
+	DecidedAt   time.Time
+	History     []Transition // Status changes, oldest first
+}
+
+// NewApproval creates a pending approval request for an order
+func NewApproval(orderID, customerID string, amount decimal.Decimal, requestedAt, deadline time.Time) (*Approval, error) {
+	if orderID == "" {
+		return nil, fmt.Errorf("order ID cannot be empty")
+	}
+	if !deadline.After(requestedAt) {
+		return nil, fmt.Errorf("approval deadline must be after the request time")
+	}
+
+	return &Approval{
+		OrderID:     orderID,
+		CustomerID:  customerID,
+		Amount:      amount,
+		Status:      ApprovalStatusPending,
+		RequestedAt: requestedAt,
+		Deadline:    deadline,
+	}, nil
+}
+
+// ValidateTransition returns a *TransitionError if the approval may not move
+// to status
+func (a *Approval) ValidateTransition(status ApprovalStatus) error {
+	_, err := transition(approvalTransitions, "approval", a.OrderID, a.Status, status, "", time.Time{})
+	return err
+}
+
+// decide moves the approval to a final status and records who decided and when
+func (a *Approval) decide(status ApprovalStatus, approver, comment string, at time.Time) error {
+	t, err := transition(approvalTransitions, "approval", a.OrderID, a.Status, status, comment, at)
+	if err != nil {
+		return err
+	}
+	a.Status = status
+	a.Approver = approver
+	a.Comment = comment
+	a.DecidedAt = t.At
+	a.History = append(a.History, t)
+	return nil
+}
+
+// Approve marks the approval as approved by approver
+func (a *Approval) Approve(approver, comment string, at time.Time) error {
+	return a.decide(ApprovalStatusApproved, approver, comment, at)
+}
+
+// Reject marks the approval as rejected by approver
+func (a *Approval) Reject(approver, comment string, at time.Time) error {
+	return a.decide(ApprovalStatusRejected, approver, comment, at)
+}
+
+// Expire marks the approval as expired because nobody decided in time
+func (a *Approval) Expire(at time.Time) error {
+	return a.decide(ApprovalStatusExpired, "", "approval deadline passed", at)
+}
+
+// IsPending checks if the approval is still waiting for a decision
+func (a *Approval) IsPending() bool {
+	return a.Status == ApprovalStatusPending
+}
@@ -13,6 +13,7 @@ type InventoryReservation struct {
 	Status    ReservationStatus
 	CreatedAt time.Time
 	ExpiresAt time.Time
+	History   []Transition // Status changes, oldest first
 }
 
 // ReservedItem represents a reserved inventory item
@@ -25,11 +26,22 @@ type ReservedItem struct {
 type ReservationStatus string
 
 const (
-	ReservationStatusActive   ReservationStatus = "active"
-	ReservationStatusReleased ReservationStatus = "released"
-	ReservationStatusExpired  ReservationStatus = "expired"
+	ReservationStatusActive    ReservationStatus = "active"
+	ReservationStatusReleased  ReservationStatus = "released"
+	ReservationStatusExpired   ReservationStatus = "expired"
+	ReservationStatusCommitted ReservationStatus = "committed" // Stock left the warehouse for a confirmed order
+	ReservationStatusRestocked ReservationStatus = "restocked" // Committed stock came back on a refund or cancellation
 )
 
+// ReservationID returns the ID of an order's reservation in a warehouse.
+// Each order holds at most one reservation per warehouse.
+func ReservationID(orderID, warehouse string) string {
+	if warehouse == "" {
+		return fmt.Sprintf("RES_%s", orderID)
+	}
+	return fmt.Sprintf("RES_%s@%s", orderID, warehouse)
+}
+
 // NewInventoryReservation creates a new inventory reservation
 func NewInventoryReservation(id, orderID string, items []ReservedItem) (*InventoryReservation, error) {
 	if id == "" {
@@ -53,22 +65,122 @@ func NewInventoryReservation(id, orderID string, items []ReservedItem) (*Invento
 	}, nil
 }
 
+// CanTransitionTo reports whether the reservation may move to status
+func (r *InventoryReservation) CanTransitionTo(status ReservationStatus) bool {
+	return canTransition(reservationTransitions, r.Status, status)
+}
+
+// ValidateTransition returns a *TransitionError if the reservation may not move
+// to status
+func (r *InventoryReservation) ValidateTransition(status ReservationStatus) error {
+	_, err := transition(reservationTransitions, "reservation", r.ID, r.Status, status, "", time.Time{})
+	return err
+}
+
+// transitionTo moves the reservation to status and records it in the
+// history, or returns a *TransitionError if the move is not allowed
+func (r *InventoryReservation) transitionTo(status ReservationStatus, reason string, at time.Time) error {
+	t, err := transition(reservationTransitions, "reservation", r.ID, r.Status, status, reason, at)
+	if err != nil {
+		return err
+	}
+	r.Status = status
+	r.History = append(r.History, t)
+	return nil
+}
+
 // MarkReleased marks the reservation as released
-func (r *InventoryReservation) MarkReleased() {
-	r.Status = ReservationStatusReleased
+func (r *InventoryReservation) MarkReleased(at time.Time) error {
+	return r.transitionTo(ReservationStatusReleased, "", at)
 }
 
 // MarkExpired marks the reservation as expired
-func (r *InventoryReservation) MarkExpired() {
-	r.Status = ReservationStatusExpired
+func (r *InventoryReservation) MarkExpired(at time.Time) error {
+	return r.transitionTo(ReservationStatusExpired, "", at)
+}
+
+// MarkCommitted marks the reservation's stock as taken for its order. A
+// committed reservation no longer expires.
+func (r *InventoryReservation) MarkCommitted(at time.Time) error {
+	return r.transitionTo(ReservationStatusCommitted, "", at)
 }
 
-// IsExpired checks if the reservation has expired
+// MarkRestocked marks the reservation's committed stock as returned
+func (r *InventoryReservation) MarkRestocked(at time.Time) error {
+	return r.transitionTo(ReservationStatusRestocked, "", at)
+}
+
+// IsExpired checks if the reservation has expired. Only active reservations
+// expire.
 func (r *InventoryReservation) IsExpired() bool {
-	return time.Now().After(r.ExpiresAt) || r.Status == ReservationStatusExpired
+	return r.Status == ReservationStatusExpired ||
+		(r.Status == ReservationStatusActive && time.Now().After(r.ExpiresAt))
 }
 
 // IsActive checks if the reservation is active
 func (r *InventoryReservation) IsActive() bool {
 	return r.Status == ReservationStatusActive && !r.IsExpired()
 }
+
+// ReleaseItems returns part of a reservation. The reservation becomes
+// released once no quantity remains; releasing more of a SKU than is
+// reserved is an error and leaves the reservation unchanged.
+func (r *InventoryReservation) ReleaseItems(items []ReservedItem, at time.Time) error {
+	if err := r.ValidateTransition(ReservationStatusReleased); err != nil {
+		return err
+	}
+	if err := r.removeItems(items, "release"); err != nil {
+		return err
+	}
+	if len(r.Items) == 0 {
+		return r.MarkReleased(at)
+	}
+	return nil
+}
+
+// RestockItems returns part of a committed reservation's stock, such as the
+// lines of a partial return. The reservation becomes restocked once no
+// quantity remains; restocking more of a SKU than was committed is an error
+// and leaves the reservation unchanged.
+func (r *InventoryReservation) RestockItems(items []ReservedItem, at time.Time) error {
+	if err := r.ValidateTransition(ReservationStatusRestocked); err != nil {
+		return err
+	}
+	if err := r.removeItems(items, "restock"); err != nil {
+		return err
+	}
+	if len(r.Items) == 0 {
+		return r.MarkRestocked(at)
+	}
+	return nil
+}
+
+// removeItems takes items out of the reservation, or returns an error naming
+// op and leaves the reservation unchanged if a SKU does not hold enough
+func (r *InventoryReservation) removeItems(items []ReservedItem, op string) error {
+	remaining := make([]ReservedItem, len(r.Items))
+	copy(remaining, r.Items)
+
+	for _, item := range items {
+		want := item.Quantity
+		for i := range remaining {
+			if remaining[i].SKU != item.SKU || want == 0 {
+				continue
+			}
+			take := min(want, remaining[i].Quantity)
+			remaining[i].Quantity -= take
+			want -= take
+		}
+		if want > 0 {
+			return fmt.Errorf("cannot %s %d of SKU %s: exceeds quantity reserved", op, item.Quantity, item.SKU)
+		}
+	}
+
+	r.Items = r.Items[:0]
+	for _, item := range remaining {
+		if item.Quantity > 0 {
+			r.Items = append(r.Items, item)
+		}
+	}
+	return nil
+}
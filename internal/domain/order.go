@@ -2,6 +2,7 @@ package domain
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -11,18 +12,48 @@ import (
 type OrderStatus string
 
 const (
-	OrderStatusPending    OrderStatus = "pending"
-	OrderStatusConfirmed  OrderStatus = "confirmed"
-	OrderStatusFailed     OrderStatus = "failed"
-	OrderStatusRefunded   OrderStatus = "refunded"
-	OrderStatusCancelled  OrderStatus = "cancelled"
+	OrderStatusPending   OrderStatus = "pending"
+	OrderStatusConfirmed OrderStatus = "confirmed"
+	OrderStatusFailed    OrderStatus = "failed"
+	OrderStatusRefunded  OrderStatus = "refunded"
+	OrderStatusCancelled OrderStatus = "cancelled"
 )
 
 // OrderItem represents a single item in an order
 type OrderItem struct {
-	SKU      string
-	Quantity int32
-	Price    decimal.Decimal
+	SKU       string
+	Quantity  int32
+	Price     decimal.Decimal
+	Warehouse string `json:",omitempty"` // Fulfilling warehouse; empty is the default warehouse
+}
+
+// WarehouseItems are the items of an order fulfilled from one warehouse
+type WarehouseItems struct {
+	Warehouse string
+	Items     []OrderItem
+}
+
+// GroupByWarehouse splits items by fulfilling warehouse, ordered by warehouse
+// name with the default warehouse first
+func GroupByWarehouse(items []OrderItem) []WarehouseItems {
+	index := make(map[string]int)
+	var groups []WarehouseItems
+	for _, item := range items {
+		i, ok := index[item.Warehouse]
+		if !ok {
+			i = len(groups)
+			index[item.Warehouse] = i
+			groups = append(groups, WarehouseItems{Warehouse: item.Warehouse})
+		}
+		groups[i].Items = append(groups[i].Items, item)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Warehouse < groups[j].Warehouse })
+	return groups
+}
+
+// LineTotal returns the price of the line: unit price times quantity
+func (i OrderItem) LineTotal() decimal.Decimal {
+	return i.Price.Mul(decimal.NewFromInt(int64(i.Quantity)))
 }
 
 // Order represents a customer order
@@ -37,6 +68,7 @@ type Order struct {
 	PaymentID     string
 	ReservationID string
 	FailureReason string
+	History       []Transition // Status changes, oldest first
 }
 
 // NewOrder creates a new order
@@ -57,19 +89,18 @@ func NewOrder(id, customerID string, items []OrderItem) (*Order, error) {
 		if item.Quantity <= 0 {
 			return nil, fmt.Errorf("quantity must be greater than zero for SKU %s", item.SKU)
 		}
-		lineTotal := item.Price.Mul(decimal.NewFromInt(int64(item.Quantity)))
-		total = total.Add(lineTotal)
+		total = total.Add(item.LineTotal())
 	}
 
 	now := time.Now()
 	return &Order{
-		ID:         id,
-		CustomerID: customerID,
-		Items:      items,
+		ID:          id,
+		CustomerID:  customerID,
+		Items:       items,
 		TotalAmount: total,
-		Status:     OrderStatusPending,
-		CreatedAt:  now,
-		UpdatedAt:  now,
+		Status:      OrderStatusPending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}, nil
 }
 
@@ -90,28 +121,125 @@ func (o *Order) IsValid() error {
 	return nil
 }
 
-// MarkConfirmed marks the order as confirmed
-func (o *Order) MarkConfirmed(paymentID, reservationID string) {
-	o.Status = OrderStatusConfirmed
+// CanTransitionTo reports whether the order may move to status
+func (o *Order) CanTransitionTo(status OrderStatus) bool {
+	return canTransition(orderTransitions, o.Status, status)
+}
+
+// ValidateTransition returns a *TransitionError if the order may not move
+// to status
+func (o *Order) ValidateTransition(status OrderStatus) error {
+	_, err := transition(orderTransitions, "order", o.ID, o.Status, status, "", time.Time{})
+	return err
+}
+
+// transitionTo moves the order to status and records it in the history,
+// or returns a *TransitionError if the move is not allowed
+func (o *Order) transitionTo(status OrderStatus, reason string, at time.Time) error {
+	t, err := transition(orderTransitions, "order", o.ID, o.Status, status, reason, at)
+	if err != nil {
+		return err
+	}
+	o.Status = status
+	o.UpdatedAt = t.At
+	o.History = append(o.History, t)
+	return nil
+}
+
+// MarkConfirmed marks the order as confirmed at the given time
+func (o *Order) MarkConfirmed(paymentID, reservationID string, at time.Time) error {
+	if err := o.transitionTo(OrderStatusConfirmed, "", at); err != nil {
+		return err
+	}
 	o.PaymentID = paymentID
 	o.ReservationID = reservationID
-	o.UpdatedAt = time.Now()
+	return nil
 }
 
-// MarkFailed marks the order as failed
-func (o *Order) MarkFailed(reason string) {
-	o.Status = OrderStatusFailed
+// MarkFailed marks the order as failed at the given time
+func (o *Order) MarkFailed(reason string, at time.Time) error {
+	if err := o.transitionTo(OrderStatusFailed, reason, at); err != nil {
+		return err
+	}
 	o.FailureReason = reason
-	o.UpdatedAt = time.Now()
+	return nil
 }
 
-// MarkRefunded marks the order as refunded
-func (o *Order) MarkRefunded() {
-	o.Status = OrderStatusRefunded
-	o.UpdatedAt = time.Now()
+// MarkRefunded marks the order as refunded at the given time
+func (o *Order) MarkRefunded(at time.Time) error {
+	return o.transitionTo(OrderStatusRefunded, "", at)
 }
 
 // CanBeConfirmed checks if order can be confirmed
 func (o *Order) CanBeConfirmed() bool {
-	return o.Status == OrderStatusPending
+	return o.CanTransitionTo(OrderStatusConfirmed)
+}
+
+// MarkCancelled marks the order as cancelled at the given time
+func (o *Order) MarkCancelled(reason string, at time.Time) error {
+	if err := o.transitionTo(OrderStatusCancelled, reason, at); err != nil {
+		return err
+	}
+	o.FailureReason = reason
+	return nil
+}
+
+// CanBeRefunded checks if order can be refunded
+func (o *Order) CanBeRefunded() bool {
+	return o.CanTransitionTo(OrderStatusRefunded)
+}
+
+// CanBeCancelled checks if order can be cancelled
+func (o *Order) CanBeCancelled() bool {
+	return o.CanTransitionTo(OrderStatusCancelled)
+}
+
+// WarehouseOf returns the warehouse fulfilling sku in the order
+func (o *Order) WarehouseOf(sku string) string {
+	for _, item := range o.Items {
+		if item.SKU == sku {
+			return item.Warehouse
+		}
+	}
+	return ""
+}
+
+// ReturnTotal returns the refund due for returning items from the order.
+// Each returned quantity is priced at the order's own line prices, so the
+// total rounds exactly like the order total; returning a SKU that is not in
+// the order, or more of it than was ordered, is an error. Earlier returns are
+// not known to the order: the refund workflow checks them against the
+// order's committed reservations.
+func (o *Order) ReturnTotal(items []OrderItem) (decimal.Decimal, error) {
+	if len(items) == 0 {
+		return decimal.Zero, fmt.Errorf("no items to return")
+	}
+
+	// Quantity still returnable on each order line
+	remaining := make([]int32, len(o.Items))
+	for i, line := range o.Items {
+		remaining[i] = line.Quantity
+	}
+
+	total := decimal.Zero
+	for _, item := range items {
+		if item.Quantity <= 0 {
+			return decimal.Zero, fmt.Errorf("return quantity must be greater than zero for SKU %s", item.SKU)
+		}
+
+		want := item.Quantity
+		for i, line := range o.Items {
+			if line.SKU != item.SKU || remaining[i] == 0 || want == 0 {
+				continue
+			}
+			take := min(want, remaining[i])
+			remaining[i] -= take
+			want -= take
+			total = total.Add(OrderItem{SKU: line.SKU, Quantity: take, Price: line.Price}.LineTotal())
+		}
+		if want > 0 {
+			return decimal.Zero, fmt.Errorf("cannot return %d of SKU %s: exceeds quantity ordered", item.Quantity, item.SKU)
+		}
+	}
+	return total, nil
 }
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderReturnTotal(t *testing.T) {
+	order, err := NewOrder("ORD-1", "CUST-1", []OrderItem{
+		{SKU: "ITEM-001", Quantity: 3, Price: decimal.RequireFromString("0.10")},
+		{SKU: "ITEM-002", Quantity: 1, Price: decimal.RequireFromString("49.99")},
+	})
+	require.NoError(t, err)
+
+	// Line totals add up exactly, with no float rounding
+	total, err := order.ReturnTotal([]OrderItem{{SKU: "ITEM-001", Quantity: 3}})
+	require.NoError(t, err)
+	assert.Equal(t, "0.3", total.String())
+
+	all, err := order.ReturnTotal(order.Items)
+	require.NoError(t, err)
+	assert.True(t, order.TotalAmount.Equal(all))
+
+	_, err = order.ReturnTotal([]OrderItem{{SKU: "ITEM-002", Quantity: 2}})
+	assert.Error(t, err, "more than was ordered")
+	_, err = order.ReturnTotal([]OrderItem{{SKU: "ITEM-999", Quantity: 1}})
+	assert.Error(t, err, "not in the order")
+}
+
+func TestPaymentApplyRefund(t *testing.T) {
+	now := time.Now()
+	pay, err := NewPayment("PAY-1", "ORD-1", decimal.RequireFromString("100.00"), PaymentMethodCard)
+	require.NoError(t, err)
+	pay.MarkCompleted("TXN-1", now)
+
+	require.NoError(t, pay.ApplyRefund(decimal.RequireFromString("30.10"), now))
+	assert.Equal(t, PaymentStatusCompleted, pay.Status)
+	assert.True(t, decimal.RequireFromString("69.90").Equal(pay.RefundableAmount()))
+
+	assert.Error(t, pay.ApplyRefund(decimal.RequireFromString("69.91"), now))
+	assert.True(t, decimal.RequireFromString("30.10").Equal(pay.RefundedAmount))
+
+	require.NoError(t, pay.ApplyRefund(decimal.RequireFromString("69.90"), now))
+	assert.Equal(t, PaymentStatusRefunded, pay.Status)
+	assert.Error(t, pay.ApplyRefund(decimal.RequireFromString("0.01"), now))
+}
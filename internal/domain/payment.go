@@ -22,9 +22,11 @@ type PaymentStatus string
 const (
 	PaymentStatusPending    PaymentStatus = "pending"
 	PaymentStatusProcessing PaymentStatus = "processing"
+	PaymentStatusAuthorized PaymentStatus = "authorized" // Held on the customer's payment method, not yet captured
 	PaymentStatusCompleted  PaymentStatus = "completed"
 	PaymentStatusFailed     PaymentStatus = "failed"
 	PaymentStatusRefunded   PaymentStatus = "refunded"
+	PaymentStatusVoided     PaymentStatus = "voided" // Authorization released without capturing
 )
 
 // Payment represents a payment transaction
@@ -32,13 +34,15 @@ type Payment struct {
 	ID              string
 	OrderID         string
 	Amount          decimal.Decimal
+	RefundedAmount  decimal.Decimal // Cumulative amount refunded so far
 	Method          PaymentMethod
 	Status          PaymentStatus
 	TransactionID   string
 	FailureReason   string
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
-	ProcessingError error `json:"-"`
+	ProcessingError error        `json:"-"`
+	History         []Transition // Status changes, oldest first
 }
 
 // NewPayment creates a new payment
@@ -65,34 +69,102 @@ func NewPayment(id, orderID string, amount decimal.Decimal, method PaymentMethod
 	}, nil
 }
 
+// CanTransitionTo reports whether the payment may move to status
+func (p *Payment) CanTransitionTo(status PaymentStatus) bool {
+	return canTransition(paymentTransitions, p.Status, status)
+}
+
+// ValidateTransition returns a *TransitionError if the payment may not move
+// to status
+func (p *Payment) ValidateTransition(status PaymentStatus) error {
+	_, err := transition(paymentTransitions, "payment", p.ID, p.Status, status, "", time.Time{})
+	return err
+}
+
+// transitionTo moves the payment to status and records it in the history,
+// or returns a *TransitionError if the move is not allowed
+func (p *Payment) transitionTo(status PaymentStatus, reason string, at time.Time) error {
+	t, err := transition(paymentTransitions, "payment", p.ID, p.Status, status, reason, at)
+	if err != nil {
+		return err
+	}
+	p.Status = status
+	p.UpdatedAt = t.At
+	p.History = append(p.History, t)
+	return nil
+}
+
 // MarkProcessing marks the payment as processing
-func (p *Payment) MarkProcessing() {
-	p.Status = PaymentStatusProcessing
-	p.UpdatedAt = time.Now()
+func (p *Payment) MarkProcessing(at time.Time) error {
+	return p.transitionTo(PaymentStatusProcessing, "", at)
 }
 
-// MarkCompleted marks the payment as completed with transaction ID
-func (p *Payment) MarkCompleted(transactionID string) {
-	p.Status = PaymentStatusCompleted
+// MarkAuthorized marks the payment as authorized with transaction ID
+func (p *Payment) MarkAuthorized(transactionID string, at time.Time) error {
+	if err := p.transitionTo(PaymentStatusAuthorized, "", at); err != nil {
+		return err
+	}
 	p.TransactionID = transactionID
-	p.UpdatedAt = time.Now()
+	return nil
+}
+
+// MarkVoided marks an authorized payment as voided
+func (p *Payment) MarkVoided(reason string, at time.Time) error {
+	return p.transitionTo(PaymentStatusVoided, reason, at)
+}
+
+// MarkCompleted marks the payment as completed with transaction ID, either
+// charged at once or captured after authorization
+func (p *Payment) MarkCompleted(transactionID string, at time.Time) error {
+	if err := p.transitionTo(PaymentStatusCompleted, "", at); err != nil {
+		return err
+	}
+	p.TransactionID = transactionID
+	return nil
 }
 
 // MarkFailed marks the payment as failed with reason
-func (p *Payment) MarkFailed(reason string, err error) {
-	p.Status = PaymentStatusFailed
+func (p *Payment) MarkFailed(reason string, err error, at time.Time) error {
+	if terr := p.transitionTo(PaymentStatusFailed, reason, at); terr != nil {
+		return terr
+	}
 	p.FailureReason = reason
 	p.ProcessingError = err
-	p.UpdatedAt = time.Now()
+	return nil
 }
 
 // MarkRefunded marks the payment as refunded
-func (p *Payment) MarkRefunded() {
-	p.Status = PaymentStatusRefunded
-	p.UpdatedAt = time.Now()
+func (p *Payment) MarkRefunded(at time.Time) error {
+	return p.transitionTo(PaymentStatusRefunded, "", at)
 }
 
 // CanBeRefunded checks if payment can be refunded
 func (p *Payment) CanBeRefunded() bool {
-	return p.Status == PaymentStatusCompleted
+	return p.CanTransitionTo(PaymentStatusRefunded)
+}
+
+// RefundableAmount returns the captured amount not yet refunded
+func (p *Payment) RefundableAmount() decimal.Decimal {
+	return p.Amount.Sub(p.RefundedAmount)
+}
+
+// ApplyRefund records a full or partial refund. Refunds may not exceed the
+// captured amount; the payment becomes refunded once nothing is left.
+func (p *Payment) ApplyRefund(amount decimal.Decimal, at time.Time) error {
+	if !p.CanBeRefunded() {
+		return fmt.Errorf("payment %s cannot be refunded in status %s", p.ID, p.Status)
+	}
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return fmt.Errorf("refund amount must be greater than zero")
+	}
+	if amount.GreaterThan(p.RefundableAmount()) {
+		return fmt.Errorf("refund of %s exceeds refundable amount %s", amount, p.RefundableAmount())
+	}
+
+	p.RefundedAmount = p.RefundedAmount.Add(amount)
+	p.UpdatedAt = at
+	if p.RefundableAmount().IsZero() {
+		return p.MarkRefunded(at)
+	}
+	return nil
 }
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrInvalidTransition is matched by every TransitionError
+var ErrInvalidTransition = errors.New("invalid state transition")
+
+// TransitionError reports a status change that an entity's transition table
+// does not allow
+type TransitionError struct {
+	Entity string // "order", "payment", "reservation" or "approval"
+	ID     string
+	From   string
+	To     string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s %s cannot move from %s to %s", e.Entity, e.ID, e.From, e.To)
+}
+
+// Is makes errors.Is(err, ErrInvalidTransition) match any TransitionError
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// Transition is one entry in an entity's audit history
+type Transition struct {
+	From   string
+	To     string
+	At     time.Time
+	Reason string `json:",omitempty"`
+}
+
+// orderTransitions lists the statuses each order status may move to.
+// Statuses without an entry are terminal.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:   {OrderStatusConfirmed, OrderStatusFailed},
+	OrderStatusConfirmed: {OrderStatusRefunded, OrderStatusCancelled},
+}
+
+// paymentTransitions lists the statuses each payment status may move to.
+// Statuses without an entry are terminal.
+var paymentTransitions = map[PaymentStatus][]PaymentStatus{
+	PaymentStatusPending:    {PaymentStatusProcessing, PaymentStatusAuthorized, PaymentStatusCompleted, PaymentStatusFailed},
+	PaymentStatusProcessing: {PaymentStatusCompleted, PaymentStatusFailed},
+	PaymentStatusAuthorized: {PaymentStatusCompleted, PaymentStatusVoided, PaymentStatusFailed},
+	PaymentStatusCompleted:  {PaymentStatusRefunded},
+}
+
+// reservationTransitions lists the statuses each reservation status may move
+// to. Statuses without an entry are terminal.
+var reservationTransitions = map[ReservationStatus][]ReservationStatus{
+	ReservationStatusActive:    {ReservationStatusReleased, ReservationStatusExpired, ReservationStatusCommitted},
+	ReservationStatusCommitted: {ReservationStatusRestocked},
+}
+
+// approvalTransitions lists the statuses each approval status may move to.
+// Statuses without an entry are terminal.
+var approvalTransitions = map[ApprovalStatus][]ApprovalStatus{
+	ApprovalStatusPending: {ApprovalStatusApproved, ApprovalStatusRejected, ApprovalStatusExpired},
+}
+
+// canTransition reports whether table allows moving from one status to another
+func canTransition[S ~string](table map[S][]S, from, to S) bool {
+	return slices.Contains(table[from], to)
+}
+
+// transition validates a status change against table and returns the audit
+// entry recording it at the given time. Orchestrations pass their replay-safe
+// current time so the history is identical on every replay.
+func transition[S ~string](table map[S][]S, entity, id string, from, to S, reason string, at time.Time) (Transition, error) {
+	if !canTransition(table, from, to) {
+		return Transition{}, &TransitionError{Entity: entity, ID: id, From: string(from), To: string(to)}
+	}
+	return Transition{From: string(from), To: string(to), At: at, Reason: reason}, nil
+}
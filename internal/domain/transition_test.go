@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderTransitions(t *testing.T) {
+	order, err := NewOrder("ORD-1", "CUST-1", []OrderItem{{SKU: "ITEM-001", Quantity: 1, Price: decimal.NewFromInt(10)}})
+	require.NoError(t, err)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// A pending order has not been paid, so it cannot be refunded
+	err = order.MarkRefunded(now)
+	var terr *TransitionError
+	require.ErrorAs(t, err, &terr)
+	assert.True(t, errors.Is(err, ErrInvalidTransition))
+	assert.Equal(t, "pending", terr.From)
+	assert.Equal(t, "refunded", terr.To)
+	assert.Equal(t, OrderStatusPending, order.Status)
+	assert.Empty(t, order.History)
+
+	require.NoError(t, order.MarkConfirmed("PAY-1", "RES-1", now))
+	require.NoError(t, order.MarkCancelled("customer request", now.Add(time.Hour)))
+	assert.ErrorIs(t, order.MarkConfirmed("PAY-2", "RES-2", now), ErrInvalidTransition)
+	assert.Equal(t, "PAY-1", order.PaymentID)
+
+	// Transitions are stamped with the caller's time, so orchestration
+	// replays record the same history
+	require.Len(t, order.History, 2)
+	assert.Equal(t, Transition{From: "pending", To: "confirmed", At: now}, order.History[0])
+	assert.Equal(t, "customer request", order.History[1].Reason)
+	assert.Equal(t, now.Add(time.Hour), order.History[1].At)
+	assert.Equal(t, now.Add(time.Hour), order.UpdatedAt)
+}
+
+func TestPaymentTransitions(t *testing.T) {
+	now := time.Now()
+	pay, err := NewPayment("PAY-1", "ORD-1", decimal.NewFromInt(10), PaymentMethodCard)
+	require.NoError(t, err)
+
+	require.NoError(t, pay.MarkProcessing(now))
+	require.NoError(t, pay.MarkFailed("card declined", nil, now))
+
+	// A failed payment stays failed
+	assert.ErrorIs(t, pay.MarkCompleted("TXN-1", now), ErrInvalidTransition)
+	assert.Empty(t, pay.TransactionID)
+	assert.False(t, pay.CanBeRefunded())
+	assert.Len(t, pay.History, 2)
+}
+
+func TestPaymentAuthorizationTransitions(t *testing.T) {
+	now := time.Now()
+	captured, err := NewPayment("PAY-1", "ORD-1", decimal.NewFromInt(10), PaymentMethodCard)
+	require.NoError(t, err)
+	require.NoError(t, captured.MarkAuthorized("TXN-1", now))
+	assert.False(t, captured.CanBeRefunded(), "nothing is captured yet")
+	require.NoError(t, captured.MarkCompleted("TXN-1", now))
+	assert.ErrorIs(t, captured.MarkVoided("too late", now), ErrInvalidTransition)
+	assert.True(t, captured.CanBeRefunded())
+
+	voided, err := NewPayment("PAY-2", "ORD-2", decimal.NewFromInt(10), PaymentMethodCard)
+	require.NoError(t, err)
+	require.NoError(t, voided.MarkAuthorized("TXN-2", now))
+	require.NoError(t, voided.MarkVoided("inventory reservation failed", now))
+	assert.Equal(t, "TXN-2", voided.TransactionID)
+
+	// A voided authorization cannot be captured or refunded
+	assert.ErrorIs(t, voided.MarkCompleted("TXN-2", now), ErrInvalidTransition)
+	assert.False(t, voided.CanBeRefunded())
+	require.Len(t, voided.History, 2)
+	assert.Equal(t, "inventory reservation failed", voided.History[1].Reason)
+}
+
+func TestReservationTransitions(t *testing.T) {
+	now := time.Now()
+	res, err := NewInventoryReservation("RES-1", "ORD-1", []ReservedItem{{SKU: "ITEM-001", Quantity: 1}})
+	require.NoError(t, err)
+
+	require.NoError(t, res.MarkExpired(now))
+	assert.ErrorIs(t, res.MarkReleased(now), ErrInvalidTransition)
+	assert.ErrorIs(t, res.ReleaseItems(res.Items, now), ErrInvalidTransition)
+	assert.Equal(t, ReservationStatusExpired, res.Status)
+
+	committed, err := NewInventoryReservation("RES-2", "ORD-2", []ReservedItem{{SKU: "ITEM-001", Quantity: 3}})
+	require.NoError(t, err)
+	require.NoError(t, committed.MarkCommitted(now))
+	committed.ExpiresAt = time.Now().Add(-time.Minute)
+	assert.False(t, committed.IsExpired(), "committed stock does not expire")
+
+	// Committed stock is restocked, never released or expired
+	assert.ErrorIs(t, committed.MarkReleased(now), ErrInvalidTransition)
+	assert.ErrorIs(t, committed.MarkExpired(now), ErrInvalidTransition)
+	require.NoError(t, committed.RestockItems([]ReservedItem{{SKU: "ITEM-001", Quantity: 1}}, now))
+	assert.Equal(t, ReservationStatusCommitted, committed.Status)
+	assert.Error(t, committed.RestockItems([]ReservedItem{{SKU: "ITEM-001", Quantity: 3}}, now))
+	require.NoError(t, committed.RestockItems([]ReservedItem{{SKU: "ITEM-001", Quantity: 2}}, now))
+	assert.Equal(t, ReservationStatusRestocked, committed.Status)
+}
+
+func TestApprovalTransitions(t *testing.T) {
+	now := time.Now()
+	_, err := NewApproval("ORD-1", "CUST-1", decimal.NewFromInt(5000), now, now)
+	assert.Error(t, err, "deadline must be after the request")
+
+	approval, err := NewApproval("ORD-1", "CUST-1", decimal.NewFromInt(5000), now, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, approval.IsPending())
+
+	require.NoError(t, approval.Reject("alice", "too large", now))
+	assert.Equal(t, "alice", approval.Approver)
+	assert.False(t, approval.DecidedAt.IsZero())
+
+	// A decision is final, including a deadline passing afterwards
+	assert.ErrorIs(t, approval.Expire(now), ErrInvalidTransition)
+	assert.ErrorIs(t, approval.Approve("bob", "", now), ErrInvalidTransition)
+	assert.Equal(t, ApprovalStatusRejected, approval.Status)
+	assert.Equal(t, "alice", approval.Approver)
+	require.Len(t, approval.History, 1)
+	assert.Equal(t, "too large", approval.History[0].Reason)
+}
@@ -0,0 +1,658 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/microsoft/durabletask-go/api"
+
+	"github.com/Youmanvi/taskorchestrator/internal/activities/approval"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/deadletter"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/notification"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+	"github.com/Youmanvi/taskorchestrator/internal/middleware"
+	"github.com/Youmanvi/taskorchestrator/internal/workflows"
+)
+
+// OrchestrationClient is the subset of the durabletask TaskHubClient used by the API
+type OrchestrationClient interface {
+	ScheduleNewOrchestration(ctx context.Context, orchestrator interface{}, opts ...api.NewOrchestrationOptions) (api.InstanceID, error)
+	FetchOrchestrationMetadata(ctx context.Context, id api.InstanceID) (*api.OrchestrationMetadata, error)
+	RaiseEvent(ctx context.Context, id api.InstanceID, eventName string, opts ...api.RaiseEventOptions) error
+	SuspendOrchestration(ctx context.Context, id api.InstanceID, reason string) error
+	ResumeOrchestration(ctx context.Context, id api.InstanceID, reason string) error
+	TerminateOrchestration(ctx context.Context, id api.InstanceID, opts ...api.TerminateOptions) error
+	PurgeOrchestrationState(ctx context.Context, id api.InstanceID, opts ...api.PurgeOptions) error
+}
+
+// CircuitBreakerAdmin exposes circuit breaker state for the admin endpoints
+type CircuitBreakerAdmin interface {
+	States() []middleware.BreakerState
+	Reset(name string) error
+}
+
+// ApprovalQueue exposes approval requests for the approval endpoints
+type ApprovalQueue interface {
+	Get(ctx context.Context, orderID string) (*domain.Approval, error)
+	ListPending(ctx context.Context) ([]*domain.Approval, error)
+}
+
+// DeadLetterQueue exposes dead-letter entries for the dead-letter endpoints
+type DeadLetterQueue interface {
+	Get(ctx context.Context, id int64) (*deadletter.Entry, error)
+	List(ctx context.Context, status deadletter.Status) ([]*deadletter.Entry, error)
+	Resolve(ctx context.Context, id int64, status deadletter.Status) error
+}
+
+// NotificationOutbox exposes queued notifications for the order
+// notifications endpoint
+type NotificationOutbox interface {
+	ListByOrder(ctx context.Context, orderID string) ([]*notification.OutboxMessage, error)
+}
+
+// IdempotencyRecords forgets the recorded step outputs of purged instances
+type IdempotencyRecords interface {
+	DeleteInstance(ctx context.Context, instanceID string) error
+}
+
+// Server exposes orchestration management over HTTP/JSON
+type Server struct {
+	client      OrchestrationClient
+	breakers    CircuitBreakerAdmin
+	approvals   ApprovalQueue
+	deadLetters DeadLetterQueue
+	outbox      NotificationOutbox
+	idempotency IdempotencyRecords
+	logger      *observability.Logger
+	mux         *http.ServeMux
+	http        *http.Server
+}
+
+// NewServer creates a new API server listening on the given port
+func NewServer(client OrchestrationClient, logger *observability.Logger, port int) *Server {
+	s := &Server{
+		client: client,
+		logger: logger,
+		mux:    http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /health", s.handleHealth)
+	s.mux.HandleFunc("POST /api/v1/orders/{instanceID}", s.handleStartOrder)
+	s.mux.HandleFunc("GET /api/v1/orders/{orderID}/notifications", s.handleListNotifications)
+	s.mux.HandleFunc("GET /api/v1/orchestrations/{instanceID}", s.handleGetOrchestration)
+	s.mux.HandleFunc("DELETE /api/v1/orchestrations/{instanceID}", s.handlePurge)
+	s.mux.HandleFunc("POST /api/v1/orchestrations/{instanceID}/events/{eventName}", s.handleRaiseEvent)
+	s.mux.HandleFunc("POST /api/v1/orchestrations/{instanceID}/suspend", s.handleSuspend)
+	s.mux.HandleFunc("POST /api/v1/orchestrations/{instanceID}/resume", s.handleResume)
+	s.mux.HandleFunc("POST /api/v1/orchestrations/{instanceID}/terminate", s.handleTerminate)
+	s.mux.HandleFunc("GET /api/v1/admin/circuit-breakers", s.handleListBreakers)
+	s.mux.HandleFunc("POST /api/v1/admin/circuit-breakers/{name}/reset", s.handleResetBreaker)
+	s.mux.HandleFunc("GET /api/v1/approvals", s.handleListApprovals)
+	s.mux.HandleFunc("POST /api/v1/approvals/{orderID}", s.handleDecideApproval)
+	s.mux.HandleFunc("GET /api/v1/dead-letters", s.handleListDeadLetters)
+	s.mux.HandleFunc("GET /api/v1/dead-letters/{id}", s.handleGetDeadLetter)
+	s.mux.HandleFunc("POST /api/v1/dead-letters/{id}/replay", s.handleReplayDeadLetter)
+	s.mux.HandleFunc("POST /api/v1/dead-letters/{id}/discard", s.handleDiscardDeadLetter)
+
+	s.http = &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s
+}
+
+// SetCircuitBreakers attaches the circuit breakers exposed by the admin endpoints
+func (s *Server) SetCircuitBreakers(breakers CircuitBreakerAdmin) {
+	s.breakers = breakers
+}
+
+// SetApprovals attaches the approval requests exposed by the approval endpoints
+func (s *Server) SetApprovals(approvals ApprovalQueue) {
+	s.approvals = approvals
+}
+
+// SetDeadLetters attaches the dead-letter queue exposed by the dead-letter
+// endpoints
+func (s *Server) SetDeadLetters(deadLetters DeadLetterQueue) {
+	s.deadLetters = deadLetters
+}
+
+// SetOutbox attaches the notification outbox exposed by the order
+// notifications endpoint
+func (s *Server) SetOutbox(outbox NotificationOutbox) {
+	s.outbox = outbox
+}
+
+// SetIdempotency attaches the idempotency records cleared when an instance
+// is purged
+func (s *Server) SetIdempotency(idempotency IdempotencyRecords) {
+	s.idempotency = idempotency
+}
+
+// Handler returns the HTTP handler serving all API routes
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// ListenAndServe serves requests until Shutdown is called
+func (s *Server) ListenAndServe() error {
+	if err := s.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown gracefully stops the server
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+// StartOrderResponse is returned after scheduling an order
+type StartOrderResponse struct {
+	InstanceID string `json:"instance_id"`
+}
+
+// OrchestrationStatus describes the state of an orchestration instance
+type OrchestrationStatus struct {
+	InstanceID    string          `json:"instance_id"`
+	Name          string          `json:"name"`
+	RuntimeStatus string          `json:"runtime_status"`
+	CreatedAt     time.Time       `json:"created_at"`
+	LastUpdatedAt time.Time       `json:"last_updated_at"`
+	Input         json.RawMessage `json:"input,omitempty"`
+	Output        json.RawMessage `json:"output,omitempty"`
+	Failure       *FailureDetails `json:"failure,omitempty"`
+}
+
+// FailureDetails describes why an orchestration failed
+type FailureDetails struct {
+	ErrorType    string `json:"error_type"`
+	ErrorMessage string `json:"error_message"`
+}
+
+// ReasonRequest carries an optional reason for suspend/resume/terminate
+type ReasonRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ApprovalResponse describes an approval request
+type ApprovalResponse struct {
+	OrderID     string    `json:"order_id"`
+	CustomerID  string    `json:"customer_id"`
+	Amount      string    `json:"amount"`
+	Status      string    `json:"status"`
+	RequestedAt time.Time `json:"requested_at"`
+	Deadline    time.Time `json:"deadline"`
+}
+
+// ReplayResponse is returned after scheduling a dead-letter replay
+type ReplayResponse struct {
+	EntryID    int64  `json:"entry_id"`
+	InstanceID string `json:"instance_id"`
+}
+
+// ErrorResponse is the body returned for failed requests
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleStartOrder(w http.ResponseWriter, r *http.Request) {
+	instanceID := r.PathValue("instanceID")
+
+	var input workflows.OrderProcessingInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid order payload: %w", err))
+		return
+	}
+
+	if input.Order.ID == "" {
+		input.Order.ID = instanceID
+	}
+	if input.Order.ID != instanceID {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("order ID %q does not match instance ID %q", input.Order.ID, instanceID))
+		return
+	}
+	// Orders start pending, priced from their items. A client total is only
+	// a cross-check: a mismatch means the client priced the order differently.
+	order, err := domain.NewOrder(input.Order.ID, input.Order.CustomerID, input.Order.Items)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !input.Order.TotalAmount.IsZero() && !input.Order.TotalAmount.Equal(order.TotalAmount) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("total amount %s does not match the items' total %s", input.Order.TotalAmount, order.TotalAmount))
+		return
+	}
+	if err := order.IsValid(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	input.Order = *order
+	if input.CustomerEmail == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("customer email is required"))
+		return
+	}
+
+	inputBytes, _ := json.Marshal(input)
+	id, err := s.client.ScheduleNewOrchestration(r.Context(), "order_processing",
+		api.WithInstanceID(api.InstanceID(instanceID)),
+		api.WithRawInput(string(inputBytes)),
+	)
+	if err != nil {
+		s.writeClientError(w, "failed to schedule order", err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, StartOrderResponse{InstanceID: string(id)})
+}
+
+// handleListNotifications returns an order's queued notifications and their
+// delivery status
+func (s *Server) handleListNotifications(w http.ResponseWriter, r *http.Request) {
+	if s.outbox == nil {
+		writeJSON(w, http.StatusOK, []*notification.OutboxMessage{})
+		return
+	}
+
+	messages, err := s.outbox.ListByOrder(r.Context(), r.PathValue("orderID"))
+	if err != nil {
+		s.logger.Error("failed to list notifications", err)
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to list notifications: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, messages)
+}
+
+func (s *Server) handleGetOrchestration(w http.ResponseWriter, r *http.Request) {
+	id := api.InstanceID(r.PathValue("instanceID"))
+
+	metadata, err := s.client.FetchOrchestrationMetadata(r.Context(), id)
+	if err != nil {
+		s.writeClientError(w, "failed to fetch orchestration", err)
+		return
+	}
+	if metadata == nil {
+		writeError(w, http.StatusNotFound, api.ErrInstanceNotFound)
+		return
+	}
+
+	status := OrchestrationStatus{
+		InstanceID:    string(metadata.InstanceID),
+		Name:          metadata.Name,
+		RuntimeStatus: strings.TrimPrefix(metadata.RuntimeStatus.String(), "ORCHESTRATION_STATUS_"),
+		CreatedAt:     metadata.CreatedAt,
+		LastUpdatedAt: metadata.LastUpdatedAt,
+		Input:         rawJSON(metadata.SerializedInput),
+		Output:        rawJSON(metadata.SerializedOutput),
+	}
+	if fd := metadata.FailureDetails; fd != nil {
+		status.Failure = &FailureDetails{
+			ErrorType:    fd.GetErrorType(),
+			ErrorMessage: fd.GetErrorMessage(),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) handleRaiseEvent(w http.ResponseWriter, r *http.Request) {
+	id := api.InstanceID(r.PathValue("instanceID"))
+	eventName := r.PathValue("eventName")
+
+	var payload json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid event payload: %w", err))
+		return
+	}
+
+	var opts []api.RaiseEventOptions
+	if len(payload) > 0 {
+		opts = append(opts, api.WithRawEventData(string(payload)))
+	}
+
+	if err := s.client.RaiseEvent(r.Context(), id, eventName, opts...); err != nil {
+		s.writeClientError(w, "failed to raise event", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleSuspend(w http.ResponseWriter, r *http.Request) {
+	id := api.InstanceID(r.PathValue("instanceID"))
+	req, ok := decodeReason(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.client.SuspendOrchestration(r.Context(), id, req.Reason); err != nil {
+		s.writeClientError(w, "failed to suspend orchestration", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	id := api.InstanceID(r.PathValue("instanceID"))
+	req, ok := decodeReason(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.client.ResumeOrchestration(r.Context(), id, req.Reason); err != nil {
+		s.writeClientError(w, "failed to resume orchestration", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleTerminate(w http.ResponseWriter, r *http.Request) {
+	id := api.InstanceID(r.PathValue("instanceID"))
+	req, ok := decodeReason(w, r)
+	if !ok {
+		return
+	}
+
+	var opts []api.TerminateOptions
+	if req.Reason != "" {
+		opts = append(opts, api.WithOutput(req.Reason))
+	}
+
+	if err := s.client.TerminateOrchestration(r.Context(), id, opts...); err != nil {
+		s.writeClientError(w, "failed to terminate orchestration", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handlePurge(w http.ResponseWriter, r *http.Request) {
+	id := api.InstanceID(r.PathValue("instanceID"))
+
+	err := s.client.PurgeOrchestrationState(r.Context(), id)
+	if err != nil && !errors.Is(err, api.ErrInstanceNotFound) {
+		s.writeClientError(w, "failed to purge orchestration", err)
+		return
+	}
+
+	// Forget the instance's completed steps so an orchestration reusing the
+	// ID runs them again. This also runs when the instance is already gone,
+	// so repeating a purge whose cleanup failed finishes it.
+	if s.idempotency != nil {
+		if err := s.idempotency.DeleteInstance(r.Context(), string(id)); err != nil {
+			s.logger.Error("failed to delete idempotency keys", err)
+			writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to delete idempotency keys: %w", err))
+			return
+		}
+	}
+	if err != nil {
+		s.writeClientError(w, "failed to purge orchestration", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListBreakers(w http.ResponseWriter, r *http.Request) {
+	if s.breakers == nil {
+		writeJSON(w, http.StatusOK, []middleware.BreakerState{})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.breakers.States())
+}
+
+func (s *Server) handleResetBreaker(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if s.breakers == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", middleware.ErrBreakerNotFound, name))
+		return
+	}
+
+	if err := s.breakers.Reset(name); err != nil {
+		if errors.Is(err, middleware.ErrBreakerNotFound) {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		s.logger.Error("failed to reset circuit breaker", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.logger.Logger.Warn().Str("dependency", name).Msg("circuit breaker reset via admin API")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleListApprovals(w http.ResponseWriter, r *http.Request) {
+	responses := []ApprovalResponse{}
+	if s.approvals == nil {
+		writeJSON(w, http.StatusOK, responses)
+		return
+	}
+
+	pending, err := s.approvals.ListPending(r.Context())
+	if err != nil {
+		s.logger.Error("failed to list approvals", err)
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to list approvals: %w", err))
+		return
+	}
+	for _, a := range pending {
+		responses = append(responses, ApprovalResponse{
+			OrderID:     a.OrderID,
+			CustomerID:  a.CustomerID,
+			Amount:      a.Amount.StringFixed(2),
+			Status:      string(a.Status),
+			RequestedAt: a.RequestedAt,
+			Deadline:    a.Deadline,
+		})
+	}
+	writeJSON(w, http.StatusOK, responses)
+}
+
+// handleDecideApproval raises the approval event on a pending order's
+// orchestration. The order records the decision when it handles the event.
+func (s *Server) handleDecideApproval(w http.ResponseWriter, r *http.Request) {
+	orderID := r.PathValue("orderID")
+	if s.approvals == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", approval.ErrApprovalNotFound, orderID))
+		return
+	}
+
+	var decision approval.Decision
+	if err := json.NewDecoder(r.Body).Decode(&decision); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid approval decision: %w", err))
+		return
+	}
+	if err := decision.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	pending, err := s.approvals.Get(r.Context(), orderID)
+	if errors.Is(err, approval.ErrApprovalNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		s.logger.Error("failed to load approval", err)
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to load approval: %w", err))
+		return
+	}
+	if !pending.IsPending() {
+		writeError(w, http.StatusConflict, fmt.Errorf("approval for order %s is already %s", orderID, pending.Status))
+		return
+	}
+
+	payload, _ := json.Marshal(decision)
+	if err := s.client.RaiseEvent(r.Context(), api.InstanceID(orderID), approval.EventName, api.WithRawEventData(string(payload))); err != nil {
+		s.writeClientError(w, "failed to raise approval event", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if s.deadLetters == nil {
+		writeJSON(w, http.StatusOK, []*deadletter.Entry{})
+		return
+	}
+
+	// ?status=pending|replayed|discarded filters; no status lists every entry
+	entries, err := s.deadLetters.List(r.Context(), deadletter.Status(r.URL.Query().Get("status")))
+	if err != nil {
+		s.logger.Error("failed to list dead-letter entries", err)
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to list dead-letter entries: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (s *Server) handleGetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	entry, ok := s.loadDeadLetter(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+// handleReplayDeadLetter schedules a dead_letter_replay orchestration for a
+// pending entry; the orchestration marks the entry replayed if it succeeds
+func (s *Server) handleReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	entry, ok := s.loadDeadLetter(w, r)
+	if !ok {
+		return
+	}
+	if entry.Status != deadletter.StatusPending {
+		writeError(w, http.StatusConflict, fmt.Errorf("%w: %d is %s", deadletter.ErrEntryResolved, entry.ID, entry.Status))
+		return
+	}
+
+	input, _ := json.Marshal(workflows.DeadLetterReplayInput{
+		EntryID:  entry.ID,
+		Activity: entry.Activity,
+		Payload:  entry.Payload,
+		Attempts: entry.Attempts,
+	})
+	// The attempt count changes with every replay, so each replay gets its
+	// own instance while a double submit of the same one is rejected
+	instanceID := fmt.Sprintf("dead-letter-%d-%d", entry.ID, entry.Attempts)
+	id, err := s.client.ScheduleNewOrchestration(r.Context(), "dead_letter_replay",
+		api.WithInstanceID(api.InstanceID(instanceID)),
+		api.WithRawInput(string(input)),
+	)
+	if err != nil {
+		s.writeClientError(w, "failed to schedule dead-letter replay", err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, ReplayResponse{EntryID: entry.ID, InstanceID: string(id)})
+}
+
+func (s *Server) handleDiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	entry, ok := s.loadDeadLetter(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.deadLetters.Resolve(r.Context(), entry.ID, deadletter.StatusDiscarded); err != nil {
+		if errors.Is(err, deadletter.ErrEntryResolved) {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+		s.logger.Error("failed to discard dead-letter entry", err)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.logger.Logger.Warn().Int64("entry_id", entry.ID).Str("activity", entry.Activity).Msg("dead-letter entry discarded via API")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadDeadLetter reads the entry named by the {id} path value, writing the
+// error response if there is none
+func (s *Server) loadDeadLetter(w http.ResponseWriter, r *http.Request) (*deadletter.Entry, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid dead-letter entry ID %q", r.PathValue("id")))
+		return nil, false
+	}
+	if s.deadLetters == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %d", deadletter.ErrEntryNotFound, id))
+		return nil, false
+	}
+
+	entry, err := s.deadLetters.Get(r.Context(), id)
+	if errors.Is(err, deadletter.ErrEntryNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return nil, false
+	}
+	if err != nil {
+		s.logger.Error("failed to load dead-letter entry", err)
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to load dead-letter entry: %w", err))
+		return nil, false
+	}
+	return entry, true
+}
+
+// writeClientError maps task hub client errors to HTTP status codes
+func (s *Server) writeClientError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, api.ErrInstanceNotFound):
+		writeError(w, http.StatusNotFound, err)
+		return
+	case errors.Is(err, api.ErrDuplicateInstance):
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	s.logger.Error(msg, err)
+	writeError(w, http.StatusInternalServerError, fmt.Errorf("%s: %w", msg, err))
+}
+
+// decodeReason reads an optional ReasonRequest body
+func decodeReason(w http.ResponseWriter, r *http.Request) (ReasonRequest, bool) {
+	var req ReasonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return req, false
+	}
+	return req, true
+}
+
+// rawJSON converts a serialized payload into raw JSON, quoting it if needed
+func rawJSON(s string) json.RawMessage {
+	if s == "" {
+		return nil
+	}
+	if json.Valid([]byte(s)) {
+		return json.RawMessage(s)
+	}
+	quoted, _ := json.Marshal(s)
+	return quoted
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
@@ -0,0 +1,515 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/microsoft/durabletask-go/api"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Youmanvi/taskorchestrator/internal/activities/approval"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/deadletter"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/notification"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/config"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/idempotency"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+	"github.com/Youmanvi/taskorchestrator/internal/middleware"
+	"github.com/Youmanvi/taskorchestrator/internal/workflows"
+)
+
+// fakeClient records calls made by the server
+type fakeClient struct {
+	nextID     api.InstanceID
+	scheduled  []string
+	inputs     []string
+	events     []string
+	terminated []api.InstanceID
+	purged     []api.InstanceID
+	metadata   map[api.InstanceID]*api.OrchestrationMetadata
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{metadata: make(map[api.InstanceID]*api.OrchestrationMetadata)}
+}
+
+func (f *fakeClient) ScheduleNewOrchestration(ctx context.Context, orchestrator interface{}, opts ...api.NewOrchestrationOptions) (api.InstanceID, error) {
+	if _, ok := f.metadata[f.nextID]; ok {
+		return api.EmptyInstanceID, fmt.Errorf("failed to start orchestration: %w", api.ErrDuplicateInstance)
+	}
+	f.metadata[f.nextID] = &api.OrchestrationMetadata{InstanceID: f.nextID}
+	f.scheduled = append(f.scheduled, orchestrator.(string))
+	f.inputs = append(f.inputs, inputOf(opts))
+	return f.nextID, nil
+}
+
+// inputOf returns the serialized input that opts set on a create request.
+// The request type is internal to durabletask, so it is built by reflection.
+func inputOf(opts []api.NewOrchestrationOptions) string {
+	if len(opts) == 0 {
+		return ""
+	}
+	req := reflect.New(reflect.TypeOf(opts[0]).In(0).Elem())
+	for _, opt := range opts {
+		reflect.ValueOf(opt).Call([]reflect.Value{req})
+	}
+	input := req.Elem().FieldByName("Input")
+	if input.IsNil() {
+		return ""
+	}
+	return input.Elem().FieldByName("Value").String()
+}
+
+func (f *fakeClient) FetchOrchestrationMetadata(ctx context.Context, id api.InstanceID) (*api.OrchestrationMetadata, error) {
+	md, ok := f.metadata[id]
+	if !ok {
+		return nil, api.ErrInstanceNotFound
+	}
+	return md, nil
+}
+
+func (f *fakeClient) RaiseEvent(ctx context.Context, id api.InstanceID, eventName string, opts ...api.RaiseEventOptions) error {
+	if _, ok := f.metadata[id]; !ok {
+		return api.ErrInstanceNotFound
+	}
+	f.events = append(f.events, eventName)
+	return nil
+}
+
+func (f *fakeClient) SuspendOrchestration(ctx context.Context, id api.InstanceID, reason string) error {
+	return nil
+}
+
+func (f *fakeClient) ResumeOrchestration(ctx context.Context, id api.InstanceID, reason string) error {
+	return nil
+}
+
+func (f *fakeClient) TerminateOrchestration(ctx context.Context, id api.InstanceID, opts ...api.TerminateOptions) error {
+	f.terminated = append(f.terminated, id)
+	return nil
+}
+
+func (f *fakeClient) PurgeOrchestrationState(ctx context.Context, id api.InstanceID, opts ...api.PurgeOptions) error {
+	if _, ok := f.metadata[id]; !ok {
+		return api.ErrInstanceNotFound
+	}
+	delete(f.metadata, id)
+	f.purged = append(f.purged, id)
+	return nil
+}
+
+func newTestServer(client *fakeClient) http.Handler {
+	logger := observability.NewLogger(&config.ObservabilityConfig{LogLevel: "error"})
+	return NewServer(client, logger, 0).Handler()
+}
+
+func TestServer_StartOrder(t *testing.T) {
+	client := newFakeClient()
+	client.nextID = "ORD-1"
+	handler := newTestServer(client)
+
+	body := `{
+		"Order": {
+			"CustomerID": "CUST-1",
+			"Items": [{"SKU": "ITEM-001", "Quantity": 1, "Price": "10.00"}],
+			"TotalAmount": "10.00"
+		},
+		"CustomerEmail": "customer@example.com"
+	}`
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders/ORD-1", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	var resp StartOrderResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "ORD-1", resp.InstanceID)
+	assert.Equal(t, []string{"order_processing"}, client.scheduled)
+}
+
+func TestServer_StartOrder_PricesFromItems(t *testing.T) {
+	client := newFakeClient()
+	client.nextID = "ORD-1"
+	handler := newTestServer(client)
+
+	// The total is optional and a client status is ignored: the order starts
+	// pending, priced from its items
+	body := `{
+		"Order": {
+			"CustomerID": "CUST-1",
+			"Items": [{"SKU": "ITEM-001", "Quantity": 2, "Price": "10.00"}],
+			"Status": "confirmed"
+		},
+		"CustomerEmail": "customer@example.com"
+	}`
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders/ORD-1", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	require.Len(t, client.inputs, 1)
+
+	var input workflows.OrderProcessingInput
+	require.NoError(t, json.Unmarshal([]byte(client.inputs[0]), &input))
+	assert.Equal(t, "ORD-1", input.Order.ID)
+	assert.Equal(t, domain.OrderStatusPending, input.Order.Status)
+	assert.True(t, decimal.RequireFromString("20.00").Equal(input.Order.TotalAmount), "total was %s", input.Order.TotalAmount)
+}
+
+func TestServer_StartOrder_Duplicate(t *testing.T) {
+	client := newFakeClient()
+	client.nextID = "ORD-1"
+	handler := newTestServer(client)
+
+	body := `{
+		"Order": {
+			"CustomerID": "CUST-1",
+			"Items": [{"SKU": "ITEM-001", "Quantity": 1, "Price": "10.00"}],
+			"TotalAmount": "10.00"
+		},
+		"CustomerEmail": "customer@example.com"
+	}`
+
+	for _, want := range []int{http.StatusAccepted, http.StatusConflict} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/orders/ORD-1", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, want, rec.Code)
+	}
+	assert.Equal(t, []string{"order_processing"}, client.scheduled)
+}
+
+func TestServer_StartOrder_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"malformed json", `{`},
+		{"mismatched order ID", `{"Order": {"ID": "ORD-2", "CustomerID": "C", "Items": [{"SKU": "A", "Quantity": 1, "Price": "1"}], "TotalAmount": "1"}, "CustomerEmail": "a@b.c"}`},
+		{"missing items", `{"Order": {"CustomerID": "C", "TotalAmount": "1"}, "CustomerEmail": "a@b.c"}`},
+		{"missing email", `{"Order": {"CustomerID": "C", "Items": [{"SKU": "A", "Quantity": 1, "Price": "1"}], "TotalAmount": "1"}}`},
+		{"mismatched total", `{"Order": {"CustomerID": "C", "Items": [{"SKU": "A", "Quantity": 2, "Price": "5"}], "TotalAmount": "1"}, "CustomerEmail": "a@b.c"}`},
+		{"zero quantity", `{"Order": {"CustomerID": "C", "Items": [{"SKU": "A", "Quantity": 0, "Price": "5"}]}, "CustomerEmail": "a@b.c"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeClient()
+			handler := newTestServer(client)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/orders/ORD-1", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Empty(t, client.scheduled)
+		})
+	}
+}
+
+func TestServer_GetOrchestration(t *testing.T) {
+	client := newFakeClient()
+	client.metadata["ORD-1"] = &api.OrchestrationMetadata{
+		InstanceID:       "ORD-1",
+		Name:             "order_processing",
+		SerializedOutput: `{"Status":"confirmed"}`,
+	}
+	handler := newTestServer(client)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orchestrations/ORD-1", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var status OrchestrationStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, "ORD-1", status.InstanceID)
+	assert.Equal(t, "order_processing", status.Name)
+	assert.JSONEq(t, `{"Status":"confirmed"}`, string(status.Output))
+}
+
+func TestServer_NotFound(t *testing.T) {
+	handler := newTestServer(newFakeClient())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orchestrations/missing", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/orchestrations/missing/events/approval", strings.NewReader(`{}`))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_Lifecycle(t *testing.T) {
+	client := newFakeClient()
+	client.metadata["ORD-1"] = &api.OrchestrationMetadata{InstanceID: "ORD-1"}
+	handler := newTestServer(client)
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{http.MethodPost, "/api/v1/orchestrations/ORD-1/events/approval", `{"approved":true}`, http.StatusAccepted},
+		{http.MethodPost, "/api/v1/orchestrations/ORD-1/suspend", `{"reason":"investigating"}`, http.StatusAccepted},
+		{http.MethodPost, "/api/v1/orchestrations/ORD-1/resume", ``, http.StatusAccepted},
+		{http.MethodPost, "/api/v1/orchestrations/ORD-1/terminate", `{"reason":"cancelled"}`, http.StatusAccepted},
+		{http.MethodDelete, "/api/v1/orchestrations/ORD-1", ``, http.StatusNoContent},
+	}
+
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, r.want, rec.Code, "%s %s", r.method, r.path)
+	}
+
+	assert.Equal(t, []string{"approval"}, client.events)
+	assert.Equal(t, []api.InstanceID{"ORD-1"}, client.terminated)
+	assert.Equal(t, []api.InstanceID{"ORD-1"}, client.purged)
+}
+
+func TestServer_PurgeForgetsIdempotencyKeys(t *testing.T) {
+	ctx := context.Background()
+	store := idempotency.NewMemoryStore()
+	require.NoError(t, store.Put(ctx, "ORD-1", "ORD-1/payment:charge#1", "payment:charge", []byte(`{}`)))
+
+	client := newFakeClient()
+	client.metadata["ORD-1"] = &api.OrchestrationMetadata{InstanceID: "ORD-1"}
+	logger := observability.NewLogger(&config.ObservabilityConfig{LogLevel: "error"})
+	server := NewServer(client, logger, 0)
+	server.SetIdempotency(store)
+	handler := server.Handler()
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/orchestrations/ORD-1", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	// A new orchestration reusing ORD-1 must not replay the old charge
+	_, found, err := store.Get(ctx, "ORD-1/payment:charge#1")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// Purging again reports the instance gone
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/orchestrations/ORD-1", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_CircuitBreakers(t *testing.T) {
+	breakers := middleware.NewBreakerRegistry(nil, nil)
+	breakers.Register("payment", "payment:charge", 0.5, time.Minute)
+
+	logger := observability.NewLogger(&config.ObservabilityConfig{LogLevel: "error"})
+	server := NewServer(newFakeClient(), logger, 0)
+	server.SetCircuitBreakers(breakers)
+	handler := server.Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/circuit-breakers", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var states []middleware.BreakerState
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&states))
+	require.Len(t, states, 1)
+	assert.Equal(t, "payment", states[0].Name)
+	assert.Equal(t, "closed", states[0].State)
+	assert.Equal(t, []string{"payment:charge"}, states[0].Activities)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/circuit-breakers/payment/reset", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/circuit-breakers/missing/reset", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_Approvals(t *testing.T) {
+	ctx := context.Background()
+	store := approval.NewMockApprovalStore()
+	now := time.Now().UTC()
+	for _, id := range []string{"ORD-1", "ORD-2"} {
+		a, err := domain.NewApproval(id, "CUST-1", decimal.NewFromInt(5000), now, now.Add(time.Hour))
+		require.NoError(t, err)
+		require.NoError(t, store.Create(ctx, a))
+	}
+	decided, err := store.Get(ctx, "ORD-2")
+	require.NoError(t, err)
+	require.NoError(t, decided.Reject("alice", "too large", now))
+	require.NoError(t, store.Save(ctx, decided))
+
+	client := newFakeClient()
+	client.metadata["ORD-1"] = &api.OrchestrationMetadata{InstanceID: "ORD-1"}
+	logger := observability.NewLogger(&config.ObservabilityConfig{LogLevel: "error"})
+	server := NewServer(client, logger, 0)
+	server.SetApprovals(store)
+	handler := server.Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/approvals", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var pending []ApprovalResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&pending))
+	require.Len(t, pending, 1)
+	assert.Equal(t, "ORD-1", pending[0].OrderID)
+	assert.Equal(t, "5000.00", pending[0].Amount)
+
+	requests := []struct {
+		path string
+		body string
+		want int
+	}{
+		{"/api/v1/approvals/ORD-1", `{"approver":"alice","decision":"maybe"}`, http.StatusBadRequest},
+		{"/api/v1/approvals/ORD-1", `{"decision":"approve"}`, http.StatusBadRequest},
+		{"/api/v1/approvals/missing", `{"approver":"alice","decision":"approve"}`, http.StatusNotFound},
+		{"/api/v1/approvals/ORD-2", `{"approver":"alice","decision":"approve"}`, http.StatusConflict},
+		{"/api/v1/approvals/ORD-1", `{"approver":"alice","decision":"approve"}`, http.StatusAccepted},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(http.MethodPost, r.path, strings.NewReader(r.body))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, r.want, rec.Code, "%s %s", r.path, r.body)
+	}
+
+	assert.Equal(t, []string{approval.EventName}, client.events)
+}
+
+func TestServer_DeadLetters(t *testing.T) {
+	ctx := context.Background()
+	store := deadletter.NewMockDeadLetterStore()
+	for _, id := range []string{"ORD-1", "ORD-2"} {
+		_, err := store.Add(ctx, deadletter.Entry{
+			OrchestrationID: id,
+			Activity:        "notification:order_confirmation",
+			Payload:         json.RawMessage(`{"OrderID":"` + id + `"}`),
+			ErrorCode:       "EMAIL_SEND_FAILED",
+			Attempts:        3,
+		})
+		require.NoError(t, err)
+	}
+
+	client := newFakeClient()
+	client.nextID = "dead-letter-1-3"
+	logger := observability.NewLogger(&config.ObservabilityConfig{LogLevel: "error"})
+	server := NewServer(client, logger, 0)
+	server.SetDeadLetters(store)
+	handler := server.Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/dead-letters?status=pending", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var entries []deadletter.Entry
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&entries))
+	require.Len(t, entries, 2)
+	assert.Equal(t, "ORD-1", entries[0].OrchestrationID)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/dead-letters/1", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var entry deadletter.Entry
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&entry))
+	assert.JSONEq(t, `{"OrderID":"ORD-1"}`, string(entry.Payload))
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/dead-letters/1/replay", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	var replay ReplayResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&replay))
+	assert.Equal(t, "dead-letter-1-3", replay.InstanceID)
+	assert.Equal(t, []string{"dead_letter_replay"}, client.scheduled)
+
+	requests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/api/v1/dead-letters/abc", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/dead-letters/99", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/dead-letters/1/replay", http.StatusConflict},
+		{http.MethodPost, "/api/v1/dead-letters/2/discard", http.StatusNoContent},
+		{http.MethodPost, "/api/v1/dead-letters/2/discard", http.StatusConflict},
+		{http.MethodPost, "/api/v1/dead-letters/2/replay", http.StatusConflict},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.path, nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, r.want, rec.Code, "%s %s", r.method, r.path)
+	}
+
+	discarded, err := store.List(ctx, deadletter.StatusDiscarded)
+	require.NoError(t, err)
+	require.Len(t, discarded, 1)
+	assert.Equal(t, "ORD-2", discarded[0].OrchestrationID)
+}
+
+func TestServer_Notifications(t *testing.T) {
+	ctx := context.Background()
+	outbox := notification.NewMockOutbox()
+	_, err := outbox.Enqueue(ctx, notification.OutboxMessage{
+		OrderID:   "ORD-1",
+		EventType: notification.EventOrderConfirmed,
+		Channel:   notification.ChannelEmail,
+		Recipient: "customer@example.com",
+		Subject:   "Order ORD-1 Confirmed",
+		Body:      "Your order ORD-1 has been confirmed and is being processed.",
+	})
+	require.NoError(t, err)
+	key := notification.OutboxKey{OrderID: "ORD-1", EventType: notification.EventOrderConfirmed, Channel: notification.ChannelEmail}
+	_, err = outbox.ClaimDue(ctx, time.Now(), 10, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, outbox.MarkSent(ctx, key, 1, "MSG_1", time.Now()))
+
+	logger := observability.NewLogger(&config.ObservabilityConfig{LogLevel: "error"})
+	server := NewServer(newFakeClient(), logger, 0)
+	server.SetOutbox(outbox)
+	handler := server.Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/ORD-1/notifications", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var messages []notification.OutboxMessage
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&messages))
+	require.Len(t, messages, 1)
+	assert.Equal(t, notification.OutboxStatusSent, messages[0].Status)
+	assert.Equal(t, "MSG_1", messages[0].MessageID)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/orders/ORD-2/notifications", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+}
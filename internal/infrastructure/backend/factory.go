@@ -1,6 +1,10 @@
 package backend
 
 import (
+	"context"
+	"errors"
+
+	"github.com/microsoft/durabletask-go/api"
 	"github.com/microsoft/durabletask-go/backend"
 	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/config"
 )
@@ -10,3 +14,13 @@ import (
 func NewBackend(cfg *config.BackendConfig) (backend.Backend, error) {
 	return NewSQLiteBackend(cfg)
 }
+
+// Ping verifies the backend store is reachable by looking up an instance
+// that never exists; only a not-found result counts as healthy
+func Ping(ctx context.Context, be backend.Backend) error {
+	_, err := be.GetOrchestrationMetadata(ctx, api.InstanceID("__readyz__"))
+	if err == nil || errors.Is(err, api.ErrInstanceNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,148 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/microsoft/durabletask-go/api"
+	"github.com/microsoft/durabletask-go/backend"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+)
+
+// OrchestrationStarted describes an orchestration instance whose first
+// episode has been committed
+type OrchestrationStarted struct {
+	Name       string
+	InstanceID api.InstanceID
+	CreatedAt  time.Time
+}
+
+// OrchestrationFinished describes an orchestration instance whose final
+// episode has been committed
+type OrchestrationFinished struct {
+	Name          string
+	InstanceID    api.InstanceID
+	RuntimeStatus api.OrchestrationStatus
+	Output        string // Serialized orchestrator output, empty on failure
+	Duration      time.Duration
+}
+
+// Succeeded reports whether the orchestrator returned without error
+func (f OrchestrationFinished) Succeeded() bool {
+	return f.RuntimeStatus == api.RUNTIME_STATUS_COMPLETED
+}
+
+// LifecycleHook observes orchestration instances starting and finishing.
+// Hooks run once per instance, after the work item is committed, never from
+// replayed orchestrator code.
+type LifecycleHook interface {
+	OrchestrationStarted(ctx context.Context, e OrchestrationStarted)
+	OrchestrationFinished(ctx context.Context, e OrchestrationFinished)
+}
+
+// lifecycleBackend decorates a backend with lifecycle hooks
+type lifecycleBackend struct {
+	backend.Backend
+	hooks []LifecycleHook
+}
+
+// WithLifecycleHooks wraps a backend so hooks observe every orchestration
+// work item it commits
+func WithLifecycleHooks(be backend.Backend, hooks ...LifecycleHook) backend.Backend {
+	if len(hooks) == 0 {
+		return be
+	}
+	return &lifecycleBackend{Backend: be, hooks: hooks}
+}
+
+// CompleteOrchestrationWorkItem commits the work item, then notifies hooks.
+// A failed commit is retried by the worker, so hooks only run on success.
+func (b *lifecycleBackend) CompleteOrchestrationWorkItem(ctx context.Context, wi *backend.OrchestrationWorkItem) error {
+	if err := b.Backend.CompleteOrchestrationWorkItem(ctx, wi); err != nil {
+		return err
+	}
+
+	state := wi.State
+	if state == nil {
+		return nil
+	}
+	name, _ := state.Name()
+	createdAt, _ := state.CreatedTime()
+
+	if startsInstance(wi) {
+		started := OrchestrationStarted{Name: name, InstanceID: wi.InstanceID, CreatedAt: createdAt}
+		for _, hook := range b.hooks {
+			hook.OrchestrationStarted(ctx, started)
+		}
+	}
+
+	if state.IsCompleted() {
+		finished := OrchestrationFinished{
+			Name:          name,
+			InstanceID:    wi.InstanceID,
+			RuntimeStatus: state.RuntimeStatus(),
+		}
+		finished.Output, _ = state.Output()
+		if completedAt, err := state.CompletedTime(); err == nil && !createdAt.IsZero() {
+			finished.Duration = completedAt.Sub(createdAt)
+		}
+		for _, hook := range b.hooks {
+			hook.OrchestrationFinished(ctx, finished)
+		}
+	}
+
+	return nil
+}
+
+// startsInstance reports whether the work item carries the ExecutionStarted
+// event of a new instance
+func startsInstance(wi *backend.OrchestrationWorkItem) bool {
+	for _, e := range wi.NewEvents {
+		if e.GetExecutionStarted() != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// metricsHook records orchestration metrics from lifecycle events
+type metricsHook struct {
+	metrics *observability.Metrics
+}
+
+// NewMetricsHook returns a hook that records orchestration start, completion,
+// failure and duration, labelled by orchestrator name and final status
+func NewMetricsHook(metrics *observability.Metrics) LifecycleHook {
+	return &metricsHook{metrics: metrics}
+}
+
+func (h *metricsHook) OrchestrationStarted(ctx context.Context, e OrchestrationStarted) {
+	h.metrics.RecordOrchestrationStart(e.Name)
+}
+
+func (h *metricsHook) OrchestrationFinished(ctx context.Context, e OrchestrationFinished) {
+	if e.Succeeded() {
+		h.metrics.RecordOrchestrationCompleted(e.Name, OutputStatus(e.Output), e.Duration)
+		return
+	}
+	h.metrics.RecordOrchestrationFailed(e.Name, RuntimeStatusName(e.RuntimeStatus), e.Duration)
+}
+
+// OutputStatus returns the Status field of a serialized orchestrator output,
+// such as OrderProcessingOutput.Status, or "completed" when there is none
+func OutputStatus(output string) string {
+	var out struct {
+		Status string
+	}
+	if err := json.Unmarshal([]byte(output), &out); err != nil || out.Status == "" {
+		return "completed"
+	}
+	return out.Status
+}
+
+// RuntimeStatusName returns the lowercase runtime status, e.g. "failed"
+func RuntimeStatusName(status api.OrchestrationStatus) string {
+	return strings.ToLower(strings.TrimPrefix(status.String(), "ORCHESTRATION_STATUS_"))
+}
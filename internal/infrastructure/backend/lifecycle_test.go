@@ -0,0 +1,46 @@
+package backend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/microsoft/durabletask-go/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+)
+
+func TestOutputStatus(t *testing.T) {
+	assert.Equal(t, "confirmed", OutputStatus(`{"Status":"confirmed","OrderID":"ORD-1"}`))
+	assert.Equal(t, "completed", OutputStatus(`"done"`))
+	assert.Equal(t, "completed", OutputStatus(""))
+	assert.Equal(t, "failed", RuntimeStatusName(api.RUNTIME_STATUS_FAILED))
+}
+
+func TestMetricsHook(t *testing.T) {
+	metrics := observability.NewMetrics(prometheus.NewRegistry())
+	hook := NewMetricsHook(metrics)
+	ctx := context.Background()
+
+	hook.OrchestrationStarted(ctx, OrchestrationStarted{Name: "order_processing", InstanceID: "ORD-1"})
+	hook.OrchestrationFinished(ctx, OrchestrationFinished{
+		Name:          "order_processing",
+		InstanceID:    "ORD-1",
+		RuntimeStatus: api.RUNTIME_STATUS_COMPLETED,
+		Output:        `{"Status":"failed"}`,
+		Duration:      2 * time.Second,
+	})
+	hook.OrchestrationFinished(ctx, OrchestrationFinished{
+		Name:          "order_processing",
+		InstanceID:    "ORD-2",
+		RuntimeStatus: api.RUNTIME_STATUS_TERMINATED,
+	})
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.OrchestrationStarted.WithLabelValues("order_processing")))
+	// A business-level failure still ran to completion
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.OrchestrationCompleted.WithLabelValues("order_processing", "failed")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.OrchestrationFailed.WithLabelValues("order_processing", "terminated")))
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.OrchestrationDuration))
+}
@@ -18,11 +18,6 @@ func NewSQLiteBackend(cfg *config.BackendConfig) (backend.Backend, error) {
 		return nil, fmt.Errorf("failed to create data directory: %w", err)
 	}
 
-	// Create SQLite backend
-	sqliteBackend := sqlite.NewSqliteBackend(cfg.SQLiteFile)
-	if sqliteBackend == nil {
-		return nil, fmt.Errorf("failed to create SQLite backend at %s", cfg.SQLiteFile)
-	}
-
-	return sqliteBackend, nil
+	// Create SQLite backend; the database is opened when the worker starts
+	return sqlite.NewSqliteBackend(sqlite.NewSqliteOptions(cfg.SQLiteFile), backend.DefaultLogger()), nil
 }
@@ -1,17 +1,24 @@
 package config
 
 import (
+	"math"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	App          AppConfig
-	Backend      BackendConfig
+	App           AppConfig
+	Backend       BackendConfig
 	Observability ObservabilityConfig
-	Activities   ActivitiesConfig
+	Activities    ActivitiesConfig
+	Payment       PaymentConfig
+	Inventory     InventoryConfig
+	Approval      ApprovalConfig
+	Notification  NotificationConfig
 }
 
 type AppConfig struct {
@@ -30,17 +37,238 @@ type ObservabilityConfig struct {
 	LogLevel       string
 	LogFormat      string // "json" or "text"
 	MetricsEnabled bool
-	MetricsPort    int
+	MetricsPort    int  // Serves /metrics, /healthz and /readyz
+	PprofEnabled   bool // Also serve /debug/pprof on MetricsPort
 	TracingEnabled bool
 	ZipkinEndpoint string
+	// TelemetryFile is the SQLite file backing the logs and task_events tables
+	TelemetryFile      string
+	TelemetryBatchSize int
 }
 
 type ActivitiesConfig struct {
-	RetryMaxAttempts    int
-	RetryBackoffMs      int
-	TimeoutSeconds      int
+	RetryMaxAttempts        int
+	RetryBackoffMs          int
+	RetryMaxBackoffMs       int
+	TimeoutSeconds          int
+	CircuitBreakerEnabled   bool
 	CircuitBreakerThreshold float64
 	CircuitBreakerTimeout   time.Duration
+	// IdempotencyFile is the SQLite file recording completed step outputs;
+	// empty disables the idempotency middleware
+	IdempotencyFile string
+	// DeadLetterFile is the SQLite file holding non-critical activity calls
+	// that failed after their retries; empty keeps them in memory
+	DeadLetterFile string
+	// Overrides are keyed by registered activity name ("payment:charge") or
+	// by a prefix wildcard ("inventory:*"). Zero fields inherit.
+	Overrides map[string]ActivityOverride
+}
+
+// PaymentConfig selects the payment gateway used by payment activities
+type PaymentConfig struct {
+	Backend string // "mock" or "http"
+	BaseURL string // Payment provider API, for the http backend
+	// APIKey is usually supplied through APP_PAYMENT_API_KEY rather than the
+	// config file
+	APIKey   string
+	Timeout  time.Duration // Per provider request
+	Currency string        // ISO 4217 code charged when an order has none
+}
+
+type InventoryConfig struct {
+	Backend        string // "mock" or "sqlite"
+	SQLiteFile     string
+	ReservationTTL time.Duration // How long a reservation holds stock before the sweeper expires it
+	SweepInterval  time.Duration
+	// MaxParallelism caps the concurrent per-SKU availability checks and
+	// per-warehouse reservations of one order; 0 means no cap
+	MaxParallelism int
+	// Stock seeds on-hand quantities at startup (sqlite backend only)
+	Stock []StockLevel
+}
+
+// ApprovalConfig controls the human approval step for high-value orders
+type ApprovalConfig struct {
+	// Threshold is the decimal order total above which an order waits for
+	// approval before it is charged; empty disables approvals
+	Threshold string
+	// Timeout is how long an order waits for a decision before it is
+	// rejected automatically. Keep it below Inventory.ReservationTTL so the
+	// order's stock is still held when it is approved.
+	Timeout    time.Duration
+	Backend    string // "mock" or "sqlite"
+	SQLiteFile string
+}
+
+// NotificationConfig controls notification templates, channels, the outbox
+// and its dispatcher
+type NotificationConfig struct {
+	// OutboxFile is the SQLite file holding queued notifications; empty
+	// keeps them in memory
+	OutboxFile       string
+	DispatchInterval time.Duration // How often due notifications are sent
+	MaxAttempts      int           // Delivery attempts before a notification is marked failed
+	RetryBackoff     time.Duration // Delay before the first redelivery, doubling after each
+	// TemplateDir holds <locale>/<event>.<part>.tmpl files; empty uses the
+	// built-in templates
+	TemplateDir   string
+	DefaultLocale string // Used when a customer's locale has no template
+	// Routes maps event types to the channels ("email", "sms", "webhook")
+	// they are sent on; unrouted events are emailed
+	Routes         map[string][]string
+	WebhookURL     string // Enables the webhook channel
+	WebhookTimeout time.Duration
+	EmailBackend   string // "mock" or "smtp"
+	SMTP           SMTPConfig
+}
+
+// SMTPConfig configures the SMTP relay used when Notification.EmailBackend
+// is "smtp"
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	From     string // Envelope sender and From header
+	Username string // Enables AUTH PLAIN when set
+	// Password is usually supplied through APP_SMTP_PASSWORD rather than
+	// the config file
+	Password string
+	// StartTLS is "required", "opportunistic" or "disabled"
+	StartTLS           string
+	InsecureSkipVerify bool
+	PoolSize           int           // Idle connections kept open between sends
+	IdleTimeout        time.Duration // Idle connections older than this are reopened
+	Timeout            time.Duration // Per message, including connecting
+}
+
+// StockLevel is the on-hand quantity for a SKU
+type StockLevel struct {
+	SKU      string
+	Quantity int32
+}
+
+// ActivityOverride overrides activity defaults for matching activities
+type ActivityOverride struct {
+	RetryMaxAttempts        int
+	RetryBackoffMs          int
+	RetryMaxBackoffMs       int
+	RetryableCodes          []string
+	TimeoutSeconds          int
+	CircuitBreakerEnabled   *bool
+	CircuitBreakerName      string // Dependency whose breaker is shared; defaults to the name prefix
+	CircuitBreakerThreshold float64
+	CircuitBreakerTimeout   time.Duration
+	RateLimitPerSecond      float64
+	RateLimitBurst          int
+}
+
+// ActivityPolicy is the effective configuration for a single activity
+type ActivityPolicy struct {
+	RetryMaxAttempts        int
+	RetryBackoff            time.Duration
+	RetryMaxBackoff         time.Duration
+	RetryableCodes          []string
+	Timeout                 time.Duration
+	CircuitBreakerEnabled   bool
+	CircuitBreakerName      string
+	CircuitBreakerThreshold float64
+	CircuitBreakerTimeout   time.Duration
+	RateLimitPerSecond      float64 // 0 disables rate limiting
+	RateLimitBurst          int
+}
+
+// PolicyFor resolves the effective policy for an activity. Defaults are
+// applied first, then matching wildcard overrides from least to most
+// specific, then an exact-name override.
+func (c ActivitiesConfig) PolicyFor(name string) ActivityPolicy {
+	policy := ActivityPolicy{
+		RetryMaxAttempts:        c.RetryMaxAttempts,
+		RetryBackoff:            time.Duration(c.RetryBackoffMs) * time.Millisecond,
+		RetryMaxBackoff:         time.Duration(c.RetryMaxBackoffMs) * time.Millisecond,
+		Timeout:                 time.Duration(c.TimeoutSeconds) * time.Second,
+		CircuitBreakerEnabled:   c.CircuitBreakerEnabled,
+		CircuitBreakerName:      dependencyOf(name),
+		CircuitBreakerThreshold: c.CircuitBreakerThreshold,
+		CircuitBreakerTimeout:   c.CircuitBreakerTimeout,
+	}
+
+	patterns := make([]string, 0, len(c.Overrides))
+	for pattern := range c.Overrides {
+		if MatchesActivity(pattern, name) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		return patternSpecificity(patterns[i]) < patternSpecificity(patterns[j])
+	})
+
+	for _, pattern := range patterns {
+		c.Overrides[pattern].applyTo(&policy)
+	}
+
+	return policy
+}
+
+// applyTo copies the non-zero override fields onto a policy
+func (o ActivityOverride) applyTo(policy *ActivityPolicy) {
+	if o.RetryMaxAttempts > 0 {
+		policy.RetryMaxAttempts = o.RetryMaxAttempts
+	}
+	if o.RetryBackoffMs > 0 {
+		policy.RetryBackoff = time.Duration(o.RetryBackoffMs) * time.Millisecond
+	}
+	if o.RetryMaxBackoffMs > 0 {
+		policy.RetryMaxBackoff = time.Duration(o.RetryMaxBackoffMs) * time.Millisecond
+	}
+	if len(o.RetryableCodes) > 0 {
+		policy.RetryableCodes = o.RetryableCodes
+	}
+	if o.TimeoutSeconds > 0 {
+		policy.Timeout = time.Duration(o.TimeoutSeconds) * time.Second
+	}
+	if o.CircuitBreakerEnabled != nil {
+		policy.CircuitBreakerEnabled = *o.CircuitBreakerEnabled
+	}
+	if o.CircuitBreakerName != "" {
+		policy.CircuitBreakerName = o.CircuitBreakerName
+	}
+	if o.CircuitBreakerThreshold > 0 {
+		policy.CircuitBreakerThreshold = o.CircuitBreakerThreshold
+	}
+	if o.CircuitBreakerTimeout > 0 {
+		policy.CircuitBreakerTimeout = o.CircuitBreakerTimeout
+	}
+	if o.RateLimitPerSecond > 0 {
+		policy.RateLimitPerSecond = o.RateLimitPerSecond
+	}
+	if o.RateLimitBurst > 0 {
+		policy.RateLimitBurst = o.RateLimitBurst
+	}
+}
+
+// MatchesActivity reports whether an override key matches an activity name.
+// Keys ending in "*" match by prefix; all other keys must match exactly.
+func MatchesActivity(pattern, name string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(name, prefix)
+	}
+	return pattern == name
+}
+
+// dependencyOf returns the downstream dependency an activity calls, taken from
+// its name prefix ("payment:charge" -> "payment")
+func dependencyOf(name string) string {
+	dependency, _, _ := strings.Cut(name, ":")
+	return dependency
+}
+
+// patternSpecificity orders patterns so exact names apply after any wildcard
+// and longer wildcard prefixes apply after shorter ones
+func patternSpecificity(pattern string) int {
+	if strings.HasSuffix(pattern, "*") {
+		return len(pattern) - 1
+	}
+	return math.MaxInt
 }
 
 // DefaultConfig returns configuration with sensible defaults
@@ -56,19 +284,58 @@ func DefaultConfig() *Config {
 			MaxConnection: 25,
 		},
 		Observability: ObservabilityConfig{
-			LogLevel:       "info",
-			LogFormat:      "json",
-			MetricsEnabled: true,
-			MetricsPort:    9090,
-			TracingEnabled: false,
-			ZipkinEndpoint: "http://localhost:9411/api/v2/spans",
+			LogLevel:           "info",
+			LogFormat:          "json",
+			MetricsEnabled:     true,
+			MetricsPort:        9090,
+			TracingEnabled:     false,
+			ZipkinEndpoint:     "http://localhost:9411/api/v2/spans",
+			TelemetryFile:      "data/telemetry.db",
+			TelemetryBatchSize: 100,
 		},
 		Activities: ActivitiesConfig{
 			RetryMaxAttempts:        3,
 			RetryBackoffMs:          100,
+			RetryMaxBackoffMs:       30000,
 			TimeoutSeconds:          30,
+			CircuitBreakerEnabled:   true,
 			CircuitBreakerThreshold: 0.5,
 			CircuitBreakerTimeout:   10 * time.Second,
+			IdempotencyFile:         "data/idempotency.db",
+			DeadLetterFile:          "data/deadletter.db",
+		},
+		Payment: PaymentConfig{
+			Backend:  "mock",
+			Timeout:  10 * time.Second,
+			Currency: "USD",
+		},
+		Inventory: InventoryConfig{
+			Backend:        "mock",
+			SQLiteFile:     "data/inventory.db",
+			ReservationTTL: 24 * time.Hour,
+			SweepInterval:  1 * time.Minute,
+			MaxParallelism: 10,
+		},
+		Approval: ApprovalConfig{
+			Timeout:    12 * time.Hour,
+			Backend:    "mock",
+			SQLiteFile: "data/approvals.db",
+		},
+		Notification: NotificationConfig{
+			OutboxFile:       "data/outbox.db",
+			DispatchInterval: 1 * time.Second,
+			MaxAttempts:      5,
+			RetryBackoff:     30 * time.Second,
+			DefaultLocale:    "en",
+			WebhookTimeout:   5 * time.Second,
+			EmailBackend:     "mock",
+			SMTP: SMTPConfig{
+				Port:        587,
+				StartTLS:    "required",
+				PoolSize:    4,
+				IdleTimeout: 30 * time.Second,
+				Timeout:     30 * time.Second,
+			},
 		},
 	}
 }
@@ -103,6 +370,12 @@ func LoadConfig(configPath string) (*Config, error) {
 	if zipkinEndpoint := os.Getenv("APP_ZIPKIN_ENDPOINT"); zipkinEndpoint != "" {
 		cfg.Observability.ZipkinEndpoint = zipkinEndpoint
 	}
+	if paymentAPIKey := os.Getenv("APP_PAYMENT_API_KEY"); paymentAPIKey != "" {
+		cfg.Payment.APIKey = paymentAPIKey
+	}
+	if smtpPassword := os.Getenv("APP_SMTP_PASSWORD"); smtpPassword != "" {
+		cfg.Notification.SMTP.Password = smtpPassword
+	}
 
 	return cfg, nil
 }
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivitiesConfig_PolicyFor(t *testing.T) {
+	enabled := true
+	cfg := ActivitiesConfig{
+		RetryMaxAttempts:        3,
+		RetryBackoffMs:          100,
+		TimeoutSeconds:          30,
+		CircuitBreakerThreshold: 0.5,
+		CircuitBreakerTimeout:   10 * time.Second,
+		Overrides: map[string]ActivityOverride{
+			"payment:*":      {TimeoutSeconds: 10, CircuitBreakerEnabled: &enabled},
+			"payment:charge": {TimeoutSeconds: 5, RateLimitPerSecond: 50, RateLimitBurst: 10},
+		},
+	}
+
+	// No matching override uses defaults
+	release := cfg.PolicyFor("inventory:release")
+	assert.Equal(t, 30*time.Second, release.Timeout)
+	assert.Equal(t, 3, release.RetryMaxAttempts)
+	assert.False(t, release.CircuitBreakerEnabled)
+	assert.Zero(t, release.RateLimitPerSecond)
+
+	// Wildcard applies to every matching activity
+	refund := cfg.PolicyFor("payment:refund")
+	assert.Equal(t, 10*time.Second, refund.Timeout)
+	assert.True(t, refund.CircuitBreakerEnabled)
+
+	// Exact match wins over the wildcard and inherits fields it does not set
+	charge := cfg.PolicyFor("payment:charge")
+	assert.Equal(t, 5*time.Second, charge.Timeout)
+	assert.True(t, charge.CircuitBreakerEnabled)
+	assert.Equal(t, 50.0, charge.RateLimitPerSecond)
+	assert.Equal(t, 10, charge.RateLimitBurst)
+	assert.Equal(t, 100*time.Millisecond, charge.RetryBackoff)
+	assert.Equal(t, "payment", charge.CircuitBreakerName)
+}
+
+func TestMatchesActivity(t *testing.T) {
+	assert.True(t, MatchesActivity("payment:charge", "payment:charge"))
+	assert.False(t, MatchesActivity("payment:charge", "payment:refund"))
+	assert.True(t, MatchesActivity("payment:*", "payment:refund"))
+	assert.False(t, MatchesActivity("payment:*", "inventory:reserve"))
+}
+
+func TestLoadConfig_ActivityOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `
+activities:
+  retryMaxAttempts: 4
+  timeoutSeconds: 20
+  overrides:
+    "payment:charge":
+      timeoutSeconds: 5
+      circuitBreakerEnabled: true
+      retryableCodes:
+        - PAYMENT_GATEWAY_UNAVAILABLE
+    "notification:*":
+      retryMaxAttempts: 10
+`
+	require.NoError(t, os.WriteFile(path, []byte(yaml), 0644))
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	charge := cfg.Activities.PolicyFor("payment:charge")
+	assert.Equal(t, 5*time.Second, charge.Timeout)
+	assert.True(t, charge.CircuitBreakerEnabled)
+	assert.Equal(t, []string{"PAYMENT_GATEWAY_UNAVAILABLE"}, charge.RetryableCodes)
+	assert.Equal(t, 4, charge.RetryMaxAttempts)
+
+	assert.Equal(t, 10, cfg.Activities.PolicyFor("notification:refund").RetryMaxAttempts)
+	assert.Equal(t, 20*time.Second, cfg.Activities.PolicyFor("inventory:check").Timeout)
+}
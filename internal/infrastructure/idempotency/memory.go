@@ -0,0 +1,51 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore is an in-memory idempotency store for tests
+type MemoryStore struct {
+	mu        sync.Mutex
+	outputs   map[string][]byte
+	instances map[string][]string // Keys recorded per instance ID
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		outputs:   make(map[string][]byte),
+		instances: make(map[string][]string),
+	}
+}
+
+// Get returns the recorded output for key
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	output, ok := s.outputs[key]
+	return output, ok, nil
+}
+
+// Put records the output for key. The first recorded output wins.
+func (s *MemoryStore) Put(ctx context.Context, instanceID, key, activity string, output []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.outputs[key]; !ok {
+		s.outputs[key] = output
+		s.instances[instanceID] = append(s.instances[instanceID], key)
+	}
+	return nil
+}
+
+// DeleteInstance forgets the outputs recorded for instanceID
+func (s *MemoryStore) DeleteInstance(ctx context.Context, instanceID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range s.instances[instanceID] {
+		delete(s.outputs, key)
+	}
+	delete(s.instances, instanceID)
+	return nil
+}
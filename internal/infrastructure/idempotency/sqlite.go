@@ -0,0 +1,139 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore persists completed activity outputs keyed by idempotency key
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (or creates) the idempotency store at dbPath
+func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open idempotency database: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping idempotency database: %w", err)
+	}
+
+	s := &SQLiteStore{db: db}
+	if err := s.initSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// initSchema creates the idempotency_keys table
+func (s *SQLiteStore) initSchema() error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		key TEXT PRIMARY KEY,
+		instance_id TEXT NOT NULL DEFAULT '',
+		activity TEXT NOT NULL,
+		output BLOB NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	-- Pruning by age
+	CREATE INDEX IF NOT EXISTS idx_idempotency_created_at ON idempotency_keys(created_at);
+	`
+
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Databases created before instance_id existed need it added; their rows
+	// keep an empty instance ID and only age out
+	if err := s.ensureInstanceColumn(); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("CREATE INDEX IF NOT EXISTS idx_idempotency_instance ON idempotency_keys(instance_id)")
+	return err
+}
+
+// ensureInstanceColumn adds the instance_id column if it is missing
+func (s *SQLiteStore) ensureInstanceColumn() error {
+	rows, err := s.db.Query("PRAGMA table_info(idempotency_keys)")
+	if err != nil {
+		return fmt.Errorf("failed to inspect idempotency_keys: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return fmt.Errorf("failed to scan table info: %w", err)
+		}
+		if name == "instance_id" {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("ALTER TABLE idempotency_keys ADD COLUMN instance_id TEXT NOT NULL DEFAULT ''")
+	return err
+}
+
+// Get returns the recorded output for key
+func (s *SQLiteStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var output []byte
+	err := s.db.QueryRowContext(ctx, "SELECT output FROM idempotency_keys WHERE key = ?", key).Scan(&output)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+	return output, true, nil
+}
+
+// Put records the output for key. The first recorded output wins.
+func (s *SQLiteStore) Put(ctx context.Context, instanceID, key, activity string, output []byte) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT OR IGNORE INTO idempotency_keys (key, instance_id, activity, output, created_at) VALUES (?, ?, ?, ?, ?)",
+		key, instanceID, activity, output, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteInstance deletes the keys recorded for instanceID
+func (s *SQLiteStore) DeleteInstance(ctx context.Context, instanceID string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE instance_id = ?", instanceID); err != nil {
+		return fmt.Errorf("failed to delete idempotency keys: %w", err)
+	}
+	return nil
+}
+
+// PruneOlderThan deletes keys recorded before the retention window
+func (s *SQLiteStore) PruneOlderThan(ctx context.Context, olderThan time.Duration) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE created_at < ?", time.Now().UTC().Add(-olderThan),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
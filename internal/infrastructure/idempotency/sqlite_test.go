@@ -0,0 +1,56 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteStore_GetPut(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewSQLiteStore(t.TempDir() + "/idempotency.db")
+	require.NoError(t, err)
+	defer store.Close()
+
+	_, found, err := store.Get(ctx, "ORD-1/payment:charge#1")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, store.Put(ctx, "ORD-1", "ORD-1/payment:charge#1", "payment:charge", []byte(`{"TransactionID":"TXN_1"}`)))
+	// The first recorded output wins
+	require.NoError(t, store.Put(ctx, "ORD-1", "ORD-1/payment:charge#1", "payment:charge", []byte(`{"TransactionID":"TXN_2"}`)))
+
+	output, found, err := store.Get(ctx, "ORD-1/payment:charge#1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.JSONEq(t, `{"TransactionID":"TXN_1"}`, string(output))
+
+	deleted, err := store.PruneOlderThan(ctx, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+}
+
+func TestSQLiteStore_DeleteInstance(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewSQLiteStore(t.TempDir() + "/idempotency.db")
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.Put(ctx, "ORD-1", "ORD-1/payment:charge#1", "payment:charge", []byte(`{"TransactionID":"TXN_1"}`)))
+	require.NoError(t, store.Put(ctx, "ORD-1/x", "ORD-1/x/payment:charge#1", "payment:charge", []byte(`{"TransactionID":"TXN_2"}`)))
+
+	// Purging ORD-1 forgets only its own steps, so a reused instance ID
+	// executes them again
+	require.NoError(t, store.DeleteInstance(ctx, "ORD-1"))
+
+	_, found, err := store.Get(ctx, "ORD-1/payment:charge#1")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	_, found, err = store.Get(ctx, "ORD-1/x/payment:charge#1")
+	require.NoError(t, err)
+	assert.True(t, found)
+}
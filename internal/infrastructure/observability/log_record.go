@@ -25,8 +25,10 @@ type LogRecord struct {
 	Level           LogLevel        `json:"level"`
 	TraceID         string          `json:"trace_id"`
 	SpanID          string          `json:"span_id,omitempty"`
+	ParentSpanID    string          `json:"parent_span_id,omitempty"`
 	OrchestrationID string          `json:"orchestration_id,omitempty"`
 	Activity        string          `json:"activity,omitempty"`
+	Attempt         int             `json:"attempt,omitempty"`
 	Message         string          `json:"message"`
 	DurationMs      int64           `json:"duration_ms,omitempty"`
 	InputHash       string          `json:"input_hash,omitempty"`
@@ -52,12 +54,25 @@ func (lr *LogRecord) WithOrchestrationID(id string) *LogRecord {
 	return lr
 }
 
+// WithSpan adds the span and its parent within the trace
+func (lr *LogRecord) WithSpan(spanID, parentSpanID string) *LogRecord {
+	lr.SpanID = spanID
+	lr.ParentSpanID = parentSpanID
+	return lr
+}
+
 // WithActivity adds activity context
 func (lr *LogRecord) WithActivity(name string) *LogRecord {
 	lr.Activity = name
 	return lr
 }
 
+// WithAttempt adds the retry attempt number
+func (lr *LogRecord) WithAttempt(attempt int) *LogRecord {
+	lr.Attempt = attempt
+	return lr
+}
+
 // WithDuration adds execution duration
 func (lr *LogRecord) WithDuration(d time.Duration) *LogRecord {
 	lr.DurationMs = d.Milliseconds()
@@ -1,6 +1,7 @@
 package observability
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"sync"
@@ -64,8 +65,10 @@ func (r *LogRepository) initSchema() error {
 		level TEXT NOT NULL,
 		trace_id TEXT NOT NULL,
 		span_id TEXT,
+		parent_span_id TEXT,
 		orchestration_id TEXT,
 		activity TEXT,
+		attempt INTEGER,
 		message TEXT NOT NULL,
 		duration_ms INTEGER,
 		input_hash TEXT,
@@ -96,7 +99,41 @@ func (r *LogRepository) initSchema() error {
 		ON logs(activity, timestamp DESC);
 	`
 
-	_, err := r.db.Exec(schema)
+	if _, err := r.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Databases created before these columns existed need them added
+	if err := r.ensureColumn("logs", "attempt", "INTEGER"); err != nil {
+		return err
+	}
+	return r.ensureColumn("logs", "parent_span_id", "TEXT")
+}
+
+// ensureColumn adds a column to an existing table if it is missing
+func (r *LogRepository) ensureColumn(table, column, definition string) error {
+	rows, err := r.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return fmt.Errorf("failed to scan table info: %w", err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	return err
 }
 
@@ -136,10 +173,10 @@ func (r *LogRepository) FlushBatch() error {
 
 	stmt, err := tx.Prepare(`
 		INSERT INTO logs (
-			timestamp, level, trace_id, span_id, orchestration_id,
-			activity, message, duration_ms, input_hash, output_hash,
+			timestamp, level, trace_id, span_id, parent_span_id, orchestration_id,
+			activity, attempt, message, duration_ms, input_hash, output_hash,
 			error_message, error_hash, raw_json
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
@@ -155,8 +192,10 @@ func (r *LogRepository) FlushBatch() error {
 			log.Level,
 			log.TraceID,
 			log.SpanID,
+			log.ParentSpanID,
 			log.OrchestrationID,
 			log.Activity,
+			log.Attempt,
 			log.Message,
 			log.DurationMs,
 			log.InputHash,
@@ -206,11 +245,20 @@ func (r *LogRepository) Close() error {
 	return r.db.Close()
 }
 
+// Ping verifies the database is reachable and the logs table readable
+func (r *LogRepository) Ping(ctx context.Context) error {
+	var n int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM (SELECT 1 FROM logs LIMIT 1)").Scan(&n); err != nil {
+		return fmt.Errorf("logs table unreachable: %w", err)
+	}
+	return nil
+}
+
 // QueryByTraceID retrieves all logs for a given trace ID
 func (r *LogRepository) QueryByTraceID(traceID string) ([]*LogRecord, error) {
 	rows, err := r.db.Query(`
-		SELECT id, timestamp, level, trace_id, span_id, orchestration_id,
-		       activity, message, duration_ms, input_hash, output_hash,
+		SELECT id, timestamp, level, trace_id, span_id, parent_span_id, orchestration_id,
+		       activity, attempt, message, duration_ms, input_hash, output_hash,
 		       error_message, error_hash
 		FROM logs
 		WHERE trace_id = ?
@@ -227,8 +275,8 @@ func (r *LogRepository) QueryByTraceID(traceID string) ([]*LogRecord, error) {
 // QueryByOrchestrationID retrieves all logs for a given orchestration
 func (r *LogRepository) QueryByOrchestrationID(orchID string) ([]*LogRecord, error) {
 	rows, err := r.db.Query(`
-		SELECT id, timestamp, level, trace_id, span_id, orchestration_id,
-		       activity, message, duration_ms, input_hash, output_hash,
+		SELECT id, timestamp, level, trace_id, span_id, parent_span_id, orchestration_id,
+		       activity, attempt, message, duration_ms, input_hash, output_hash,
 		       error_message, error_hash
 		FROM logs
 		WHERE orchestration_id = ?
@@ -245,8 +293,8 @@ func (r *LogRepository) QueryByOrchestrationID(orchID string) ([]*LogRecord, err
 // QueryErrorsByHash retrieves all logs with a specific error hash
 func (r *LogRepository) QueryErrorsByHash(errorHash string) ([]*LogRecord, error) {
 	rows, err := r.db.Query(`
-		SELECT id, timestamp, level, trace_id, span_id, orchestration_id,
-		       activity, message, duration_ms, input_hash, output_hash,
+		SELECT id, timestamp, level, trace_id, span_id, parent_span_id, orchestration_id,
+		       activity, attempt, message, duration_ms, input_hash, output_hash,
 		       error_message, error_hash
 		FROM logs
 		WHERE error_hash = ?
@@ -359,12 +407,12 @@ func (r *LogRepository) scanRows(rows *sql.Rows) ([]*LogRecord, error) {
 		var id int64
 		var timestamp time.Time
 		var level, traceID, spanID, orchID, activity, message string
-		var durationMs sql.NullInt64
-		var inputHash, outputHash, errorMsg, errorHash sql.NullString
+		var attempt, durationMs sql.NullInt64
+		var parentSpanID, inputHash, outputHash, errorMsg, errorHash sql.NullString
 
 		err := rows.Scan(
-			&id, &timestamp, &level, &traceID, &spanID, &orchID,
-			&activity, &message, &durationMs, &inputHash, &outputHash,
+			&id, &timestamp, &level, &traceID, &spanID, &parentSpanID, &orchID,
+			&activity, &attempt, &message, &durationMs, &inputHash, &outputHash,
 			&errorMsg, &errorHash,
 		)
 		if err != nil {
@@ -382,6 +430,12 @@ func (r *LogRepository) scanRows(rows *sql.Rows) ([]*LogRecord, error) {
 			Message:         message,
 		}
 
+		if parentSpanID.Valid {
+			record.ParentSpanID = parentSpanID.String
+		}
+		if attempt.Valid {
+			record.Attempt = int(attempt.Int64)
+		}
 		if durationMs.Valid {
 			record.DurationMs = durationMs.Int64
 		}
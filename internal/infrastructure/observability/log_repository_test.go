@@ -1,7 +1,6 @@
 package observability
 
 import (
-	"os"
 	"testing"
 	"time"
 
@@ -48,6 +47,28 @@ func TestLogRepository_WriteAndQuery(t *testing.T) {
 	assert.Equal(t, "test error", logs[1].Message)
 }
 
+func TestLogRepository_Attempt(t *testing.T) {
+	tmpFile := t.TempDir() + "/test.db"
+	repo, err := NewLogRepository(tmpFile, 10)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		record := NewLogRecord(LogLevelInfo, "trace-attempt", "activity started").
+			WithOrchestrationID("orch-1").
+			WithActivity("payment:charge").
+			WithAttempt(attempt)
+		require.NoError(t, repo.WriteLog(record))
+	}
+	require.NoError(t, repo.FlushBatch())
+
+	logs, err := repo.QueryByOrchestrationID("orch-1")
+	require.NoError(t, err)
+	require.Equal(t, 3, len(logs))
+	assert.Equal(t, 1, logs[0].Attempt)
+	assert.Equal(t, 3, logs[2].Attempt)
+}
+
 func TestLogRepository_ErrorGrouping(t *testing.T) {
 	tmpFile := t.TempDir() + "/test.db"
 	repo, err := NewLogRepository(tmpFile, 10)
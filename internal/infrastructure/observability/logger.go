@@ -18,7 +18,8 @@ const TraceIDKey = "trace_id"
 
 type Logger struct {
 	*zerolog.Logger
-	repo *LogRepository
+	repo      *LogRepository
+	eventRepo *TaskEventRepository
 }
 
 // NewLogger creates a new structured logger based on configuration
@@ -48,28 +49,44 @@ func (l *Logger) SetLogRepository(repo *LogRepository) {
 	l.repo = repo
 }
 
+// SetTaskEventRepository attaches a task event repository for persistence
+func (l *Logger) SetTaskEventRepository(repo *TaskEventRepository) {
+	l.eventRepo = repo
+}
+
+// with returns a derived logger that keeps the attached repositories
+func (l *Logger) with(logger zerolog.Logger) *Logger {
+	return &Logger{Logger: &logger, repo: l.repo, eventRepo: l.eventRepo}
+}
+
 // WithTraceID returns a new logger with trace ID attached
 func (l *Logger) WithTraceID(ctx context.Context, traceID string) *Logger {
-	logger := l.With().Str(TraceIDKey, traceID).Logger()
-	return &Logger{Logger: &logger}
+	return l.with(l.With().Str(TraceIDKey, traceID).Logger())
 }
 
 // WithOrchestrationID returns a new logger with orchestration ID
 func (l *Logger) WithOrchestrationID(orchestrationID string) *Logger {
-	logger := l.With().Str("orchestration_id", orchestrationID).Logger()
-	return &Logger{Logger: &logger}
+	return l.with(l.With().Str("orchestration_id", orchestrationID).Logger())
 }
 
 // WithActivityName returns a new logger with activity name
 func (l *Logger) WithActivityName(activityName string) *Logger {
-	logger := l.With().Str("activity", activityName).Logger()
-	return &Logger{Logger: &logger}
+	return l.with(l.With().Str("activity", activityName).Logger())
+}
+
+// WithAttempt returns a new logger with the retry attempt number
+func (l *Logger) WithAttempt(attempt int) *Logger {
+	return l.with(l.With().Int("attempt", attempt).Logger())
+}
+
+// WithSpan returns a new logger with the span ID attached
+func (l *Logger) WithSpan(spanID string) *Logger {
+	return l.with(l.With().Str("span_id", spanID).Logger())
 }
 
 // WithError returns a new logger with error attached
 func (l *Logger) WithError(err error) *Logger {
-	logger := l.With().Err(err).Logger()
-	return &Logger{Logger: &logger}
+	return l.with(l.With().Err(err).Logger())
 }
 
 // Info logs an info level message
@@ -119,6 +136,14 @@ func (l *Logger) WriteLogRecord(record *LogRecord) error {
 	return l.repo.WriteLog(record)
 }
 
+// WriteTaskEvent writes a task event to the repository if configured
+func (l *Logger) WriteTaskEvent(event *TaskEvent) error {
+	if l.eventRepo == nil {
+		return nil // Repository not configured, skip
+	}
+	return l.eventRepo.WriteEvent(event)
+}
+
 // GenerateCryptographicTraceID creates a cryptographically random trace ID
 func GenerateCryptographicTraceID() (string, error) {
 	// 16 bytes = 128 bits (W3C Trace Context standard)
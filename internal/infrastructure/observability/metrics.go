@@ -7,91 +7,131 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+// Activity outcomes used as the "outcome" label of activity metrics
+const (
+	OutcomeSuccess   = "success"
+	OutcomeTransient = "transient"
+	OutcomePermanent = "permanent"
+	OutcomeTimeout   = "timeout"
+)
+
 type Metrics struct {
-	OrchestrationStarted    prometheus.Counter
-	OrchestrationCompleted  prometheus.Counter
-	OrchestrationFailed     prometheus.Counter
-	OrchestrationDuration   prometheus.Histogram
-	ActivityExecutions      prometheus.Counter
-	ActivityDuration        prometheus.Histogram
-	ActivityErrors          prometheus.Counter
-	CompensationExecutions  prometheus.Counter
-	CompensationDuration    prometheus.Histogram
-	CompensationErrors      prometheus.Counter
+	OrchestrationStarted   *prometheus.CounterVec   // orchestrator
+	OrchestrationCompleted *prometheus.CounterVec   // orchestrator, status
+	OrchestrationFailed    *prometheus.CounterVec   // orchestrator, status
+	OrchestrationDuration  *prometheus.HistogramVec // orchestrator, status
+	ActivityExecutions     *prometheus.CounterVec   // activity, outcome, code
+	ActivityDuration       *prometheus.HistogramVec // activity, outcome
+	ActivityErrors         *prometheus.CounterVec   // activity, code
+	ActivityRetries        *prometheus.CounterVec   // activity
+	ActivityInFlight       *prometheus.GaugeVec     // activity
+	CompensationExecutions prometheus.Counter
+	CompensationDuration   prometheus.Histogram
+	CompensationErrors     prometheus.Counter
+	CircuitBreakerState    *prometheus.GaugeVec
 }
 
-// NewMetrics creates a new metrics collector
-func NewMetrics() *Metrics {
+// NewMetrics creates a new metrics collector registered with reg. Each
+// registry can hold one collector, so tests should pass their own
+// prometheus.NewRegistry().
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	factory := promauto.With(reg)
+
 	return &Metrics{
-		OrchestrationStarted: promauto.NewCounter(prometheus.CounterOpts{
+		OrchestrationStarted: factory.NewCounterVec(prometheus.CounterOpts{
 			Name: "orchestration_started_total",
 			Help: "Total number of orchestrations started",
-		}),
-		OrchestrationCompleted: promauto.NewCounter(prometheus.CounterOpts{
+		}, []string{"orchestrator"}),
+		OrchestrationCompleted: factory.NewCounterVec(prometheus.CounterOpts{
 			Name: "orchestration_completed_total",
-			Help: "Total number of orchestrations completed successfully",
-		}),
-		OrchestrationFailed: promauto.NewCounter(prometheus.CounterOpts{
+			Help: "Total number of orchestrations that ran to completion, by final status",
+		}, []string{"orchestrator", "status"}),
+		OrchestrationFailed: factory.NewCounterVec(prometheus.CounterOpts{
 			Name: "orchestration_failed_total",
-			Help: "Total number of orchestrations failed",
-		}),
-		OrchestrationDuration: promauto.NewHistogram(prometheus.HistogramOpts{
-			Name: "orchestration_duration_seconds",
-			Help: "Orchestration execution duration in seconds",
+			Help: "Total number of orchestrations that failed or were terminated",
+		}, []string{"orchestrator", "status"}),
+		OrchestrationDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "orchestration_duration_seconds",
+			Help:    "Orchestration execution duration in seconds",
 			Buckets: []float64{.1, .5, 1, 2, 5, 10, 30, 60},
-		}),
-		ActivityExecutions: promauto.NewCounter(prometheus.CounterOpts{
+		}, []string{"orchestrator", "status"}),
+		ActivityExecutions: factory.NewCounterVec(prometheus.CounterOpts{
 			Name: "activity_executions_total",
-			Help: "Total number of activity executions",
-		}),
-		ActivityDuration: promauto.NewHistogram(prometheus.HistogramOpts{
-			Name: "activity_duration_seconds",
-			Help: "Activity execution duration in seconds",
+			Help: "Total number of activity executions by outcome and error code",
+		}, []string{"activity", "outcome", "code"}),
+		ActivityDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "activity_duration_seconds",
+			Help:    "Activity execution duration in seconds",
 			Buckets: []float64{.01, .05, .1, .5, 1, 5, 10},
-		}),
-		ActivityErrors: promauto.NewCounter(prometheus.CounterOpts{
+		}, []string{"activity", "outcome"}),
+		ActivityErrors: factory.NewCounterVec(prometheus.CounterOpts{
 			Name: "activity_errors_total",
-			Help: "Total number of activity errors",
-		}),
-		CompensationExecutions: promauto.NewCounter(prometheus.CounterOpts{
+			Help: "Total number of activity errors by error code",
+		}, []string{"activity", "code"}),
+		ActivityRetries: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "activity_retries_total",
+			Help: "Total number of activity executions that were retry attempts",
+		}, []string{"activity"}),
+		ActivityInFlight: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "activity_in_flight",
+			Help: "Number of activity executions currently running",
+		}, []string{"activity"}),
+		CompensationExecutions: factory.NewCounter(prometheus.CounterOpts{
 			Name: "compensation_executions_total",
 			Help: "Total number of compensation executions",
 		}),
-		CompensationDuration: promauto.NewHistogram(prometheus.HistogramOpts{
-			Name: "compensation_duration_seconds",
-			Help: "Compensation execution duration in seconds",
+		CompensationDuration: factory.NewHistogram(prometheus.HistogramOpts{
+			Name:    "compensation_duration_seconds",
+			Help:    "Compensation execution duration in seconds",
 			Buckets: []float64{.01, .05, .1, .5, 1, 5},
 		}),
-		CompensationErrors: promauto.NewCounter(prometheus.CounterOpts{
+		CompensationErrors: factory.NewCounter(prometheus.CounterOpts{
 			Name: "compensation_errors_total",
 			Help: "Total number of compensation errors",
 		}),
+		CircuitBreakerState: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "circuit_breaker_state",
+			Help: "Circuit breaker state per dependency (0=closed, 1=half-open, 2=open)",
+		}, []string{"dependency"}),
 	}
 }
 
 // RecordOrchestrationStart records orchestration start
-func (m *Metrics) RecordOrchestrationStart() {
-	m.OrchestrationStarted.Inc()
+func (m *Metrics) RecordOrchestrationStart(orchestrator string) {
+	m.OrchestrationStarted.WithLabelValues(orchestrator).Inc()
 }
 
-// RecordOrchestrationCompleted records successful orchestration completion
-func (m *Metrics) RecordOrchestrationCompleted(duration time.Duration) {
-	m.OrchestrationCompleted.Inc()
-	m.OrchestrationDuration.Observe(duration.Seconds())
+// RecordOrchestrationCompleted records an orchestration that ran to
+// completion. status is the final status reported in its output.
+func (m *Metrics) RecordOrchestrationCompleted(orchestrator, status string, duration time.Duration) {
+	m.OrchestrationCompleted.WithLabelValues(orchestrator, status).Inc()
+	m.OrchestrationDuration.WithLabelValues(orchestrator, status).Observe(duration.Seconds())
 }
 
-// RecordOrchestrationFailed records orchestration failure
-func (m *Metrics) RecordOrchestrationFailed(duration time.Duration) {
-	m.OrchestrationFailed.Inc()
-	m.OrchestrationDuration.Observe(duration.Seconds())
+// RecordOrchestrationFailed records an orchestration that failed or was
+// terminated. status is its runtime status.
+func (m *Metrics) RecordOrchestrationFailed(orchestrator, status string, duration time.Duration) {
+	m.OrchestrationFailed.WithLabelValues(orchestrator, status).Inc()
+	m.OrchestrationDuration.WithLabelValues(orchestrator, status).Observe(duration.Seconds())
 }
 
-// RecordActivityExecution records activity execution
-func (m *Metrics) RecordActivityExecution(duration time.Duration, err error) {
-	m.ActivityExecutions.Inc()
-	m.ActivityDuration.Observe(duration.Seconds())
-	if err != nil {
-		m.ActivityErrors.Inc()
+// RecordActivityStart marks an activity execution as in flight and counts
+// retry attempts
+func (m *Metrics) RecordActivityStart(activity string, attempt int) {
+	m.ActivityInFlight.WithLabelValues(activity).Inc()
+	if attempt > 1 {
+		m.ActivityRetries.WithLabelValues(activity).Inc()
+	}
+}
+
+// RecordActivityExecution records a finished activity execution. code is the
+// CustomError code of a failure and empty on success.
+func (m *Metrics) RecordActivityExecution(activity, outcome, code string, duration time.Duration) {
+	m.ActivityInFlight.WithLabelValues(activity).Dec()
+	m.ActivityExecutions.WithLabelValues(activity, outcome, code).Inc()
+	m.ActivityDuration.WithLabelValues(activity, outcome).Observe(duration.Seconds())
+	if outcome != OutcomeSuccess {
+		m.ActivityErrors.WithLabelValues(activity, code).Inc()
 	}
 }
 
@@ -103,3 +143,8 @@ func (m *Metrics) RecordCompensation(duration time.Duration, err error) {
 		m.CompensationErrors.Inc()
 	}
 }
+
+// RecordCircuitBreakerState records the current state of a dependency's breaker
+func (m *Metrics) RecordCircuitBreakerState(dependency string, state int) {
+	m.CircuitBreakerState.WithLabelValues(dependency).Set(float64(state))
+}
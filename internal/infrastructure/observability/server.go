@@ -0,0 +1,121 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// readinessTimeout bounds each readiness check so a hung dependency fails
+// the probe instead of blocking it
+const readinessTimeout = 2 * time.Second
+
+// ReadinessCheck reports whether a dependency the process needs is reachable
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// ReadinessResponse is the body returned by /readyz
+type ReadinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Server exposes Prometheus metrics, liveness and readiness probes and,
+// optionally, pprof profiles on a port separate from the API
+type Server struct {
+	checks []ReadinessCheck
+	mux    *http.ServeMux
+	http   *http.Server
+}
+
+// NewServer creates an observability server listening on the given port that
+// serves the metrics collected by gatherer
+func NewServer(gatherer prometheus.Gatherer, port int, enablePprof bool, checks ...ReadinessCheck) *Server {
+	s := &Server{
+		checks: checks,
+		mux:    http.NewServeMux(),
+	}
+
+	s.mux.Handle("GET /metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+	s.mux.HandleFunc("GET /healthz", s.handleHealthz)
+	s.mux.HandleFunc("GET /readyz", s.handleReadyz)
+
+	if enablePprof {
+		s.mux.HandleFunc("/debug/pprof/", pprof.Index)
+		s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		s.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		s.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	s.http = &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s
+}
+
+// Handler returns the HTTP handler serving all observability routes
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// ListenAndServe serves requests until Shutdown is called
+func (s *Server) ListenAndServe() error {
+	if err := s.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown gracefully stops the server
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+// handleHealthz reports liveness: the process is up and serving requests
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReadyz reports readiness: every dependency check passes
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	resp := ReadinessResponse{
+		Status: "ok",
+		Checks: make(map[string]string, len(s.checks)),
+	}
+	status := http.StatusOK
+
+	for _, check := range s.checks {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		err := check.Check(ctx)
+		cancel()
+
+		if err != nil {
+			resp.Checks[check.Name] = err.Error()
+			resp.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Checks[check.Name] = "ok"
+	}
+
+	writeProbe(w, status, resp)
+}
+
+func writeProbe(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,71 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_MetricsAndProbes(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := NewMetrics(registry)
+	metrics.RecordActivityStart("payment:charge", 1)
+	metrics.RecordActivityExecution("payment:charge", OutcomeSuccess, "", 10*time.Millisecond)
+
+	repo, err := NewLogRepository(t.TempDir()+"/test.db", 10)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	workerRunning := true
+	server := NewServer(registry, 0, false,
+		ReadinessCheck{Name: "log_repository", Check: repo.Ping},
+		ReadinessCheck{Name: "worker", Check: func(ctx context.Context) error {
+			if !workerRunning {
+				return errors.New("worker not running")
+			}
+			return nil
+		}},
+	)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/metrics")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), `activity_executions_total{activity="payment:charge",code="",outcome="success"} 1`))
+
+	assert.Equal(t, http.StatusOK, get("/healthz").Code)
+
+	rec = get("/readyz")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// A stopped worker fails readiness but not liveness
+	workerRunning = false
+	rec = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var resp ReadinessResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "unavailable", resp.Status)
+	assert.Equal(t, "ok", resp.Checks["log_repository"])
+	assert.Equal(t, "worker not running", resp.Checks["worker"])
+	assert.Equal(t, http.StatusOK, get("/healthz").Code)
+
+	// pprof is only served when enabled
+	assert.Equal(t, http.StatusNotFound, get("/debug/pprof/").Code)
+	withPprof := NewServer(registry, 0, true)
+	rec = httptest.NewRecorder()
+	withPprof.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -12,7 +12,7 @@ type TaskEvent struct {
 	TraceID         string            `json:"trace_id"`
 	SpanID          string            `json:"span_id,omitempty"`
 	OrchestrationID string            `json:"orchestration_id,omitempty"`
-	EventType       string            `json:"event_type"` // log, metric, trace
+	EventType       string            `json:"event_type"` // log, metric, trace, circuit_breaker
 	Activity        string            `json:"activity,omitempty"`
 	Payload         json.RawMessage   `json:"payload"`
 }
@@ -125,3 +125,25 @@ func NewTraceEvent(traceID, spanID, spanName string, timestamp time.Time, latenc
 		Payload:         payloadBytes,
 	}
 }
+
+// NewCircuitBreakerEvent creates a task event for a circuit breaker state change
+func NewCircuitBreakerEvent(dependency, from, to string, timestamp time.Time) *TaskEvent {
+	payload := EventPayload{
+		Message:  "circuit breaker state changed",
+		Severity: "warn",
+		Status:   to,
+		Attributes: map[string]interface{}{
+			"dependency": dependency,
+			"from":       from,
+			"to":         to,
+		},
+	}
+
+	payloadBytes, _ := json.Marshal(payload)
+
+	return &TaskEvent{
+		Timestamp: timestamp,
+		EventType: "circuit_breaker",
+		Payload:   payloadBytes,
+	}
+}
@@ -1,6 +1,7 @@
 package observability
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"sync"
@@ -189,6 +190,15 @@ func (r *TaskEventRepository) Close() error {
 	return r.db.Close()
 }
 
+// Ping verifies the database is reachable and the task_events table readable
+func (r *TaskEventRepository) Ping(ctx context.Context) error {
+	var n int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM (SELECT 1 FROM task_events LIMIT 1)").Scan(&n); err != nil {
+		return fmt.Errorf("task_events table unreachable: %w", err)
+	}
+	return nil
+}
+
 // QueryByTraceID retrieves all events for a given trace ID
 func (r *TaskEventRepository) QueryByTraceID(traceID string) ([]*TaskEvent, error) {
 	rows, err := r.db.Query(`
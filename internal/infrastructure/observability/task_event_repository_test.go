@@ -2,7 +2,6 @@ package observability
 
 import (
 	"encoding/json"
-	"os"
 	"testing"
 	"time"
 
@@ -320,3 +319,15 @@ func TestNewTraceEvent(t *testing.T) {
 	assert.Equal(t, int64(1450), payload.LatencyMs)
 	assert.Equal(t, "OK", payload.SpanStatus)
 }
+
+func TestNewCircuitBreakerEvent(t *testing.T) {
+	event := NewCircuitBreakerEvent("payment", "closed", "open", time.Now())
+
+	assert.Equal(t, "circuit_breaker", event.EventType)
+
+	var payload EventPayload
+	json.Unmarshal(event.Payload, &payload)
+	assert.Equal(t, "open", payload.Status)
+	assert.Equal(t, "payment", payload.Attributes["dependency"])
+	assert.Equal(t, "closed", payload.Attributes["from"])
+}
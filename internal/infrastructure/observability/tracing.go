@@ -2,21 +2,38 @@ package observability
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/zipkin"
 	"go.opentelemetry.io/otel/sdk/resource"
-	"go.opentelemetry.io/otel/sdk/trace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/config"
 )
 
+// TracerName is the instrumentation name used for spans created by the orchestrator
+const TracerName = "github.com/Youmanvi/taskorchestrator"
+
+// Span attribute keys shared by activity and orchestration spans
+const (
+	AttrOrchestrationID     = "orchestration.id"
+	AttrOrchestrationName   = "orchestration.name"
+	AttrOrchestrationStatus = "orchestration.status"
+	AttrActivityName        = "activity.name"
+	AttrActivityAttempt     = "activity.attempt"
+	AttrErrorCode           = "error.code"
+	AttrErrorType           = "error.type"
+)
+
 // InitializeTracing sets up OpenTelemetry tracing with Zipkin exporter
-func InitializeTracing(ctx context.Context, cfg *config.ObservabilityConfig, appName string) (*trace.TracerProvider, error) {
+func InitializeTracing(ctx context.Context, cfg *config.ObservabilityConfig, appName string) (*sdktrace.TracerProvider, error) {
 	if !cfg.TracingEnabled {
 		// Return a no-op tracer provider if tracing is disabled
-		return trace.NewTracerProvider(), nil
+		return sdktrace.NewTracerProvider(), nil
 	}
 
 	exporter, err := zipkin.New(
@@ -35,10 +52,11 @@ func InitializeTracing(ctx context.Context, cfg *config.ObservabilityConfig, app
 		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
 
-	tp := trace.NewTracerProvider(
-		trace.WithBatcher(exporter),
-		trace.WithResource(res),
-		trace.WithSampler(trace.AlwaysSample()),
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithIDGenerator(NewIDGenerator()),
 	)
 
 	otel.SetTracerProvider(tp)
@@ -47,11 +65,70 @@ func InitializeTracing(ctx context.Context, cfg *config.ObservabilityConfig, app
 }
 
 // ShutdownTracing shuts down the tracer provider
-func ShutdownTracing(ctx context.Context, tp *trace.TracerProvider) error {
+func ShutdownTracing(ctx context.Context, tp *sdktrace.TracerProvider) error {
 	return tp.Shutdown(ctx)
 }
 
-// GetTracer returns a tracer for the given name
-func GetTracer(name string) interface{} {
+// GetTracer returns a tracer for the given name from the global provider
+func GetTracer(name string) trace.Tracer {
 	return otel.Tracer(name)
 }
+
+// OrchestrationSpanContext returns the span context of an orchestration
+// instance's span. The IDs are derived from the instance ID, so every replay
+// of the orchestration and every activity it schedules agree on them before
+// the span itself is recorded.
+func OrchestrationSpanContext(instanceID string) trace.SpanContext {
+	sum := sha256.Sum256([]byte(instanceID))
+
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	copy(traceID[:], sum[:16])
+	copy(spanID[:], sum[16:24])
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+}
+
+type presetIDsKey struct{}
+
+// ContextWithSpanIDs asks an IDGenerator to assign the IDs of sc to the next
+// root span started with ctx
+func ContextWithSpanIDs(ctx context.Context, sc trace.SpanContext) context.Context {
+	return context.WithValue(ctx, presetIDsKey{}, sc)
+}
+
+// IDGenerator generates random trace and span IDs, except for root spans
+// whose context carries preset IDs from ContextWithSpanIDs
+type IDGenerator struct{}
+
+// NewIDGenerator creates an IDGenerator. Tracer providers must use it for
+// orchestration spans to keep the IDs their activities were given.
+func NewIDGenerator() *IDGenerator {
+	return &IDGenerator{}
+}
+
+// NewIDs returns the preset IDs from ctx, or random IDs
+func (g *IDGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	if sc, ok := ctx.Value(presetIDsKey{}).(trace.SpanContext); ok && sc.IsValid() {
+		return sc.TraceID(), sc.SpanID()
+	}
+
+	var traceID trace.TraceID
+	for !traceID.IsValid() {
+		_, _ = rand.Read(traceID[:])
+	}
+	return traceID, g.NewSpanID(ctx, traceID)
+}
+
+// NewSpanID returns a random span ID for a child span
+func (g *IDGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	var spanID trace.SpanID
+	for !spanID.IsValid() {
+		_, _ = rand.Read(spanID[:])
+	}
+	return spanID
+}
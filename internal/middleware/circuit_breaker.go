@@ -2,16 +2,52 @@ package middleware
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/sony/gobreaker"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
 	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
 )
 
-// WithCircuitBreaker returns a middleware that protects activity execution with a circuit breaker
-func WithCircuitBreaker(name string, threshold float64, timeout time.Duration) ActivityMiddleware {
-	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+// ErrBreakerNotFound is returned when resetting an unknown breaker
+var ErrBreakerNotFound = stderrors.New("circuit breaker not found")
+
+// BreakerStateChangeFunc is called whenever a breaker changes state
+type BreakerStateChangeFunc func(name string, from, to gobreaker.State)
+
+// BreakerState is a point-in-time snapshot of a circuit breaker
+type BreakerState struct {
+	Name                string   `json:"name"`
+	State               string   `json:"state"`
+	Activities          []string `json:"activities"`
+	Requests            uint32   `json:"requests"`
+	TotalFailures       uint32   `json:"total_failures"`
+	ConsecutiveFailures uint32   `json:"consecutive_failures"`
+}
+
+// Breaker is a resettable circuit breaker that may be shared by every
+// activity calling the same downstream dependency
+type Breaker struct {
+	name          string
+	settings      gobreaker.Settings
+	onStateChange BreakerStateChangeFunc
+
+	mu         sync.RWMutex
+	cb         *gobreaker.CircuitBreaker
+	activities []string
+}
+
+// newBreaker creates a closed breaker that trips once at least 3 requests
+// have been seen and the failure ratio reaches threshold. Permanent errors are
+// business outcomes such as a declined payment, not dependency failures, so
+// they count as successes.
+func newBreaker(name string, threshold float64, timeout time.Duration, onStateChange BreakerStateChangeFunc) *Breaker {
+	b := &Breaker{name: name, onStateChange: onStateChange}
+	b.settings = gobreaker.Settings{
 		Name:        name,
 		MaxRequests: 1,
 		Interval:    timeout,
@@ -20,23 +56,175 @@ func WithCircuitBreaker(name string, threshold float64, timeout time.Duration) A
 			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
 			return counts.Requests >= 3 && failureRatio >= threshold
 		},
+		IsSuccessful: func(err error) bool {
+			return err == nil || errors.ClassifyError(err) == errors.ErrorTypePermanent
+		},
 		OnStateChange: func(name string, from, to gobreaker.State) {
-			// Log state changes if needed
+			if b.onStateChange != nil {
+				b.onStateChange(name, from, to)
+			}
 		},
-	})
+	}
+	b.cb = gobreaker.NewCircuitBreaker(b.settings)
+	return b
+}
+
+// Name returns the breaker name
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// Execute runs fn through the breaker
+func (b *Breaker) Execute(fn func() (interface{}, error)) (interface{}, error) {
+	b.mu.RLock()
+	cb := b.cb
+	b.mu.RUnlock()
+	return cb.Execute(fn)
+}
+
+// Reset forces the breaker closed and clears its counts
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	from := b.cb.State()
+	b.cb = gobreaker.NewCircuitBreaker(b.settings)
+	b.mu.Unlock()
+
+	if from != gobreaker.StateClosed && b.onStateChange != nil {
+		b.onStateChange(b.name, from, gobreaker.StateClosed)
+	}
+}
+
+// State returns a snapshot of the breaker state and counts
+func (b *Breaker) State() BreakerState {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	counts := b.cb.Counts()
+	return BreakerState{
+		Name:                b.name,
+		State:               b.cb.State().String(),
+		Activities:          append([]string(nil), b.activities...),
+		Requests:            counts.Requests,
+		TotalFailures:       counts.TotalFailures,
+		ConsecutiveFailures: counts.ConsecutiveFailures,
+	}
+}
+
+// addActivity records an activity that uses this breaker
+func (b *Breaker) addActivity(activity string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.activities = append(b.activities, activity)
+}
+
+// BreakerRegistry holds one breaker per downstream dependency
+type BreakerRegistry struct {
+	mu            sync.Mutex
+	breakers      map[string]*Breaker
+	onStateChange BreakerStateChangeFunc
+	metrics       *observability.Metrics
+}
+
+// NewBreakerRegistry creates an empty registry. Each breaker's state gauge is
+// set to closed in metrics when it is created, so dependencies whose breaker
+// never trips still have a series. Either argument may be nil.
+func NewBreakerRegistry(onStateChange BreakerStateChangeFunc, metrics *observability.Metrics) *BreakerRegistry {
+	return &BreakerRegistry{
+		breakers:      make(map[string]*Breaker),
+		onStateChange: onStateChange,
+		metrics:       metrics,
+	}
+}
+
+// Register returns the breaker for a dependency, creating it on first use.
+// Threshold and timeout are taken from the first activity to register.
+func (r *BreakerRegistry) Register(dependency, activity string, threshold float64, timeout time.Duration) *Breaker {
+	r.mu.Lock()
+	b, ok := r.breakers[dependency]
+	if !ok {
+		b = newBreaker(dependency, threshold, timeout, r.onStateChange)
+		r.breakers[dependency] = b
+		if r.metrics != nil {
+			r.metrics.RecordCircuitBreakerState(dependency, int(gobreaker.StateClosed))
+		}
+	}
+	r.mu.Unlock()
+
+	b.addActivity(activity)
+	return b
+}
+
+// States returns a snapshot of every breaker, sorted by name
+func (r *BreakerRegistry) States() []BreakerState {
+	r.mu.Lock()
+	breakers := make([]*Breaker, 0, len(r.breakers))
+	for _, b := range r.breakers {
+		breakers = append(breakers, b)
+	}
+	r.mu.Unlock()
+
+	states := make([]BreakerState, 0, len(breakers))
+	for _, b := range breakers {
+		states = append(states, b.State())
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// Reset forces the named breaker closed
+func (r *BreakerRegistry) Reset(name string) error {
+	r.mu.Lock()
+	b, ok := r.breakers[name]
+	r.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrBreakerNotFound, name)
+	}
+	b.Reset()
+	return nil
+}
+
+// ObserveBreakerState returns a state change callback that logs the
+// transition, updates the breaker state gauge and records a TaskEvent.
+// Any argument may be nil.
+func ObserveBreakerState(logger *observability.Logger, metrics *observability.Metrics, events *observability.TaskEventRepository) BreakerStateChangeFunc {
+	return func(name string, from, to gobreaker.State) {
+		if logger != nil {
+			logger.Logger.Warn().
+				Str("dependency", name).
+				Str("from", from.String()).
+				Str("to", to.String()).
+				Msg("circuit breaker state changed")
+		}
+		if metrics != nil {
+			metrics.RecordCircuitBreakerState(name, int(to))
+		}
+		if events != nil {
+			events.WriteEvent(observability.NewCircuitBreakerEvent(name, from.String(), to.String(), time.Now()))
+		}
+	}
+}
+
+// WithCircuitBreaker returns a middleware that protects activity execution with a circuit breaker
+func WithCircuitBreaker(name string, threshold float64, timeout time.Duration) ActivityMiddleware {
+	return WithSharedCircuitBreaker(newBreaker(name, threshold, timeout, nil), name)
+}
 
+// WithSharedCircuitBreaker returns a middleware that runs an activity through
+// a breaker shared with other activities calling the same dependency
+func WithSharedCircuitBreaker(breaker *Breaker, activity string) ActivityMiddleware {
 	return func(next ActivityFunc) ActivityFunc {
 		return func(ctx context.Context, input []byte) ([]byte, error) {
-			result, err := cb.Execute(func() (interface{}, error) {
+			result, err := breaker.Execute(func() (interface{}, error) {
 				return next(ctx, input)
 			})
 
 			if err != nil {
 				// Check if it's a circuit breaker error
-				if err == gobreaker.ErrOpenState {
+				if err == gobreaker.ErrOpenState || err == gobreaker.ErrTooManyRequests {
 					return nil, errors.NewTransientError(
 						"CIRCUIT_BREAKER_OPEN",
-						fmt.Sprintf("circuit breaker open for activity: %s", name),
+						fmt.Sprintf("circuit breaker %s open for activity: %s", breaker.Name(), activity),
 						err,
 					)
 				}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+func failingActivity(ctx context.Context, input []byte) ([]byte, error) {
+	return nil, fmt.Errorf("gateway unavailable")
+}
+
+func succeedingActivity(ctx context.Context, input []byte) ([]byte, error) {
+	return []byte(`{}`), nil
+}
+
+func TestBreaker_IgnoresPermanentErrors(t *testing.T) {
+	registry := NewBreakerRegistry(nil, nil)
+	declined := ApplyMiddleware(func(ctx context.Context, input []byte) ([]byte, error) {
+		return nil, errors.NewPermanentError("PAYMENT_DECLINED", "payment declined", nil)
+	}, WithSharedCircuitBreaker(registry.Register("payment", "payment:authorize", 0.5, time.Minute), "payment:authorize"))
+
+	// Declines are business outcomes and must not open the breaker
+	for i := 0; i < 5; i++ {
+		_, err := declined(context.Background(), nil)
+		require.Error(t, err)
+		assert.Equal(t, "PAYMENT_DECLINED", errors.CodeOf(err))
+	}
+
+	states := registry.States()
+	require.Len(t, states, 1)
+	assert.Equal(t, "closed", states[0].State)
+	assert.Equal(t, uint32(0), states[0].TotalFailures)
+}
+
+func TestBreakerRegistry_SharedAcrossActivities(t *testing.T) {
+	var transitions []string
+	registry := NewBreakerRegistry(func(name string, from, to gobreaker.State) {
+		transitions = append(transitions, fmt.Sprintf("%s:%s->%s", name, from, to))
+	}, nil)
+
+	charge := ApplyMiddleware(failingActivity,
+		WithSharedCircuitBreaker(registry.Register("payment", "payment:charge", 0.5, time.Minute), "payment:charge"))
+	refund := ApplyMiddleware(succeedingActivity,
+		WithSharedCircuitBreaker(registry.Register("payment", "payment:refund", 0.5, time.Minute), "payment:refund"))
+
+	// Three failed charges trip the breaker shared with refunds
+	for i := 0; i < 3; i++ {
+		_, err := charge(context.Background(), nil)
+		require.Error(t, err)
+	}
+
+	_, err := refund(context.Background(), nil)
+	require.Error(t, err)
+	assert.Equal(t, "CIRCUIT_BREAKER_OPEN", errors.CodeOf(err))
+	assert.Equal(t, []string{"payment:closed->open"}, transitions)
+
+	states := registry.States()
+	require.Len(t, states, 1)
+	assert.Equal(t, "open", states[0].State)
+	assert.Equal(t, []string{"payment:charge", "payment:refund"}, states[0].Activities)
+
+	// Forcing a reset closes the breaker and reports the transition
+	require.NoError(t, registry.Reset("payment"))
+	_, err = refund(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "payment:open->closed", transitions[len(transitions)-1])
+
+	assert.ErrorIs(t, registry.Reset("email"), ErrBreakerNotFound)
+}
+
+func TestBreakerRegistry_ReportsClosedOnCreate(t *testing.T) {
+	metrics := observability.NewMetrics(prometheus.NewRegistry())
+	registry := NewBreakerRegistry(nil, metrics)
+
+	// A breaker that never trips still has a series, reading closed
+	registry.Register("payment", "payment:charge", 0.5, time.Minute)
+	registry.Register("payment", "payment:refund", 0.5, time.Minute)
+	registry.Register("email", "notification:order_confirmation", 0.5, time.Minute)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.CircuitBreakerState))
+	assert.Equal(t, float64(gobreaker.StateClosed), testutil.ToFloat64(metrics.CircuitBreakerState.WithLabelValues("payment")))
+	assert.Equal(t, float64(gobreaker.StateClosed), testutil.ToFloat64(metrics.CircuitBreakerState.WithLabelValues("email")))
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+)
+
+// IdempotencyStore records the output of completed activity steps
+type IdempotencyStore interface {
+	// Get returns the recorded output for key, if any
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Put records the output of a completed step of an orchestration instance
+	Put(ctx context.Context, instanceID, key, activity string, output []byte) error
+	// DeleteInstance forgets an instance's recorded outputs once it is purged,
+	// so a new orchestration reusing the instance ID runs its steps afresh
+	DeleteInstance(ctx context.Context, instanceID string) error
+}
+
+// WithIdempotency returns a middleware that returns the recorded output when
+// a step that already completed is executed again, e.g. after a worker
+// crashed before the result was checkpointed. Only successful outputs are
+// recorded, so failed attempts are retried normally.
+func WithIdempotency(store IdempotencyStore, logger *observability.Logger, activityName string) ActivityMiddleware {
+	return func(next ActivityFunc) ActivityFunc {
+		return func(ctx context.Context, input []byte) ([]byte, error) {
+			inv, _ := InvocationFromContext(ctx)
+			key := inv.IdempotencyKey()
+			if key == "" {
+				return next(ctx, input)
+			}
+
+			output, found, err := store.Get(ctx, key)
+			if err != nil {
+				logger.WithActivityName(activityName).Error("idempotency lookup failed", err)
+			} else if found {
+				logger.WithActivityName(activityName).Logger.Info().
+					Str("idempotency_key", key).
+					Msg("returning recorded output for completed step")
+				return output, nil
+			}
+
+			output, err = next(ctx, input)
+			if err != nil {
+				return nil, err
+			}
+
+			// Record with a fresh context so a deadline on the activity does not drop the result
+			putCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			if err := store.Put(putCtx, inv.InstanceID, key, activityName, output); err != nil {
+				logger.WithActivityName(activityName).Error("failed to record idempotent output", err)
+			}
+
+			return output, nil
+		}
+	}
+}
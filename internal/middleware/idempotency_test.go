@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/config"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+)
+
+// mapStore is a minimal IdempotencyStore for tests
+type mapStore struct {
+	mu        sync.Mutex
+	outputs   map[string][]byte
+	instances map[string]string // Instance ID of each key
+}
+
+func (s *mapStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	output, ok := s.outputs[key]
+	return output, ok, nil
+}
+
+func (s *mapStore) Put(ctx context.Context, instanceID, key, activity string, output []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outputs[key] = output
+	s.instances[key] = instanceID
+	return nil
+}
+
+func (s *mapStore) DeleteInstance(ctx context.Context, instanceID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, id := range s.instances {
+		if id == instanceID {
+			delete(s.outputs, key)
+			delete(s.instances, key)
+		}
+	}
+	return nil
+}
+
+func TestWithIdempotency_ReturnsRecordedOutput(t *testing.T) {
+	store := &mapStore{outputs: make(map[string][]byte), instances: make(map[string]string)}
+	logger := observability.NewLogger(&config.ObservabilityConfig{LogLevel: "error"})
+
+	calls := 0
+	activity := ApplyMiddleware(func(ctx context.Context, input []byte) ([]byte, error) {
+		calls++
+		if calls == 1 {
+			return nil, fmt.Errorf("gateway unavailable")
+		}
+		return []byte(fmt.Sprintf(`{"TransactionID":"TXN_%d"}`, calls)), nil
+	}, WithIdempotency(store, logger, "payment:charge"))
+
+	ctx := WithInvocation(context.Background(), Invocation{
+		InstanceID: "ORD-1",
+		StepKey:    "payment:charge#1",
+		Attempt:    1,
+	})
+
+	// Failures are not recorded, so the retry executes the activity
+	_, err := activity(ctx, nil)
+	require.Error(t, err)
+
+	first, err := activity(ctx, nil)
+	require.NoError(t, err)
+
+	// Re-execution of the completed step returns the recorded output
+	second, err := activity(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 2, calls)
+	assert.Contains(t, store.outputs, "ORD-1/payment:charge#1")
+
+	// Without an orchestration key every call executes
+	_, err = activity(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWithIdempotency_ReusedInstanceIDExecutesAgain(t *testing.T) {
+	store := &mapStore{outputs: make(map[string][]byte), instances: make(map[string]string)}
+	logger := observability.NewLogger(&config.ObservabilityConfig{LogLevel: "error"})
+
+	calls := 0
+	activity := ApplyMiddleware(func(ctx context.Context, input []byte) ([]byte, error) {
+		calls++
+		return []byte(fmt.Sprintf(`{"TransactionID":"TXN_%d"}`, calls)), nil
+	}, WithIdempotency(store, logger, "payment:charge"))
+
+	ctx := WithInvocation(context.Background(), Invocation{
+		InstanceID: "ORD-1",
+		StepKey:    "payment:charge#1",
+		Attempt:    1,
+	})
+
+	first, err := activity(ctx, nil)
+	require.NoError(t, err)
+
+	// Once the instance is purged, a new orchestration with the same ID and
+	// the same step does not get the old result back
+	require.NoError(t, store.DeleteInstance(ctx, "ORD-1"))
+	second, err := activity(ctx, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import "context"
+
+// Invocation is the envelope orchestrators send as activity input. It carries
+// the serialized activity input together with orchestration-level metadata.
+type Invocation struct {
+	InstanceID  string // Orchestration instance that scheduled the activity
+	StepKey     string // Deterministic key for the logical step, shared by all attempts
+	Attempt     int    // 1-based attempt number assigned by the orchestration retry policy
+	TraceParent string // W3C traceparent of the span the activity runs under
+	Input       []byte // Serialized activity input
+}
+
+// IdempotencyKey identifies the logical step across attempts and re-executions.
+// It is empty when the invocation was not scheduled by an orchestration.
+func (inv Invocation) IdempotencyKey() string {
+	if inv.InstanceID == "" || inv.StepKey == "" {
+		return ""
+	}
+	return inv.InstanceID + "/" + inv.StepKey
+}
+
+type invocationKey struct{}
+
+// WithInvocation returns a context carrying the invocation metadata
+func WithInvocation(ctx context.Context, inv Invocation) context.Context {
+	return context.WithValue(ctx, invocationKey{}, inv)
+}
+
+// InvocationFromContext returns the invocation metadata stored in the context
+func InvocationFromContext(ctx context.Context) (Invocation, bool) {
+	inv, ok := ctx.Value(invocationKey{}).(Invocation)
+	return inv, ok
+}
+
+// AttemptFromContext returns the current attempt number, defaulting to 1
+func AttemptFromContext(ctx context.Context) int {
+	if inv, ok := InvocationFromContext(ctx); ok && inv.Attempt > 0 {
+		return inv.Attempt
+	}
+	return 1
+}
+
+// IdempotencyKeyFromContext returns the idempotency key of the current
+// invocation, or an empty string if there is none
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	inv, _ := InvocationFromContext(ctx)
+	return inv.IdempotencyKey()
+}
@@ -13,21 +13,30 @@ func WithLogging(logger *observability.Logger, activityName string) ActivityMidd
 		return func(ctx context.Context, input []byte) ([]byte, error) {
 			start := time.Now()
 
-			// Extract trace ID from context or generate new one
-			traceID := extractTraceID(ctx)
-			if traceID == "" {
-				traceID = generateTraceID()
+			// Correlate with the span and orchestration the activity runs under
+			tc := TraceContextFromContext(ctx)
+			if tc.TraceID == "" {
+				tc.TraceID = extractTraceID(ctx)
 			}
+			if tc.TraceID == "" {
+				tc.TraceID = generateTraceID()
+			}
+
+			inv, _ := InvocationFromContext(ctx)
+			attempt := AttemptFromContext(ctx)
 
 			// Add trace context to logger
-			actLogger := logger.WithTraceID(ctx, traceID).WithActivityName(activityName)
+			actLogger := logger.WithTraceID(ctx, tc.TraceID).
+				WithSpan(tc.SpanID).
+				WithOrchestrationID(inv.InstanceID).
+				WithActivityName(activityName).
+				WithAttempt(attempt)
 
 			// Log start
 			actLogger.Logger.Debug().Msg("activity started")
 
 			// Write to repository if configured
-			startRecord := observability.NewLogRecord(observability.LogLevelDebug, traceID, "activity started").
-				WithActivity(activityName).
+			startRecord := newActivityRecord(observability.LogLevelDebug, tc, inv, activityName, attempt, "activity started").
 				WithInput(input)
 			logger.WriteLogRecord(startRecord)
 
@@ -43,12 +52,12 @@ func WithLogging(logger *observability.Logger, activityName string) ActivityMidd
 					Msg("activity failed")
 
 				// Write error to repository
-				errRecord := observability.NewLogRecord(observability.LogLevelError, traceID, "activity failed").
-					WithActivity(activityName).
+				errRecord := newActivityRecord(observability.LogLevelError, tc, inv, activityName, attempt, "activity failed").
 					WithDuration(duration).
 					WithInput(input).
 					WithError(err.Error())
 				logger.WriteLogRecord(errRecord)
+				logger.WriteTaskEvent(newActivityEvent(tc, inv, activityName, attempt, "activity failed", "ERROR", duration, err))
 
 				return nil, err
 			}
@@ -58,18 +67,45 @@ func WithLogging(logger *observability.Logger, activityName string) ActivityMidd
 				Msg("activity completed")
 
 			// Write completion to repository
-			completeRecord := observability.NewLogRecord(observability.LogLevelInfo, traceID, "activity completed").
-				WithActivity(activityName).
+			completeRecord := newActivityRecord(observability.LogLevelInfo, tc, inv, activityName, attempt, "activity completed").
 				WithDuration(duration).
 				WithInput(input).
 				WithOutput(output)
 			logger.WriteLogRecord(completeRecord)
+			logger.WriteTaskEvent(newActivityEvent(tc, inv, activityName, attempt, "activity completed", "INFO", duration, nil))
 
 			return output, nil
 		}
 	}
 }
 
+// newActivityRecord creates a log record correlated with the activity's
+// trace, span and orchestration
+func newActivityRecord(level observability.LogLevel, tc TraceContext, inv Invocation, activityName string, attempt int, message string) *observability.LogRecord {
+	return observability.NewLogRecord(level, tc.TraceID, message).
+		WithSpan(tc.SpanID, tc.ParentSpanID).
+		WithOrchestrationID(inv.InstanceID).
+		WithActivity(activityName).
+		WithAttempt(attempt)
+}
+
+// newActivityEvent creates a task event recording the outcome of an activity
+func newActivityEvent(tc TraceContext, inv Invocation, activityName string, attempt int, message, severity string, duration time.Duration, err error) *observability.TaskEvent {
+	attributes := map[string]interface{}{
+		"orchestration_id": inv.InstanceID,
+		"activity":         activityName,
+		"attempt":          attempt,
+		"duration_ms":      duration.Milliseconds(),
+	}
+	if tc.ParentSpanID != "" {
+		attributes["parent_span_id"] = tc.ParentSpanID
+	}
+	if err != nil {
+		attributes["error"] = err.Error()
+	}
+	return observability.NewLogEvent(tc.TraceID, tc.SpanID, time.Now(), message, severity, attributes)
+}
+
 // extractTraceID extracts trace ID from context
 func extractTraceID(ctx context.Context) string {
 	if traceID, ok := ctx.Value("trace_id").(string); ok {
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/config"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+)
+
+func TestTraceParent_RoundTrip(t *testing.T) {
+	header := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	sc := ParseTraceParent(header)
+	require.True(t, sc.IsValid())
+	assert.True(t, sc.IsRemote())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+	assert.Equal(t, header, FormatTraceParent(sc))
+
+	assert.False(t, ParseTraceParent("").IsValid())
+	assert.False(t, ParseTraceParent("not-a-traceparent").IsValid())
+	assert.Empty(t, FormatTraceParent(trace.SpanContext{}))
+}
+
+func TestWithLogging_CorrelatesOrchestrationAndSpan(t *testing.T) {
+	dir := t.TempDir()
+	logRepo, err := observability.NewLogRepository(dir+"/logs.db", 10)
+	require.NoError(t, err)
+	defer logRepo.Close()
+	eventRepo, err := observability.NewTaskEventRepository(dir+"/events.db", 10)
+	require.NoError(t, err)
+	defer eventRepo.Close()
+
+	logger := observability.NewLogger(&config.ObservabilityConfig{LogLevel: "error"})
+	logger.SetLogRepository(logRepo)
+	logger.SetTaskEventRepository(eventRepo)
+
+	parent := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	span := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    parent.TraceID(),
+		SpanID:     trace.SpanID{0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x01, 0x02},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), span)
+	ctx = ContextWithParentSpan(ctx, parent.SpanID())
+	ctx = WithInvocation(ctx, Invocation{InstanceID: "ORD-1", Attempt: 2})
+
+	activity := ApplyMiddleware(func(ctx context.Context, input []byte) ([]byte, error) {
+		return nil, fmt.Errorf("gateway unavailable")
+	}, WithLogging(logger, "payment:charge"))
+
+	_, err = activity(ctx, []byte(`{}`))
+	require.Error(t, err)
+	require.NoError(t, logRepo.FlushBatch())
+	require.NoError(t, eventRepo.FlushBatch())
+
+	logs, err := logRepo.QueryByOrchestrationID("ORD-1")
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	for _, record := range logs {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", record.TraceID)
+		assert.Equal(t, "0a0b0c0d0e0f0102", record.SpanID)
+		assert.Equal(t, "00f067aa0ba902b7", record.ParentSpanID)
+		assert.Equal(t, 2, record.Attempt)
+	}
+
+	events, err := eventRepo.QueryByOrchestrationID("ORD-1")
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "0a0b0c0d0e0f0102", events[0].SpanID)
+	assert.Equal(t, "payment:charge", events[0].Activity)
+}
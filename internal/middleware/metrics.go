@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// WithMetrics returns a middleware that records activity executions, labelled
+// by activity name, outcome and error code
+func WithMetrics(metrics *observability.Metrics, activityName string) ActivityMiddleware {
+	return func(next ActivityFunc) ActivityFunc {
+		return func(ctx context.Context, input []byte) ([]byte, error) {
+			metrics.RecordActivityStart(activityName, AttemptFromContext(ctx))
+			start := time.Now()
+
+			output, err := next(ctx, input)
+
+			metrics.RecordActivityExecution(activityName, outcomeOf(err), errors.CodeOf(err), time.Since(start))
+			return output, err
+		}
+	}
+}
+
+// outcomeOf classifies an activity result for metrics with
+// errors.ClassifyError, so outcomes match retry decisions
+func outcomeOf(err error) string {
+	if err == nil {
+		return observability.OutcomeSuccess
+	}
+
+	switch errors.ClassifyError(err) {
+	case errors.ErrorTypeTransient:
+		return observability.OutcomeTransient
+	case errors.ErrorTypeTimeout:
+		return observability.OutcomeTimeout
+	default:
+		return observability.OutcomePermanent
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+func TestWithMetrics_LabelsByActivityOutcomeAndCode(t *testing.T) {
+	metrics := observability.NewMetrics(prometheus.NewRegistry())
+
+	results := []error{
+		nil,
+		errors.NewTransientError("PAYMENT_GATEWAY_UNAVAILABLE", "gateway unavailable", nil),
+		errors.NewTimeoutError("ACTIVITY_TIMEOUT", "timed out"),
+		fmt.Errorf("unclassified"),
+	}
+	for i, result := range results {
+		result := result
+		activity := ApplyMiddleware(func(ctx context.Context, input []byte) ([]byte, error) {
+			assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ActivityInFlight.WithLabelValues("payment:charge")))
+			return nil, result
+		}, WithMetrics(metrics, "payment:charge"))
+
+		ctx := WithInvocation(context.Background(), Invocation{InstanceID: "ORD-1", Attempt: i + 1})
+		_, err := activity(ctx, nil)
+		assert.Equal(t, result, err)
+	}
+
+	executions := metrics.ActivityExecutions
+	assert.Equal(t, 1.0, testutil.ToFloat64(executions.WithLabelValues("payment:charge", observability.OutcomeSuccess, "")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(executions.WithLabelValues("payment:charge", observability.OutcomeTransient, "PAYMENT_GATEWAY_UNAVAILABLE")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(executions.WithLabelValues("payment:charge", observability.OutcomeTimeout, "ACTIVITY_TIMEOUT")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(executions.WithLabelValues("payment:charge", observability.OutcomeTransient, "")), "unclassified errors are transient, as for retries")
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ActivityErrors.WithLabelValues("payment:charge", "ACTIVITY_TIMEOUT")))
+	assert.Equal(t, 3.0, testutil.ToFloat64(metrics.ActivityRetries.WithLabelValues("payment:charge")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.ActivityInFlight.WithLabelValues("payment:charge")))
+
+	// Latency is split by outcome rather than mixed into one series
+	assert.Equal(t, 3, testutil.CollectAndCount(metrics.ActivityDuration))
+}
+
+func TestNewMetrics_SeparateRegistries(t *testing.T) {
+	// Each harness owns a registry, so creating several must not panic
+	require.NotPanics(t, func() {
+		observability.NewMetrics(prometheus.NewRegistry())
+		observability.NewMetrics(prometheus.NewRegistry())
+	})
+}
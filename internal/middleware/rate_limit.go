@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// tokenBucket is a minimal token bucket shared by all invocations of an activity
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64 // tokens per second
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// reserve takes a token and returns how long the caller must wait before using it
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a token reserved by a caller that gave up waiting
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens++
+}
+
+// WithRateLimit returns a middleware that limits activity executions to
+// ratePerSecond with bursts of up to burst. Callers wait for a token until
+// their context is done.
+func WithRateLimit(name string, ratePerSecond float64, burst int) ActivityMiddleware {
+	if burst < 1 {
+		burst = 1
+	}
+	bucket := &tokenBucket{
+		rate:     ratePerSecond,
+		capacity: float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+
+	return func(next ActivityFunc) ActivityFunc {
+		return func(ctx context.Context, input []byte) ([]byte, error) {
+			if wait := bucket.reserve(time.Now()); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					bucket.cancel()
+					return nil, errors.NewTransientError(
+						"RATE_LIMITED",
+						fmt.Sprintf("rate limit exceeded for activity: %s", name),
+						ctx.Err(),
+					)
+				}
+			}
+
+			return next(ctx, input)
+		}
+	}
+}
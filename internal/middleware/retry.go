@@ -27,7 +27,10 @@ func DefaultRetryPolicy(maxAttempts int) RetryPolicy {
 	}
 }
 
-// WithRetry returns a middleware that retries the activity on transient failures
+// WithRetry returns a middleware that retries the activity on transient failures.
+//
+// Retries happen in-process and are lost if the worker crashes; prefer
+// declaring a workflows.RetryPolicy so the orchestration retries durably.
 func WithRetry(logger *observability.Logger, policy RetryPolicy) ActivityMiddleware {
 	return func(next ActivityFunc) ActivityFunc {
 		return func(ctx context.Context, input []byte) ([]byte, error) {
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceContext locates an activity invocation within a trace
+type TraceContext struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+}
+
+type parentSpanKey struct{}
+
+// ContextWithParentSpan records the parent of the span active in ctx, since
+// a span context alone does not expose its parent
+func ContextWithParentSpan(ctx context.Context, parent trace.SpanID) context.Context {
+	return context.WithValue(ctx, parentSpanKey{}, parent)
+}
+
+// TraceContextFromContext returns the trace, span and parent span IDs of the
+// span active in ctx. Fields are empty when there is no valid span.
+func TraceContextFromContext(ctx context.Context) TraceContext {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return TraceContext{}
+	}
+
+	tc := TraceContext{
+		TraceID: sc.TraceID().String(),
+		SpanID:  sc.SpanID().String(),
+	}
+	if parent, ok := ctx.Value(parentSpanKey{}).(trace.SpanID); ok && parent.IsValid() {
+		tc.ParentSpanID = parent.String()
+	}
+	return tc
+}
+
+// FormatTraceParent returns the W3C traceparent header for a span context
+func FormatTraceParent(sc trace.SpanContext) string {
+	if !sc.IsValid() {
+		return ""
+	}
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(trace.ContextWithSpanContext(context.Background(), sc), carrier)
+	return carrier.Get("traceparent")
+}
+
+// ParseTraceParent parses a W3C traceparent header into a remote span
+// context. The result is invalid if the header is empty or malformed.
+func ParseTraceParent(traceparent string) trace.SpanContext {
+	if traceparent == "" {
+		return trace.SpanContext{}
+	}
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{"traceparent": traceparent})
+	return trace.SpanContextFromContext(ctx)
+}
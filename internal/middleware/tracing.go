@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// WithTracing returns a middleware that records each activity execution as a
+// span, nested under the span active in the context (normally the span of the
+// orchestration that scheduled the activity)
+func WithTracing(tracer trace.Tracer, activityName string) ActivityMiddleware {
+	return func(next ActivityFunc) ActivityFunc {
+		return func(ctx context.Context, input []byte) ([]byte, error) {
+			inv, _ := InvocationFromContext(ctx)
+			parent := trace.SpanContextFromContext(ctx)
+
+			ctx, span := tracer.Start(ctx, activityName,
+				trace.WithSpanKind(trace.SpanKindInternal),
+				trace.WithAttributes(
+					attribute.String(observability.AttrActivityName, activityName),
+					attribute.Int(observability.AttrActivityAttempt, AttemptFromContext(ctx)),
+				),
+			)
+			defer span.End()
+
+			if inv.InstanceID != "" {
+				span.SetAttributes(attribute.String(observability.AttrOrchestrationID, inv.InstanceID))
+			}
+			// A non-recording tracer hands back the parent, which is not its own parent
+			if parent.IsValid() && span.SpanContext().SpanID() != parent.SpanID() {
+				ctx = ContextWithParentSpan(ctx, parent.SpanID())
+			}
+
+			output, err := next(ctx, input)
+			if err != nil {
+				RecordSpanError(span, err)
+				return nil, err
+			}
+
+			span.SetStatus(codes.Ok, "")
+			return output, nil
+		}
+	}
+}
+
+// RecordSpanError marks a span as failed and attaches the CustomError code
+// and type of err when it has them
+func RecordSpanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+
+	if code := errors.CodeOf(err); code != "" {
+		span.SetAttributes(attribute.String(observability.AttrErrorCode, code))
+	}
+	var customErr *errors.CustomError
+	if stderrors.As(err, &customErr) {
+		span.SetAttributes(attribute.String(observability.AttrErrorType, customErr.Type.String()))
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+func TestWithTracing_NestsUnderOrchestrationSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer(observability.TracerName)
+
+	parent := observability.OrchestrationSpanContext("ORD-1")
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), parent)
+	ctx = WithInvocation(ctx, Invocation{InstanceID: "ORD-1", Attempt: 2})
+
+	var seen TraceContext
+	activity := ApplyMiddleware(func(ctx context.Context, input []byte) ([]byte, error) {
+		seen = TraceContextFromContext(ctx)
+		return nil, errors.NewPermanentError("CARD_DECLINED", "card declined", nil)
+	}, WithTracing(tracer, "payment:charge"))
+
+	_, err := activity(ctx, nil)
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+
+	assert.Equal(t, "payment:charge", span.Name())
+	assert.Equal(t, parent.TraceID(), span.SpanContext().TraceID())
+	assert.Equal(t, parent.SpanID(), span.Parent().SpanID())
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	attrs := make(map[string]interface{})
+	for _, kv := range span.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	assert.Equal(t, "payment:charge", attrs[observability.AttrActivityName])
+	assert.Equal(t, int64(2), attrs[observability.AttrActivityAttempt])
+	assert.Equal(t, "ORD-1", attrs[observability.AttrOrchestrationID])
+	assert.Equal(t, "CARD_DECLINED", attrs[observability.AttrErrorCode])
+	assert.Equal(t, "permanent", attrs[observability.AttrErrorType])
+
+	// Inner middleware sees the activity span and its parent
+	assert.Equal(t, span.SpanContext().SpanID().String(), seen.SpanID)
+	assert.Equal(t, parent.SpanID().String(), seen.ParentSpanID)
+}
+
+func TestIDGenerator_PresetRootSpanIDs(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(recorder),
+		sdktrace.WithIDGenerator(observability.NewIDGenerator()),
+	).Tracer(observability.TracerName)
+
+	want := observability.OrchestrationSpanContext("ORD-1")
+	assert.Equal(t, want, observability.OrchestrationSpanContext("ORD-1"), "IDs are deterministic")
+	assert.NotEqual(t, want.TraceID(), observability.OrchestrationSpanContext("ORD-2").TraceID())
+
+	ctx := observability.ContextWithSpanIDs(context.Background(), want)
+	_, root := tracer.Start(ctx, "orchestration:order_processing", trace.WithNewRoot())
+	root.End()
+	assert.Equal(t, want.TraceID(), root.SpanContext().TraceID())
+	assert.Equal(t, want.SpanID(), root.SpanContext().SpanID())
+
+	// Spans started without preset IDs are random
+	_, other := tracer.Start(context.Background(), "other")
+	other.End()
+	assert.NotEqual(t, want.TraceID(), other.SpanContext().TraceID())
+}
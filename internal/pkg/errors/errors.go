@@ -1,7 +1,9 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
+	"regexp"
 )
 
 // ErrorType represents the classification of an error
@@ -16,6 +18,20 @@ const (
 	ErrorTypeTimeout
 )
 
+// String returns the lowercase name of the error type
+func (t ErrorType) String() string {
+	switch t {
+	case ErrorTypeTransient:
+		return "transient"
+	case ErrorTypePermanent:
+		return "permanent"
+	case ErrorTypeTimeout:
+		return "timeout"
+	default:
+		return fmt.Sprintf("ErrorType(%d)", int(t))
+	}
+}
+
 // CustomError is a custom error with classification and context
 type CustomError struct {
 	Type    ErrorType
@@ -81,16 +97,73 @@ func (e *CustomError) IsTimeout() bool {
 	return e.Type == ErrorTypeTimeout
 }
 
-// ClassifyError attempts to classify a regular error
+// ClassifyError returns the ErrorType of err, as TypeOf does. Unclassified
+// errors, such as a locked database, count as transient. Retry decisions,
+// circuit breakers and metrics all classify through here so they agree.
 func ClassifyError(err error) ErrorType {
+	if errType, ok := TypeOf(err); ok {
+		return errType
+	}
+	return ErrorTypeTransient
+}
+
+// typePattern matches the "type: [CODE]" prefix added by Annotate
+var typePattern = regexp.MustCompile(`\b(transient|permanent|timeout): \[[A-Z0-9_]+\]`)
+
+// Annotate prefixes the message of an error wrapping a CustomError with its
+// type, as in "permanent: [CODE] msg", so the classification survives an
+// orchestration boundary. Other errors are returned unchanged.
+func Annotate(err error) error {
+	var customErr *CustomError
+	if !stderrors.As(err, &customErr) {
+		return err
+	}
+	return fmt.Errorf("%s: %w", customErr.Type, err)
+}
+
+// TypeOf returns the ErrorType of err and whether it is known. Errors that
+// crossed an orchestration boundary are classified by their Annotate prefix.
+func TypeOf(err error) (ErrorType, bool) {
 	if err == nil {
-		return ErrorTypePermanent
+		return ErrorTypePermanent, false
 	}
 
-	if customErr, ok := err.(*CustomError); ok {
-		return customErr.Type
+	var customErr *CustomError
+	if stderrors.As(err, &customErr) {
+		return customErr.Type, true
 	}
 
-	// Default to permanent for unknown errors
-	return ErrorTypePermanent
+	if m := typePattern.FindStringSubmatch(err.Error()); m != nil {
+		switch m[1] {
+		case "transient":
+			return ErrorTypeTransient, true
+		case "timeout":
+			return ErrorTypeTimeout, true
+		default:
+			return ErrorTypePermanent, true
+		}
+	}
+	return ErrorTypePermanent, false
+}
+
+// codePattern matches the "[CODE]" prefix produced by CustomError.Error
+var codePattern = regexp.MustCompile(`\[([A-Z0-9_]+)\]`)
+
+// CodeOf returns the CustomError code of err. Errors that crossed an
+// orchestration boundary only keep their message, so the code is parsed from
+// the "[CODE]" prefix when err is not a CustomError.
+func CodeOf(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var customErr *CustomError
+	if stderrors.As(err, &customErr) {
+		return customErr.Code
+	}
+
+	if m := codePattern.FindStringSubmatch(err.Error()); m != nil {
+		return m[1]
+	}
+	return ""
 }
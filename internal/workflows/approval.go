@@ -0,0 +1,94 @@
+package workflows
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/microsoft/durabletask-go/task"
+
+	"github.com/Youmanvi/taskorchestrator/internal/activities/approval"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+)
+
+// ApprovalOutcome records how an order's approval step ended
+type ApprovalOutcome struct {
+	Status   domain.ApprovalStatus // Approved, rejected or expired
+	Approver string                `json:",omitempty"`
+	Comment  string                `json:",omitempty"`
+}
+
+// Reason describes the outcome for the order's failure message
+func (o ApprovalOutcome) Reason() string {
+	reason := "approval " + string(o.Status)
+	if o.Approver != "" {
+		reason += " by " + o.Approver
+	}
+	if o.Comment != "" {
+		reason += ": " + o.Comment
+	}
+	return reason
+}
+
+// needsApproval reports whether an order's total is above the approval
+// threshold; a zero threshold disables approvals
+func (deps *WorkflowDeps) needsApproval(order domain.Order) bool {
+	return deps.ApprovalThreshold.IsPositive() && order.TotalAmount.GreaterThan(deps.ApprovalThreshold)
+}
+
+// awaitApproval records an approval request for the order and waits for an
+// approval.EventName event, up to ApprovalTimeout. The wait is a durable
+// timer, so a deadline set before a worker restart still fires after it.
+// The order is expired if nobody decides in time.
+func awaitApproval(ctx *task.OrchestrationContext, deps *WorkflowDeps, order domain.Order) (ApprovalOutcome, error) {
+	requestedAt := ctx.CurrentTimeUtc
+	requestInput := approval.RequestApprovalInput{
+		OrderID:     order.ID,
+		CustomerID:  order.CustomerID,
+		Amount:      order.TotalAmount,
+		RequestedAt: requestedAt,
+		Deadline:    requestedAt.Add(deps.ApprovalTimeout),
+	}
+	var requestOutput approval.RequestApprovalOutput
+	if err := deps.callActivity(ctx, "approval:request", requestInput, &requestOutput); err != nil {
+		return ApprovalOutcome{}, err
+	}
+
+	// The payload is decoded here rather than by Await so a malformed event
+	// is told apart from the deadline passing
+	var raw json.RawMessage
+	outcome := ApprovalOutcome{Status: domain.ApprovalStatusExpired, Comment: "approval deadline passed"}
+	if err := ctx.WaitForSingleEvent(approval.EventName, deps.ApprovalTimeout).Await(&raw); err == nil {
+		outcome = decisionOutcome(raw)
+	}
+
+	// The orchestration's outcome stands even if recording it fails; the
+	// call then waits in the dead-letter queue to be replayed
+	resolveInput := approval.ResolveApprovalInput{
+		OrderID:  order.ID,
+		Status:   outcome.Status,
+		Approver: outcome.Approver,
+		Comment:  outcome.Comment,
+	}
+	var resolveOutput approval.ResolveApprovalOutput
+	_ = deps.callNonCritical(ctx, "approval:resolve", resolveInput, &resolveOutput)
+
+	return outcome, nil
+}
+
+// decisionOutcome turns an approval event payload into an outcome. Anything
+// other than a valid approval rejects the order.
+func decisionOutcome(raw json.RawMessage) ApprovalOutcome {
+	var decision approval.Decision
+	if err := json.Unmarshal(raw, &decision); err != nil {
+		return ApprovalOutcome{Status: domain.ApprovalStatusRejected, Comment: fmt.Sprintf("invalid approval event: %v", err)}
+	}
+	if err := decision.Validate(); err != nil {
+		return ApprovalOutcome{Status: domain.ApprovalStatusRejected, Approver: decision.Approver, Comment: fmt.Sprintf("invalid approval event: %v", err)}
+	}
+
+	status := domain.ApprovalStatusRejected
+	if decision.Decision == approval.DecisionApprove {
+		status = domain.ApprovalStatusApproved
+	}
+	return ApprovalOutcome{Status: status, Approver: decision.Approver, Comment: decision.Comment}
+}
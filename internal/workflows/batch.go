@@ -0,0 +1,162 @@
+package workflows
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/microsoft/durabletask-go/task"
+)
+
+// Batch limits enforced by BatchOrchestrator
+const (
+	MaxBatchItems           = 1000
+	DefaultBatchParallelism = 10
+)
+
+// Batch outcome statuses reported in OrchestrationOutput
+const (
+	BatchStatusCompleted = "completed" // Every item succeeded
+	BatchStatusPartial   = "partial"   // Some items failed
+	BatchStatusFailed    = "failed"    // Every item failed
+	BatchStatusRejected  = "rejected"  // The input was invalid; nothing ran
+)
+
+// BatchItem is one item of a batch; Input is passed to the activity as is
+type BatchItem struct {
+	ID    string          `json:"id"`
+	Input json.RawMessage `json:"input"`
+}
+
+// OrchestrationInput is the input to the batch orchestrator
+type OrchestrationInput struct {
+	Activity       string      `json:"activity"`
+	Items          []BatchItem `json:"items"`
+	MaxParallelism int         `json:"max_parallelism,omitempty"` // Zero uses DefaultBatchParallelism
+}
+
+// BatchItemResult is the outcome of one batch item
+type BatchItemResult struct {
+	ID       string          `json:"id"`
+	Success  bool            `json:"success"`
+	Output   json.RawMessage `json:"output,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Attempts int             `json:"attempts"`
+}
+
+// OrchestrationOutput is the output of the batch orchestrator
+type OrchestrationOutput struct {
+	Status       string            `json:"status"`
+	TotalItems   int               `json:"total_items"`
+	SuccessCount int               `json:"success_count"`
+	FailureCount int               `json:"failure_count"`
+	Results      []BatchItemResult `json:"results"`
+	Errors       []string          `json:"errors"`
+}
+
+// OrchestrationStatus reports the batch status as the orchestration outcome
+func (o OrchestrationOutput) OrchestrationStatus() string {
+	return o.Status
+}
+
+// Validate checks the batch before anything runs and returns every problem
+// found
+func (in OrchestrationInput) Validate() []string {
+	var problems []string
+	if in.Activity == "" {
+		problems = append(problems, "activity is required")
+	}
+	if len(in.Items) == 0 {
+		problems = append(problems, "batch must contain at least one item")
+	}
+	if len(in.Items) > MaxBatchItems {
+		problems = append(problems, fmt.Sprintf("batch has %d items, more than the limit of %d", len(in.Items), MaxBatchItems))
+	}
+	if in.MaxParallelism < 0 {
+		problems = append(problems, "max_parallelism cannot be negative")
+	}
+
+	seen := make(map[string]bool, len(in.Items))
+	for i, item := range in.Items {
+		switch {
+		case item.ID == "":
+			problems = append(problems, fmt.Sprintf("item %d has no id", i))
+		case seen[item.ID]:
+			problems = append(problems, fmt.Sprintf("item id %s is duplicated", item.ID))
+		}
+		seen[item.ID] = true
+
+		if len(item.Input) == 0 || string(item.Input) == "null" {
+			problems = append(problems, fmt.Sprintf("item %s has no input", item.ID))
+		} else if !json.Valid(item.Input) {
+			problems = append(problems, fmt.Sprintf("item %s input is not valid JSON", item.ID))
+		}
+	}
+	return problems
+}
+
+// BatchOrchestrator runs one activity over a list of items. The batch is
+// validated up front, items fan out with bounded parallelism, and each
+// item's output or error is reported in input order.
+func BatchOrchestrator(deps *WorkflowDeps) task.Orchestrator {
+	return func(ctx *task.OrchestrationContext) (any, error) {
+		var inp OrchestrationInput
+		if err := ctx.GetInput(&inp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal batch input: %w", err)
+		}
+		return runBatch(ctx, deps, inp), nil
+	}
+}
+
+// runBatch validates, fans out and aggregates a batch
+func runBatch(ctx *task.OrchestrationContext, deps *WorkflowDeps, inp OrchestrationInput) OrchestrationOutput {
+	output := OrchestrationOutput{
+		TotalItems: len(inp.Items),
+		Results:    []BatchItemResult{},
+		Errors:     []string{},
+	}
+
+	if problems := inp.Validate(); len(problems) > 0 {
+		output.Status = BatchStatusRejected
+		output.FailureCount = output.TotalItems
+		output.Errors = problems
+		return output
+	}
+
+	parallelism := inp.MaxParallelism
+	if parallelism == 0 {
+		parallelism = DefaultBatchParallelism
+	}
+
+	calls := make([]ActivityCall, len(inp.Items))
+	for i, item := range inp.Items {
+		// Raw JSON is sent as is rather than re-encoded as a string
+		calls[i] = ActivityCall{Activity: inp.Activity, Input: []byte(item.Input)}
+	}
+
+	for i, result := range FanOut(ctx, deps.RetryPolicies, calls, parallelism) {
+		item := BatchItemResult{
+			ID:       inp.Items[i].ID,
+			Success:  result.Err == nil,
+			Output:   result.Output,
+			Attempts: result.Attempts,
+		}
+		if result.Err != nil {
+			item.Error = result.Err.Error()
+			output.FailureCount++
+			output.Errors = append(output.Errors, fmt.Sprintf("item %s: %v", item.ID, result.Err))
+		} else {
+			output.SuccessCount++
+		}
+		output.Results = append(output.Results, item)
+	}
+
+	switch {
+	case output.FailureCount == 0:
+		output.Status = BatchStatusCompleted
+	case output.SuccessCount == 0:
+		output.Status = BatchStatusFailed
+	default:
+		output.Status = BatchStatusPartial
+	}
+	return output
+}
@@ -0,0 +1,97 @@
+package workflows
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/microsoft/durabletask-go/task"
+
+	"github.com/Youmanvi/taskorchestrator/internal/activities/deadletter"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// callNonCritical calls an activity whose failure must not fail the
+// orchestration. A call that still fails after its retries is recorded in the
+// dead-letter queue, with its input, so it can be inspected and replayed. The
+// call's error is returned either way.
+func (d *WorkflowDeps) callNonCritical(ctx *task.OrchestrationContext, activity string, input interface{}, output interface{}) error {
+	attempts, err := CallActivityWithRetry(ctx, d.RetryPolicies.For(activity), activity, input, output)
+	if err == nil {
+		return nil
+	}
+
+	payload, merr := marshalInput(input)
+	if merr != nil {
+		return err
+	}
+	record := deadletter.RecordInput{
+		OrchestrationID: string(ctx.ID),
+		Activity:        activity,
+		Payload:         payload,
+		ErrorCode:       errors.CodeOf(err),
+		ErrorMessage:    err.Error(),
+		Attempts:        attempts,
+	}
+	var recorded deadletter.RecordOutput
+	if rerr := d.callActivity(ctx, "deadletter:record", record, &recorded); rerr != nil {
+		return fmt.Errorf("%w (dead-letter record failed: %v)", err, rerr)
+	}
+	return err
+}
+
+// DeadLetterReplayInput is the input to the dead-letter replay orchestrator
+type DeadLetterReplayInput struct {
+	EntryID  int64
+	Activity string
+	Payload  json.RawMessage
+	Attempts int // Attempts made before this replay
+}
+
+// DeadLetterReplayOutput is the output of the dead-letter replay orchestrator
+type DeadLetterReplayOutput struct {
+	EntryID  int64
+	Status   string // The entry's status afterwards: replayed, or pending if the replay failed
+	Attempts int
+	Message  string
+}
+
+// OrchestrationStatus reports the entry status as the orchestration outcome
+func (o DeadLetterReplayOutput) OrchestrationStatus() string {
+	return o.Status
+}
+
+// DeadLetterReplayOrchestrator calls a dead-lettered activity again with its
+// recorded input, under the activity's retry policy, and records the outcome
+// on the entry
+func DeadLetterReplayOrchestrator(deps *WorkflowDeps) task.Orchestrator {
+	return func(ctx *task.OrchestrationContext) (any, error) {
+		var inp DeadLetterReplayInput
+		if err := ctx.GetInput(&inp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dead-letter replay input: %w", err)
+		}
+
+		attempts, err := CallActivityWithRetry(ctx, deps.RetryPolicies.For(inp.Activity), inp.Activity, []byte(inp.Payload), nil)
+		resolve := deadletter.ResolveInput{
+			ID:        inp.EntryID,
+			Succeeded: err == nil,
+			Attempts:  inp.Attempts + attempts,
+		}
+		output := DeadLetterReplayOutput{
+			EntryID:  inp.EntryID,
+			Attempts: resolve.Attempts,
+			Message:  "replay succeeded",
+		}
+		if err != nil {
+			resolve.ErrorCode = errors.CodeOf(err)
+			resolve.ErrorMessage = err.Error()
+			output.Message = fmt.Sprintf("replay failed: %v", err)
+		}
+
+		var resolved deadletter.ResolveOutput
+		if rerr := deps.callActivity(ctx, "deadletter:resolve", resolve, &resolved); rerr != nil {
+			return nil, fmt.Errorf("failed to record replay of dead-letter entry %d: %w", inp.EntryID, rerr)
+		}
+		output.Status = resolved.Status
+		return output, nil
+	}
+}
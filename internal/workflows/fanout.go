@@ -0,0 +1,107 @@
+package workflows
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/microsoft/durabletask-go/task"
+)
+
+// ActivityCall is one activity call in a fan-out
+type ActivityCall struct {
+	Activity string
+	Input    interface{}
+}
+
+// ActivityResult is the outcome of one activity call in a fan-out
+type ActivityResult struct {
+	Output   json.RawMessage
+	Err      error
+	Attempts int
+}
+
+// FanOut runs activity calls in parallel, at most maxParallelism at a time
+// (zero or less runs them all at once), and returns their results in call
+// order. Calls are scheduled in windows: every call in a window is scheduled
+// before any is awaited, and failed calls are retried together under their
+// retry policies with one durable timer, so a window never waits longer than
+// its slowest backoff. A call's step key includes its position, so calls with
+// identical inputs still get their own idempotency keys.
+func FanOut(ctx *task.OrchestrationContext, policies RetryPolicies, calls []ActivityCall, maxParallelism int) []ActivityResult {
+	results := make([]ActivityResult, len(calls))
+	if maxParallelism <= 0 || maxParallelism > len(calls) {
+		maxParallelism = len(calls)
+	}
+
+	for start := 0; start < len(calls); start += maxParallelism {
+		end := min(start+maxParallelism, len(calls))
+		fanOutWindow(ctx, policies, calls, results, start, end)
+	}
+	return results
+}
+
+// fanOutWindow runs calls[start:end] to completion, including retries
+func fanOutWindow(ctx *task.OrchestrationContext, policies RetryPolicies, calls []ActivityCall, results []ActivityResult, start, end int) {
+	inputs := make([][]byte, end-start)
+	stepKeys := make([]string, end-start)
+	pending := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		input, err := marshalInput(calls[i].Input)
+		if err != nil {
+			results[i] = ActivityResult{Err: err}
+			continue
+		}
+		inputs[i-start] = input
+		stepKeys[i-start] = fmt.Sprintf("%s/%d", StepKey(calls[i].Activity, input), i)
+		pending = append(pending, i)
+	}
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		tasks := make([]task.Task, len(pending))
+		for j, i := range pending {
+			tasks[j] = callAttempt(ctx, calls[i].Activity, stepKeys[i-start], attempt, inputs[i-start])
+		}
+
+		outputs, errs := whenAll(tasks)
+
+		var retry []int
+		var delay time.Duration
+		for j, i := range pending {
+			results[i] = ActivityResult{Output: outputs[j], Err: errs[j], Attempts: attempt}
+			if errs[j] == nil {
+				continue
+			}
+
+			policy := policies.For(calls[i].Activity)
+			if attempt >= policy.MaxAttempts || !policy.IsRetryable(errs[j]) {
+				continue
+			}
+			retry = append(retry, i)
+			seed := fmt.Sprintf("%s/%s/%d", ctx.ID, calls[i].Activity, i)
+			delay = max(delay, policy.Backoff(attempt, seed))
+		}
+
+		if len(retry) > 0 && delay > 0 {
+			if err := ctx.CreateTimer(delay).Await(nil); err != nil {
+				for _, i := range retry {
+					results[i].Err = fmt.Errorf("retry timer for %s failed: %w", calls[i].Activity, err)
+				}
+				return
+			}
+		}
+		pending = retry
+	}
+}
+
+// whenAll waits for every task and returns their outputs and errors in task
+// order. Tasks run concurrently from the moment they are scheduled, so
+// awaiting them one after another completes once the slowest has finished.
+func whenAll(tasks []task.Task) ([]json.RawMessage, []error) {
+	outputs := make([]json.RawMessage, len(tasks))
+	errs := make([]error, len(tasks))
+	for i, t := range tasks {
+		errs[i] = t.Await(&outputs[i])
+	}
+	return outputs, errs
+}
@@ -0,0 +1,181 @@
+package workflows
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/microsoft/durabletask-go/task"
+
+	"github.com/Youmanvi/taskorchestrator/internal/activities/inventory"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+)
+
+// checkAvailability checks every SKU of the order in parallel, one
+// inventory:check per SKU, and merges the results into a single output
+func checkAvailability(ctx *task.OrchestrationContext, deps *WorkflowDeps, items []domain.OrderItem) (inventory.CheckAvailabilityOutput, error) {
+	// Lines for the same SKU are checked together so their quantities add up
+	var skus []string
+	quantities := make(map[string]int32)
+	for _, item := range items {
+		if _, seen := quantities[item.SKU]; !seen {
+			skus = append(skus, item.SKU)
+		}
+		quantities[item.SKU] += item.Quantity
+	}
+
+	calls := make([]ActivityCall, len(skus))
+	for i, sku := range skus {
+		calls[i] = ActivityCall{
+			Activity: "inventory:check",
+			Input: inventory.CheckAvailabilityInput{
+				Items: []domain.OrderItem{{SKU: sku, Quantity: quantities[sku]}},
+			},
+		}
+	}
+
+	output := inventory.CheckAvailabilityOutput{UnavailableItems: []string{}}
+	for i, result := range FanOut(ctx, deps.RetryPolicies, calls, deps.MaxParallelism) {
+		if result.Err != nil {
+			return output, fmt.Errorf("%s: %w", skus[i], result.Err)
+		}
+
+		var check inventory.CheckAvailabilityOutput
+		if err := json.Unmarshal(result.Output, &check); err != nil {
+			return output, fmt.Errorf("%s: failed to unmarshal check output: %w", skus[i], err)
+		}
+		output.UnavailableItems = append(output.UnavailableItems, check.UnavailableItems...)
+	}
+
+	output.Available = len(skus) > 0 && len(output.UnavailableItems) == 0
+	return output, nil
+}
+
+// reserveInventory reserves the order's items in every warehouse that
+// fulfils them, in parallel. It returns the IDs of the reservations made,
+// even when another warehouse failed, so the caller can release them.
+func reserveInventory(ctx *task.OrchestrationContext, deps *WorkflowDeps, order domain.Order) ([]string, error) {
+	groups := domain.GroupByWarehouse(order.Items)
+
+	calls := make([]ActivityCall, len(groups))
+	for i, group := range groups {
+		calls[i] = ActivityCall{
+			Activity: "inventory:reserve",
+			Input: inventory.ReserveInventoryInput{
+				OrderID:   order.ID,
+				Warehouse: group.Warehouse,
+				Items:     group.Items,
+			},
+		}
+	}
+
+	var reserved []string
+	var firstErr error
+	for i, result := range FanOut(ctx, deps.RetryPolicies, calls, deps.MaxParallelism) {
+		if result.Err != nil {
+			if firstErr == nil {
+				firstErr = result.Err
+				if groups[i].Warehouse != "" {
+					firstErr = fmt.Errorf("warehouse %s: %w", groups[i].Warehouse, result.Err)
+				}
+			}
+			continue
+		}
+
+		var reserve inventory.ReserveInventoryOutput
+		if err := json.Unmarshal(result.Output, &reserve); err != nil {
+			// The reservation exists, so release it by its derived ID
+			reserved = append(reserved, domain.ReservationID(order.ID, groups[i].Warehouse))
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to unmarshal reserve output: %w", err)
+			}
+			continue
+		}
+		reserved = append(reserved, reserve.ReservationID)
+	}
+	return reserved, firstErr
+}
+
+// commitInventory commits the order's reservations in parallel once it is
+// paid, so their stock leaves the warehouse instead of expiring. It returns
+// the first commit error.
+func commitInventory(ctx *task.OrchestrationContext, deps *WorkflowDeps, reservationIDs []string) error {
+	calls := make([]ActivityCall, len(reservationIDs))
+	for i, id := range reservationIDs {
+		calls[i] = ActivityCall{
+			Activity: "inventory:commit",
+			Input:    inventory.CommitInventoryInput{ReservationID: id},
+		}
+	}
+
+	for i, result := range FanOut(ctx, deps.RetryPolicies, calls, deps.MaxParallelism) {
+		if result.Err != nil {
+			return fmt.Errorf("%s: %w", reservationIDs[i], result.Err)
+		}
+	}
+	return nil
+}
+
+// restockInventory puts a refunded order's committed stock back: only the
+// returned items when items is set, otherwise every warehouse's whole
+// reservation. It returns the first restock error.
+func restockInventory(ctx *task.OrchestrationContext, deps *WorkflowDeps, order domain.Order, items []domain.OrderItem) error {
+	var calls []ActivityCall
+	if len(items) == 0 {
+		for _, group := range domain.GroupByWarehouse(order.Items) {
+			calls = append(calls, ActivityCall{
+				Activity: "inventory:restock",
+				Input:    inventory.RestockInventoryInput{ReservationID: domain.ReservationID(order.ID, group.Warehouse)},
+			})
+		}
+	} else {
+		for _, group := range returnsByWarehouse(order, items) {
+			calls = append(calls, ActivityCall{
+				Activity: "inventory:restock",
+				Input: inventory.RestockInventoryInput{
+					ReservationID: domain.ReservationID(order.ID, group.Warehouse),
+					Items:         group.Items,
+				},
+			})
+		}
+	}
+
+	for _, result := range FanOut(ctx, deps.RetryPolicies, calls, deps.MaxParallelism) {
+		if result.Err != nil {
+			return result.Err
+		}
+	}
+	return nil
+}
+
+// checkReturn verifies that each warehouse still has the returned items
+// committed to the order, so lines already returned cannot be refunded again.
+// It returns the first check error.
+func checkReturn(ctx *task.OrchestrationContext, deps *WorkflowDeps, order domain.Order, items []domain.OrderItem) error {
+	var calls []ActivityCall
+	for _, group := range returnsByWarehouse(order, items) {
+		calls = append(calls, ActivityCall{
+			Activity: "inventory:check_restock",
+			Input: inventory.CheckRestockInput{
+				ReservationID: domain.ReservationID(order.ID, group.Warehouse),
+				Items:         group.Items,
+			},
+		})
+	}
+
+	for _, result := range FanOut(ctx, deps.RetryPolicies, calls, deps.MaxParallelism) {
+		if result.Err != nil {
+			return result.Err
+		}
+	}
+	return nil
+}
+
+// returnsByWarehouse groups returned items by the warehouse that shipped them
+func returnsByWarehouse(order domain.Order, items []domain.OrderItem) []domain.WarehouseItems {
+	returned := make([]domain.OrderItem, len(items))
+	for i, item := range items {
+		item.Warehouse = order.WarehouseOf(item.SKU)
+		returned[i] = item
+	}
+	return domain.GroupByWarehouse(returned)
+}
@@ -1,8 +1,8 @@
 package workflows
 
 import (
-	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/microsoft/durabletask-go/task"
 	"github.com/Youmanvi/taskorchestrator/internal/activities/inventory"
@@ -13,21 +13,42 @@ import (
 
 // OrderProcessingInput is the input to the order processing orchestrator
 type OrderProcessingInput struct {
-	Order      domain.Order
-	CustomerEmail string
+	Order          domain.Order
+	CustomerEmail  string
+	CustomerPhone  string // Optional; enables SMS notifications
+	CustomerLocale string // Optional, e.g. "fr-CA"; selects notification templates
 }
 
 // OrderProcessingOutput is the output of the order processing orchestrator
 type OrderProcessingOutput struct {
-	Status        string
-	OrderID       string
-	PaymentID     string
-	ReservationID string
-	Message       string
+	Status         string
+	OrderID        string
+	PaymentID      string   // Set once the payment is captured
+	TransactionID  string   // The authorization, set before reservation
+	PaymentStatus  string   // Authorized, then completed, or voided on failure
+	ReservationID  string   // The default warehouse's reservation, or the first
+	ReservationIDs []string `json:",omitempty"` // One reservation per warehouse
+	Message        string
+	Approval       *ApprovalOutcome     `json:",omitempty"` // Set when the order needed approval
+	Compensations  []CompensationResult `json:",omitempty"`
+	DeadLetters    []string             `json:",omitempty"` // Non-critical steps sent to the dead-letter queue
+	History        []domain.Transition  `json:",omitempty"` // The order's status changes
+}
+
+// OrchestrationStatus reports the order status as the orchestration outcome
+func (o OrderProcessingOutput) OrchestrationStatus() string {
+	return o.Status
 }
 
 // OrderProcessingOrchestrator orchestrates the order processing workflow
-func OrderProcessingOrchestrator(ctx *task.OrchestrationContext) (any, error) {
+func OrderProcessingOrchestrator(deps *WorkflowDeps) task.Orchestrator {
+	return func(ctx *task.OrchestrationContext) (any, error) {
+		return processOrder(ctx, deps)
+	}
+}
+
+// processOrder runs the order processing steps, compensating completed steps on failure
+func processOrder(ctx *task.OrchestrationContext, deps *WorkflowDeps) (any, error) {
 	var inp OrderProcessingInput
 	if err := ctx.GetInput(&inp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal order processing input: %w", err)
@@ -36,90 +57,174 @@ func OrderProcessingOrchestrator(ctx *task.OrchestrationContext) (any, error) {
 	order := inp.Order
 	output := OrderProcessingOutput{
 		OrderID: order.ID,
-		Status:  "pending",
+		Status:  string(order.Status),
 	}
-
-	// Step 1: Check inventory availability
-	checkInput := inventory.CheckAvailabilityInput{
-		Items: order.Items,
+	// notifyFailure tells the customer the order failed, dead-lettering the
+	// notification if it cannot be queued
+	notifyFailure := func() {
+		notifyInput := customerNotification(inp, order, "order_failed")
+		if err := deps.callNonCritical(ctx, "notification:order_failure", notifyInput, nil); err != nil {
+			output.DeadLetters = append(output.DeadLetters, "notification:order_failure")
+		}
 	}
-	checkInputBytes, _ := json.Marshal(checkInput)
 
-	checkResult := ctx.CallActivity("inventory:check", task.WithActivityInput(checkInputBytes))
-	var checkOutput inventory.CheckAvailabilityOutput
-	if err := checkResult.Await(&checkOutput); err != nil {
-		output.Status = "failed"
-		output.Message = fmt.Sprintf("inventory check failed: %v", err)
+	// Only pending orders can be processed. Any other order fails before
+	// payment or stock are touched, so there is nothing to compensate.
+	if err := order.ValidateTransition(domain.OrderStatusConfirmed); err != nil {
+		output.Status = string(domain.OrderStatusFailed)
+		output.Message = err.Error()
+		notifyFailure()
 		return output, nil
 	}
 
-	if !checkOutput.Available {
-		output.Status = "failed"
-		output.Message = "items not available"
+	saga := NewSaga(ctx, deps.Metrics, deps.CompensationPolicy)
+
+	// fail moves the order to failed, recording why, compensates completed
+	// steps in reverse order and tells the customer
+	fail := func(format string, args ...interface{}) (any, error) {
+		output.Message = fmt.Sprintf(format, args...)
+		if err := order.MarkFailed(output.Message, ctx.CurrentTimeUtc); err != nil {
+			output.Message = err.Error()
+		}
+		output.Status = string(order.Status)
+		output.History = order.History
+
+		output.Compensations = saga.Compensate()
+		if HasStuckCompensations(output.Compensations) {
+			output.Message += "; some compensations did not complete"
+		}
+		for _, c := range output.Compensations {
+			if c.Activity == "payment:void" && c.Status == CompensationSucceeded {
+				output.PaymentStatus = string(domain.PaymentStatusVoided)
+			}
+		}
+
+		notifyFailure()
 		return output, nil
 	}
 
-	// Step 2: Reserve inventory
-	reserveInput := inventory.ReserveInventoryInput{
-		OrderID: order.ID,
-		Items:   order.Items,
+	// Step 1: Check inventory availability, one SKU per parallel check
+	checkOutput, err := checkAvailability(ctx, deps, order.Items)
+	if err != nil {
+		return fail("inventory check failed: %v", err)
 	}
-	reserveInputBytes, _ := json.Marshal(reserveInput)
 
-	reserveResult := ctx.CallActivity("inventory:reserve", task.WithActivityInput(reserveInputBytes))
-	var reserveOutput inventory.ReserveInventoryOutput
-	if err := reserveResult.Await(&reserveOutput); err != nil {
-		output.Status = "failed"
-		output.Message = fmt.Sprintf("inventory reservation failed: %v", err)
-		return output, nil
+	if !checkOutput.Available {
+		return fail("items not available: %s", strings.Join(checkOutput.UnavailableItems, ", "))
 	}
 
-	output.ReservationID = reserveOutput.ReservationID
-
-	// Step 3: Charge payment
-	chargeInput := payment.ChargePaymentInput{
+	// Step 2: Authorize payment. The hold is voided if a later step fails,
+	// which is cheaper and faster than refunding a capture.
+	authorizeInput := payment.AuthorizePaymentInput{
 		OrderID:       order.ID,
 		Amount:        order.TotalAmount,
 		PaymentMethod: domain.PaymentMethodCard,
 		CustomerID:    order.CustomerID,
 	}
-	chargeInputBytes, _ := json.Marshal(chargeInput)
 
-	chargeResult := ctx.CallActivity("payment:charge", task.WithActivityInput(chargeInputBytes))
-	var chargeOutput payment.ChargePaymentOutput
-	if err := chargeResult.Await(&chargeOutput); err != nil {
-		// Payment failed - compensate by releasing inventory
-		releaseInput := inventory.ReleaseInventoryInput{
-			ReservationID: output.ReservationID,
+	var authorizeOutput payment.AuthorizePaymentOutput
+	if err := deps.callActivity(ctx, "payment:authorize", authorizeInput, &authorizeOutput); err != nil {
+		return fail("payment authorization failed: %v", err)
+	}
+
+	output.TransactionID = authorizeOutput.TransactionID
+	output.PaymentStatus = authorizeOutput.Status
+	saga.AddCompensation("payment:void", payment.VoidPaymentInput{
+		PaymentID:     authorizeOutput.PaymentID,
+		TransactionID: authorizeOutput.TransactionID,
+	})
+
+	// Step 3: Reserve inventory, one parallel reservation per warehouse.
+	// Warehouses that did reserve are released if any other failed.
+	reservationIDs, err := reserveInventory(ctx, deps, order)
+	for _, id := range reservationIDs {
+		saga.AddCompensation("inventory:release", inventory.ReleaseInventoryInput{ReservationID: id})
+	}
+	output.ReservationIDs = reservationIDs
+	if err != nil {
+		return fail("inventory reservation failed: %v", err)
+	}
+	output.ReservationID = reservationIDs[0]
+
+	// Step 4: High-value orders wait for an approver, holding their stock
+	// and the authorization. A rejection or missed deadline releases the
+	// reservations and voids the authorization.
+	if deps.needsApproval(order) {
+		outcome, err := awaitApproval(ctx, deps, order)
+		if err != nil {
+			return fail("approval request failed: %v", err)
+		}
+		output.Approval = &outcome
+		if outcome.Status != domain.ApprovalStatusApproved {
+			return fail("%s", outcome.Reason())
 		}
-		releaseInputBytes, _ := json.Marshal(releaseInput)
-		ctx.CallActivity("inventory:release", task.WithActivityInput(releaseInputBytes)).Await(nil)
+	}
 
-		output.Status = "failed"
-		output.Message = fmt.Sprintf("payment processing failed: %v", err)
-		return output, nil
+	// Step 5: Capture the authorized payment now that every fulfilment check
+	// passed. From here on undoing the payment takes a refund, not a void.
+	captureInput := payment.CapturePaymentInput{
+		PaymentID:     authorizeOutput.PaymentID,
+		TransactionID: authorizeOutput.TransactionID,
+		Amount:        order.TotalAmount,
 	}
 
-	output.PaymentID = chargeOutput.PaymentID
+	var captureOutput payment.CapturePaymentOutput
+	if err := deps.callActivity(ctx, "payment:capture", captureInput, &captureOutput); err != nil {
+		return fail("payment capture failed: %v", err)
+	}
 
-	// Step 4: Send confirmation email
-	emailInput := notification.EmailNotificationInput{
-		CustomerEmail: inp.CustomerEmail,
-		OrderID:       order.ID,
-		EventType:     "order_confirmed",
+	output.PaymentID = captureOutput.PaymentID
+	output.PaymentStatus = captureOutput.Status
+	saga.RemoveCompensation("payment:void")
+	saga.AddCompensation("payment:refund", payment.RefundPaymentInput{
+		PaymentID:     captureOutput.PaymentID,
+		TransactionID: captureOutput.TransactionID,
+		Amount:        order.TotalAmount,
+	})
+
+	// Step 6: Commit the reserved stock so it leaves the warehouse instead of
+	// expiring back into available stock. Warehouses that committed are
+	// restocked if another fails, and the capture is refunded.
+	for _, id := range reservationIDs {
+		saga.AddCompensation("inventory:restock", inventory.RestockInventoryInput{ReservationID: id})
+	}
+	if err := commitInventory(ctx, deps, reservationIDs); err != nil {
+		return fail("inventory commit failed: %v", err)
 	}
-	emailInputBytes, _ := json.Marshal(emailInput)
 
-	emailResult := ctx.CallActivity("notification:order_confirmation", task.WithActivityInput(emailInputBytes))
-	var emailOutput notification.EmailNotificationOutput
-	if err := emailResult.Await(&emailOutput); err != nil {
-		// Email failure is non-critical, log but continue
-		// In production, you might retry or log to a dead letter queue
+	// Confirm the order before telling the customer. Should the transition
+	// fail, the capture is refunded and the stock restocked like any other
+	// failed step.
+	if err := order.MarkConfirmed(captureOutput.PaymentID, output.ReservationID, ctx.CurrentTimeUtc); err != nil {
+		return fail("order confirmation failed: %v", err)
+	}
+
+	// Step 7: Notify the customer
+	notifyInput := customerNotification(inp, order, "order_confirmed")
+	notifyInput.PaymentID = captureOutput.PaymentID
+	if err := deps.callNonCritical(ctx, "notification:order_confirmation", notifyInput, nil); err != nil {
+		// The order stands; the notification waits in the dead-letter queue
+		output.DeadLetters = append(output.DeadLetters, "notification:order_confirmation")
 	}
 
 	// Success!
-	output.Status = "confirmed"
+	output.Status = string(order.Status)
+	output.History = order.History
 	output.Message = "order processed successfully"
 
 	return output, nil
 }
+
+// customerNotification builds the notification of an order event from the
+// order and the customer's contact details
+func customerNotification(inp OrderProcessingInput, order domain.Order, eventType string) notification.NotificationInput {
+	return notification.NotificationInput{
+		CustomerEmail: inp.CustomerEmail,
+		CustomerPhone: inp.CustomerPhone,
+		Locale:        inp.CustomerLocale,
+		OrderID:       order.ID,
+		EventType:     eventType,
+		Items:         order.Items,
+		Total:         order.TotalAmount,
+	}
+}
@@ -0,0 +1,241 @@
+package workflows
+
+import (
+	"fmt"
+
+	"github.com/microsoft/durabletask-go/task"
+	"github.com/shopspring/decimal"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/notification"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/payment"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// Refund outcome statuses reported in OrderRefundOutput
+const (
+	RefundStatusRefunded          = "refunded"
+	RefundStatusPartiallyRefunded = "partially_refunded"
+	RefundStatusCancelled         = "cancelled"
+	RefundStatusRejected          = "rejected"
+	RefundStatusFailed            = "failed"
+)
+
+// OrderRefundInput is the input to the order refund and cancellation orchestrators
+type OrderRefundInput struct {
+	Order         domain.Order
+	Payment       domain.Payment
+	Amount        decimal.Decimal    // Amount to refund; zero refunds everything still captured. Ignored by cancellation.
+	Items         []domain.OrderItem // Returned lines; refunds their line totals and restocks only their quantities
+	CustomerEmail string
+	CustomerPhone string // Optional; enables SMS notifications
+	Locale        string // Optional customer locale, e.g. "fr-CA"; selects notification templates
+	Reason        string
+}
+
+// OrderRefundOutput is the output of the order refund and cancellation orchestrators
+type OrderRefundOutput struct {
+	Status               string
+	OrderID              string
+	OrderStatus          domain.OrderStatus
+	PaymentStatus        domain.PaymentStatus
+	RefundID             string
+	RefundedAmount       decimal.Decimal // Refunded by this orchestration
+	TotalRefunded        decimal.Decimal // Refunded against the payment so far, including this refund
+	ReservationRestocked bool            // The whole reservation was restocked, not just returned lines
+	Message              string
+	History              []domain.Transition `json:",omitempty"` // The order's status changes
+}
+
+// OrchestrationStatus reports the refund status as the orchestration outcome
+func (o OrderRefundOutput) OrchestrationStatus() string {
+	return o.Status
+}
+
+// OrderRefundOrchestrator refunds all or part of a confirmed order's payment
+func OrderRefundOrchestrator(deps *WorkflowDeps) task.Orchestrator {
+	return func(ctx *task.OrchestrationContext) (any, error) {
+		var inp OrderRefundInput
+		if err := ctx.GetInput(&inp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal order refund input: %w", err)
+		}
+		return refundOrder(ctx, deps, inp, false), nil
+	}
+}
+
+// OrderCancellationOrchestrator cancels a confirmed order, refunding its
+// payment in full and restocking its committed inventory
+func OrderCancellationOrchestrator(deps *WorkflowDeps) task.Orchestrator {
+	return func(ctx *task.OrchestrationContext) (any, error) {
+		var inp OrderRefundInput
+		if err := ctx.GetInput(&inp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal order cancellation input: %w", err)
+		}
+		return refundOrder(ctx, deps, inp, true), nil
+	}
+}
+
+// refundOrder verifies and refunds the order's payment. A full refund also
+// restocks the committed inventory and moves the order to refunded, or to
+// cancelled when cancel is set; a partial refund leaves the order confirmed.
+func refundOrder(ctx *task.OrchestrationContext, deps *WorkflowDeps, inp OrderRefundInput, cancel bool) OrderRefundOutput {
+	order, pay := inp.Order, inp.Payment
+	output := OrderRefundOutput{
+		OrderID:       order.ID,
+		OrderStatus:   order.Status,
+		PaymentStatus: pay.Status,
+	}
+	reject := func(format string, args ...interface{}) OrderRefundOutput {
+		output.Status = RefundStatusRejected
+		output.Message = fmt.Sprintf(format, args...)
+		return output
+	}
+
+	// Step 1: Enforce the order and payment state machines
+	target := domain.OrderStatusRefunded
+	if cancel {
+		target = domain.OrderStatusCancelled
+	}
+	if err := order.ValidateTransition(target); err != nil {
+		return reject("%v", err)
+	}
+	if err := pay.ValidateTransition(domain.PaymentStatusRefunded); err != nil {
+		return reject("%v", err)
+	}
+
+	// Step 2: Verify the payment with the gateway and work out the amount
+	verifyInput := payment.VerifyPaymentInput{
+		PaymentID:     pay.ID,
+		TransactionID: pay.TransactionID,
+	}
+
+	var verifyOutput payment.VerifyPaymentOutput
+	if err := deps.callActivity(ctx, "payment:verify", verifyInput, &verifyOutput); err != nil {
+		output.Status = RefundStatusFailed
+		output.Message = fmt.Sprintf("payment verification failed: %v", err)
+		return output
+	}
+
+	// The gateway is the source of truth for the payment's status, what was
+	// captured and what has already been refunded, whatever the caller's
+	// copy of the payment says
+	if status := domain.PaymentStatus(verifyOutput.Status); status != domain.PaymentStatusCompleted {
+		return reject("payment %s is %s at the gateway", pay.ID, status)
+	}
+	pay.Amount = verifyOutput.Amount
+	pay.RefundedAmount = verifyOutput.RefundedAmount
+
+	amount := inp.Amount
+	switch {
+	case cancel:
+		amount = pay.RefundableAmount()
+	case len(inp.Items) > 0:
+		if !amount.IsZero() {
+			return reject("refund amount and returned items are mutually exclusive")
+		}
+		total, err := order.ReturnTotal(inp.Items)
+		if err != nil {
+			return reject("invalid return: %v", err)
+		}
+		// The committed reservation records what is still with the customer,
+		// so a line returned by an earlier refund is rejected here
+		if order.ReservationID != "" {
+			if err := checkReturn(ctx, deps, order, inp.Items); err != nil {
+				if errors.ClassifyError(err) == errors.ErrorTypePermanent {
+					return reject("invalid return: %v", err)
+				}
+				output.Status = RefundStatusFailed
+				output.Message = fmt.Sprintf("return check failed: %v", err)
+				return output
+			}
+		}
+		amount = total
+	case amount.IsZero():
+		amount = pay.RefundableAmount()
+	}
+
+	// Apply the refund to a copy first so an over-refund is rejected before
+	// any money moves
+	check := pay
+	if err := check.ApplyRefund(amount, ctx.CurrentTimeUtc); err != nil {
+		return reject("%v", err)
+	}
+	full := check.Status == domain.PaymentStatusRefunded
+
+	// Step 3: Refund the payment
+	refundInput := payment.RefundPaymentInput{
+		PaymentID:     pay.ID,
+		TransactionID: pay.TransactionID,
+		Amount:        amount,
+	}
+
+	var refundOutput payment.RefundPaymentOutput
+	if err := deps.callActivity(ctx, "payment:refund", refundInput, &refundOutput); err != nil {
+		output.Status = RefundStatusFailed
+		output.Message = fmt.Sprintf("refund failed: %v", err)
+		return output
+	}
+	pay = check
+	output.RefundID = refundOutput.RefundID
+	output.RefundedAmount = amount
+	output.TotalRefunded = pay.RefundedAmount
+
+	// Step 4: Returned lines go back to stock, as does everything once the
+	// order is fully refunded. The refund has already happened, so a failed
+	// restock is reported rather than failing the orchestration.
+	if (full || len(inp.Items) > 0) && order.ReservationID != "" {
+		var returned []domain.OrderItem
+		if !cancel && !full {
+			returned = inp.Items
+		}
+		if err := restockInventory(ctx, deps, order, returned); err != nil {
+			output.Message = fmt.Sprintf("inventory restock failed: %v", err)
+		} else {
+			output.ReservationRestocked = full
+		}
+	}
+
+	// Both transitions were validated in step 1
+	switch {
+	case full && cancel:
+		order.MarkCancelled(inp.Reason, ctx.CurrentTimeUtc)
+		output.Status = RefundStatusCancelled
+	case full:
+		order.MarkRefunded(ctx.CurrentTimeUtc)
+		output.Status = RefundStatusRefunded
+	default:
+		output.Status = RefundStatusPartiallyRefunded
+	}
+	output.OrderStatus = order.Status
+	output.PaymentStatus = pay.Status
+	output.History = order.History
+
+	// Step 5: Notify the customer. The outbox keeps one email per order and
+	// event type, so each partial refund gets its own event type.
+	eventType := "refund_issued"
+	if cancel {
+		eventType = "order_cancelled"
+	} else if output.RefundID != "" {
+		eventType = "refund_issued:" + output.RefundID
+	}
+	notifyInput := notification.NotificationInput{
+		CustomerEmail: inp.CustomerEmail,
+		CustomerPhone: inp.CustomerPhone,
+		Locale:        inp.Locale,
+		OrderID:       order.ID,
+		EventType:     eventType,
+		Items:         inp.Items,
+		Total:         order.TotalAmount,
+		PaymentID:     pay.ID,
+		RefundID:      output.RefundID,
+		Amount:        amount,
+		Reason:        inp.Reason,
+	}
+	// Notification failure is non-critical once the money is back; the
+	// notification waits in the dead-letter queue instead
+	_ = deps.callNonCritical(ctx, "notification:refund", notifyInput, nil)
+
+	if output.Message == "" {
+		output.Message = fmt.Sprintf("refunded %s", amount)
+	}
+	return output
+}
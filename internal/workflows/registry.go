@@ -1,14 +1,38 @@
 package workflows
 
 import (
+	"time"
+
 	"github.com/microsoft/durabletask-go/task"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/trace"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
 )
 
+// WorkflowDeps contains dependencies for all workflow orchestrators
+type WorkflowDeps struct {
+	Logger             *observability.Logger
+	Metrics            *observability.Metrics
+	RetryPolicies      RetryPolicies
+	CompensationPolicy RetryPolicy
+	Tracer             trace.Tracer    // Records orchestration spans; nil uses the global provider
+	MaxParallelism     int             // Caps per-SKU checks and per-warehouse reservations; 0 means no cap
+	ApprovalThreshold  decimal.Decimal // Orders above this total wait for approval; zero disables
+	ApprovalTimeout    time.Duration   // How long an order waits for an approval decision
+}
+
 // NewWorkflowRegistry creates and registers all workflow orchestrators
-func NewWorkflowRegistry() *task.TaskRegistry {
+func NewWorkflowRegistry(deps *WorkflowDeps) *task.TaskRegistry {
 	registry := task.NewTaskRegistry()
+	if deps.Tracer == nil {
+		deps.Tracer = observability.GetTracer(observability.TracerName)
+	}
 
-	registry.AddOrchestratorN("order_processing", OrderProcessingOrchestrator)
+	registry.AddOrchestratorN("order_processing", traced(deps.Tracer, "order_processing", OrderProcessingOrchestrator(deps)))
+	registry.AddOrchestratorN("order_refund", traced(deps.Tracer, "order_refund", OrderRefundOrchestrator(deps)))
+	registry.AddOrchestratorN("order_cancellation", traced(deps.Tracer, "order_cancellation", OrderCancellationOrchestrator(deps)))
+	registry.AddOrchestratorN("batch", traced(deps.Tracer, "batch", BatchOrchestrator(deps)))
+	registry.AddOrchestratorN("dead_letter_replay", traced(deps.Tracer, "dead_letter_replay", DeadLetterReplayOrchestrator(deps)))
 
 	return registry
 }
@@ -0,0 +1,213 @@
+package workflows
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/microsoft/durabletask-go/task"
+
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/config"
+	"github.com/Youmanvi/taskorchestrator/internal/middleware"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+// RetryPolicy defines a durable, orchestration-level retry strategy. Waits
+// between attempts use orchestration timers, so the schedule survives worker
+// restarts and does not hold an activity worker slot.
+type RetryPolicy struct {
+	MaxAttempts       int           // Maximum number of attempts, including the first
+	InitialBackoff    time.Duration // Delay before the second attempt
+	MaxBackoff        time.Duration // Upper bound for any delay
+	BackoffMultiplier float64       // Exponential backoff multiplier
+	Jitter            float64       // Fraction (0-1) of each delay that is randomized
+	RetryableCodes    []string      // CustomError codes to retry; empty retries every non-permanent failure
+}
+
+// DefaultRetryPolicy returns a sensible default retry policy
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:       3,
+		InitialBackoff:    100 * time.Millisecond,
+		MaxBackoff:        30 * time.Second,
+		BackoffMultiplier: 2.0,
+		Jitter:            0.2,
+	}
+}
+
+// IsRetryable reports whether a failed attempt should be retried. Permanent
+// failures never are. Transient and timeout failures, including unclassified
+// ones (see errors.ClassifyError), are retried when RetryableCodes is empty or
+// lists their code.
+func (p RetryPolicy) IsRetryable(err error) bool {
+	if errors.ClassifyError(err) == errors.ErrorTypePermanent {
+		return false
+	}
+	if len(p.RetryableCodes) == 0 {
+		return true
+	}
+	code := errors.CodeOf(err)
+	for _, c := range p.RetryableCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the delay before the given retry (1 = first retry). The
+// jitter is derived from seed so replays compute the same delay.
+func (p RetryPolicy) Backoff(retry int, seed string) time.Duration {
+	multiplier := p.BackoffMultiplier
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%s/%d", seed, retry)
+		fraction := float64(h.Sum64()%10000) / 10000 // [0, 1)
+		backoff -= backoff * p.Jitter * fraction
+	}
+
+	return time.Duration(backoff)
+}
+
+// RetryPolicies holds the default retry policy and per-activity overrides.
+// Activities keys are exact activity names or "prefix:*" wildcards.
+type RetryPolicies struct {
+	Default    RetryPolicy
+	Activities map[string]RetryPolicy
+}
+
+// RetryPoliciesFromConfig derives retry policies from the activities
+// configuration so orchestrations and the activity registry share one source
+func RetryPoliciesFromConfig(cfg config.ActivitiesConfig) RetryPolicies {
+	policies := RetryPolicies{
+		Default:    retryPolicyFromConfig(cfg.PolicyFor("")),
+		Activities: make(map[string]RetryPolicy, len(cfg.Overrides)),
+	}
+	for pattern := range cfg.Overrides {
+		// Resolving the pattern itself merges any less specific wildcards
+		policies.Activities[pattern] = retryPolicyFromConfig(cfg.PolicyFor(pattern))
+	}
+	return policies
+}
+
+// retryPolicyFromConfig converts a resolved activity policy into a RetryPolicy
+func retryPolicyFromConfig(p config.ActivityPolicy) RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = p.RetryMaxAttempts
+	if p.RetryBackoff > 0 {
+		policy.InitialBackoff = p.RetryBackoff
+	}
+	if p.RetryMaxBackoff > 0 {
+		policy.MaxBackoff = p.RetryMaxBackoff
+	}
+	policy.RetryableCodes = p.RetryableCodes
+	return policy
+}
+
+// For returns the retry policy for an activity: an exact match wins, then the
+// longest matching wildcard, then the default
+func (p RetryPolicies) For(activity string) RetryPolicy {
+	if policy, ok := p.Activities[activity]; ok {
+		return policy
+	}
+
+	best, found := "", false
+	for pattern := range p.Activities {
+		if strings.HasSuffix(pattern, "*") && config.MatchesActivity(pattern, activity) && len(pattern) > len(best) {
+			best, found = pattern, true
+		}
+	}
+	if found {
+		return p.Activities[best]
+	}
+	return p.Default
+}
+
+// CallActivityWithRetry calls an activity, retrying failures according to the
+// policy with durable timers. It returns the number of attempts made.
+func CallActivityWithRetry(ctx *task.OrchestrationContext, policy RetryPolicy, activity string, input interface{}, output interface{}) (int, error) {
+	inputBytes, err := marshalInput(input)
+	if err != nil {
+		return 0, err
+	}
+
+	stepKey := StepKey(activity, inputBytes)
+
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		lastErr = callAttempt(ctx, activity, stepKey, attempt, inputBytes).Await(output)
+		if lastErr == nil {
+			return attempt, nil
+		}
+
+		if !policy.IsRetryable(lastErr) || attempt == maxAttempts {
+			return attempt, lastErr
+		}
+
+		delay := policy.Backoff(attempt, fmt.Sprintf("%s/%s", ctx.ID, activity))
+		if delay > 0 {
+			if err := ctx.CreateTimer(delay).Await(nil); err != nil {
+				return attempt, fmt.Errorf("retry timer for %s failed: %w", activity, err)
+			}
+		}
+	}
+
+	return maxAttempts, lastErr
+}
+
+// callAttempt schedules one attempt of an activity call, wrapping its input
+// in the invocation envelope the activity registry expects
+func callAttempt(ctx *task.OrchestrationContext, activity, stepKey string, attempt int, input []byte) task.Task {
+	inv := middleware.Invocation{
+		InstanceID:  string(ctx.ID),
+		StepKey:     stepKey,
+		Attempt:     attempt,
+		TraceParent: orchestrationTraceParent(ctx),
+		Input:       input,
+	}
+	return ctx.CallActivity(activity, task.WithActivityInput(inv))
+}
+
+// StepKey derives a deterministic key for an activity call from its name and
+// input, so every attempt and re-execution of the same step shares one
+// idempotency key while calls with different inputs get distinct keys
+func StepKey(activity string, input []byte) string {
+	h := fnv.New64a()
+	h.Write(input)
+	return fmt.Sprintf("%s#%016x", activity, h.Sum64())
+}
+
+// callActivity calls an activity using the retry policy configured for it
+func (d *WorkflowDeps) callActivity(ctx *task.OrchestrationContext, activity string, input interface{}, output interface{}) error {
+	_, err := CallActivityWithRetry(ctx, d.RetryPolicies.For(activity), activity, input, output)
+	return err
+}
+
+// marshalInput serializes activity input, passing pre-serialized bytes through
+func marshalInput(input interface{}) ([]byte, error) {
+	if b, ok := input.([]byte); ok {
+		return b, nil
+	}
+	b, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal activity input: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,250 @@
+package workflows
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/microsoft/durabletask-go/api"
+	"github.com/microsoft/durabletask-go/backend"
+	"github.com/microsoft/durabletask-go/backend/sqlite"
+	"github.com/microsoft/durabletask-go/task"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/config"
+	"github.com/Youmanvi/taskorchestrator/internal/middleware"
+	"github.com/Youmanvi/taskorchestrator/internal/pkg/errors"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts:       5,
+		InitialBackoff:    100 * time.Millisecond,
+		MaxBackoff:        1 * time.Second,
+		BackoffMultiplier: 2.0,
+	}
+
+	assert.Equal(t, 100*time.Millisecond, policy.Backoff(1, "ORD-1/payment:charge"))
+	assert.Equal(t, 200*time.Millisecond, policy.Backoff(2, "ORD-1/payment:charge"))
+	assert.Equal(t, 400*time.Millisecond, policy.Backoff(3, "ORD-1/payment:charge"))
+	assert.Equal(t, 1*time.Second, policy.Backoff(10, "ORD-1/payment:charge"), "backoff should be capped")
+}
+
+func TestRetryPolicy_JitterIsDeterministic(t *testing.T) {
+	policy := DefaultRetryPolicy()
+	policy.Jitter = 0.5
+
+	first := policy.Backoff(2, "ORD-1/payment:charge")
+	second := policy.Backoff(2, "ORD-1/payment:charge")
+	assert.Equal(t, first, second, "replays must compute the same delay")
+
+	undelayed := policy.InitialBackoff * 2
+	assert.LessOrEqual(t, first, undelayed)
+	assert.GreaterOrEqual(t, first, undelayed/2)
+}
+
+func TestRetryPolicy_IsRetryable(t *testing.T) {
+	policy := RetryPolicy{RetryableCodes: []string{"PAYMENT_GATEWAY_UNAVAILABLE", "ACTIVITY_TIMEOUT"}}
+
+	assert.True(t, policy.IsRetryable(errors.NewTransientError("PAYMENT_GATEWAY_UNAVAILABLE", "gateway down", nil)))
+	assert.True(t, policy.IsRetryable(errors.NewTimeoutError("ACTIVITY_TIMEOUT", "timed out")))
+	assert.False(t, policy.IsRetryable(errors.NewTransientError("INVALID_INPUT", "bad input", nil)))
+	assert.False(t, policy.IsRetryable(fmt.Errorf("no code here")))
+
+	empty := RetryPolicy{}
+	assert.True(t, empty.IsRetryable(errors.NewTransientError("ANYTHING", "flaky", nil)))
+	assert.True(t, empty.IsRetryable(errors.NewTimeoutError("ACTIVITY_TIMEOUT", "timed out")))
+	assert.True(t, empty.IsRetryable(fmt.Errorf("database is locked")), "unclassified failures are retried")
+	assert.False(t, empty.IsRetryable(errors.NewPermanentError("PAYMENT_DECLINED", "declined", nil)))
+
+	// Permanent failures are never retried, even when their code is listed
+	listed := RetryPolicy{RetryableCodes: []string{"INSUFFICIENT_STOCK"}}
+	assert.False(t, listed.IsRetryable(errors.NewPermanentError("INSUFFICIENT_STOCK", "out of stock", nil)))
+}
+
+func TestRetryPolicy_TypeFromActivityError(t *testing.T) {
+	// The activity registry annotates errors with their type before they
+	// reach the orchestrator as plain messages
+	permanent := errors.Annotate(errors.NewPermanentError("NO_RECIPIENT", "no address", nil))
+	remoteErr := fmt.Errorf("task failed with an error: %s", permanent.Error())
+
+	errType, ok := errors.TypeOf(remoteErr)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrorTypePermanent, errType)
+	assert.Equal(t, "NO_RECIPIENT", errors.CodeOf(remoteErr))
+	assert.False(t, RetryPolicy{}.IsRetryable(remoteErr))
+
+	timeout := errors.Annotate(errors.NewTimeoutError("ACTIVITY_TIMEOUT", "timed out"))
+	errType, ok = errors.TypeOf(fmt.Errorf("task failed with an error: %s", timeout.Error()))
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrorTypeTimeout, errType)
+
+	_, ok = errors.TypeOf(fmt.Errorf("task failed with an error: database is locked"))
+	assert.False(t, ok)
+}
+
+func TestCallActivityWithRetry_StopsOnPermanentError(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond, BackoffMultiplier: 1}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"permanent", errors.NewPermanentError("INVALID_INPUT", "bad input", nil), 1},
+		{"transient", errors.NewTransientError("PAYMENT_GATEWAY_UNAVAILABLE", "gateway down", nil), 3},
+		{"timeout", errors.NewTimeoutError("ACTIVITY_TIMEOUT", "timed out"), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts, calls := runFailingActivity(t, policy, tt.err)
+			assert.Equal(t, tt.want, attempts)
+			assert.Equal(t, int32(tt.want), calls)
+		})
+	}
+}
+
+// runFailingActivity runs CallActivityWithRetry on an in-memory task hub for
+// an activity that always fails with err, annotated the way the activity
+// registry does. It returns the attempts reported and the activity calls seen.
+func runFailingActivity(t *testing.T, policy RetryPolicy, err error) (int, int32) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var calls atomic.Int32
+	activities := task.NewTaskRegistry()
+	require.NoError(t, activities.AddActivityN("test:fail", func(ctx task.ActivityContext) (any, error) {
+		calls.Add(1)
+		return nil, errors.Annotate(err)
+	}))
+	orchestrations := task.NewTaskRegistry()
+	require.NoError(t, orchestrations.AddOrchestratorN("retry", func(ctx *task.OrchestrationContext) (any, error) {
+		attempts, _ := CallActivityWithRetry(ctx, policy, "test:fail", "input", nil)
+		return attempts, nil
+	}))
+
+	var attempts int
+	runOrchestrator(t, ctx, orchestrations, activities, "retry", &attempts)
+	return attempts, calls.Load()
+}
+
+// runOrchestrator runs the named orchestrator to completion on an in-memory
+// task hub and decodes its output into output
+func runOrchestrator(t *testing.T, ctx context.Context, orchestrations, activities *task.TaskRegistry, name string, output any) {
+	logger := backend.DefaultLogger()
+	be := sqlite.NewSqliteBackend(sqlite.NewSqliteOptions(""), logger)
+	worker := backend.NewTaskHubWorker(be,
+		backend.NewOrchestrationWorker(be, task.NewTaskExecutor(orchestrations), logger),
+		backend.NewActivityTaskWorker(be, task.NewTaskExecutor(activities), logger),
+		logger,
+	)
+	require.NoError(t, worker.Start(ctx))
+	defer worker.Shutdown(context.Background())
+
+	client := backend.NewTaskHubClient(be)
+	id, err := client.ScheduleNewOrchestration(ctx, name)
+	require.NoError(t, err)
+	metadata, err := client.WaitForOrchestrationCompletion(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, api.RUNTIME_STATUS_COMPLETED, metadata.RuntimeStatus)
+	require.NoError(t, json.Unmarshal([]byte(metadata.SerializedOutput), output))
+}
+
+func TestRetryPolicy_CodeFromActivityError(t *testing.T) {
+	// Activity errors reach the orchestrator as plain messages
+	customErr := errors.NewTransientError("PAYMENT_GATEWAY_UNAVAILABLE", "gateway down", fmt.Errorf("timeout"))
+	remoteErr := fmt.Errorf("task failed: %s", customErr.Error())
+
+	assert.Equal(t, "PAYMENT_GATEWAY_UNAVAILABLE", errors.CodeOf(customErr))
+	assert.Equal(t, "PAYMENT_GATEWAY_UNAVAILABLE", errors.CodeOf(remoteErr))
+	assert.Equal(t, "", errors.CodeOf(fmt.Errorf("no code here")))
+	assert.Equal(t, "", errors.CodeOf(nil))
+}
+
+func TestRetryPolicies_For(t *testing.T) {
+	policies := RetryPolicies{
+		Default: RetryPolicy{MaxAttempts: 3},
+		Activities: map[string]RetryPolicy{
+			"notification:order_confirmation": {MaxAttempts: 10},
+		},
+	}
+
+	assert.Equal(t, 10, policies.For("notification:order_confirmation").MaxAttempts)
+	assert.Equal(t, 3, policies.For("payment:charge").MaxAttempts)
+}
+
+func TestRetryPoliciesFromConfig(t *testing.T) {
+	cfg := config.ActivitiesConfig{
+		RetryMaxAttempts: 3,
+		RetryBackoffMs:   100,
+		Overrides: map[string]config.ActivityOverride{
+			"notification:*":      {RetryMaxAttempts: 10, RetryBackoffMs: 1000},
+			"notification:refund": {RetryMaxAttempts: 2},
+			"payment:charge":      {RetryableCodes: []string{"PAYMENT_GATEWAY_UNAVAILABLE"}},
+		},
+	}
+	policies := RetryPoliciesFromConfig(cfg)
+
+	assert.Equal(t, 3, policies.For("inventory:reserve").MaxAttempts)
+	assert.Equal(t, 10, policies.For("notification:order_failure").MaxAttempts)
+	assert.Equal(t, time.Second, policies.For("notification:order_failure").InitialBackoff)
+
+	// Exact overrides inherit fields from matching wildcards
+	refund := policies.For("notification:refund")
+	assert.Equal(t, 2, refund.MaxAttempts)
+	assert.Equal(t, time.Second, refund.InitialBackoff)
+
+	charge := policies.For("payment:charge")
+	assert.Equal(t, 3, charge.MaxAttempts)
+	assert.True(t, charge.IsRetryable(errors.NewTransientError("PAYMENT_GATEWAY_UNAVAILABLE", "gateway down", nil)))
+	assert.False(t, charge.IsRetryable(errors.NewTransientError("PAYMENT_AUTHORIZATION_FAILED", "failed", nil)))
+}
+
+func TestStepKey(t *testing.T) {
+	a := StepKey("inventory:reserve", []byte(`{"OrderID":"ORD-1"}`))
+	assert.Equal(t, a, StepKey("inventory:reserve", []byte(`{"OrderID":"ORD-1"}`)))
+	assert.NotEqual(t, a, StepKey("inventory:reserve", []byte(`{"OrderID":"ORD-2"}`)))
+	assert.NotEqual(t, a, StepKey("inventory:release", []byte(`{"OrderID":"ORD-1"}`)))
+}
+
+func TestFanOut_IdenticalInputsGetDistinctStepKeys(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	activities := task.NewTaskRegistry()
+	require.NoError(t, activities.AddActivityN("test:step_key", func(ctx task.ActivityContext) (any, error) {
+		var inv middleware.Invocation
+		if err := ctx.GetInput(&inv); err != nil {
+			return nil, err
+		}
+		return inv.StepKey, nil
+	}))
+	orchestrations := task.NewTaskRegistry()
+	require.NoError(t, orchestrations.AddOrchestratorN("fan_out", func(ctx *task.OrchestrationContext) (any, error) {
+		calls := []ActivityCall{
+			{Activity: "test:step_key", Input: "same"},
+			{Activity: "test:step_key", Input: "same"},
+		}
+		var keys []string
+		for _, result := range FanOut(ctx, RetryPolicies{Default: RetryPolicy{MaxAttempts: 1}}, calls, 0) {
+			if result.Err != nil {
+				return nil, result.Err
+			}
+			var key string
+			if err := json.Unmarshal(result.Output, &key); err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+		return keys, nil
+	}))
+
+	var keys []string
+	runOrchestrator(t, ctx, orchestrations, activities, "fan_out", &keys)
+	require.Len(t, keys, 2)
+	assert.NotEqual(t, keys[0], keys[1], "identical inputs must not share an idempotency key")
+	assert.Equal(t, StepKey("test:step_key", []byte(`"same"`))+"/0", keys[0])
+}
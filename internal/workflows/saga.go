@@ -0,0 +1,130 @@
+package workflows
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/microsoft/durabletask-go/task"
+
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+)
+
+// Compensation outcome statuses reported in CompensationResult
+const (
+	CompensationSucceeded = "succeeded"
+	CompensationStuck     = "stuck"
+)
+
+// DefaultCompensationPolicy returns the retry policy used for compensations.
+// Compensations must eventually succeed, so they retry longer than forward steps.
+func DefaultCompensationPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:       5,
+		InitialBackoff:    1 * time.Second,
+		MaxBackoff:        1 * time.Minute,
+		BackoffMultiplier: 2.0,
+		Jitter:            0.2,
+	}
+}
+
+// CompensationResult reports the outcome of a single compensating activity
+type CompensationResult struct {
+	Activity string
+	Status   string
+	Attempts int
+	Error    string `json:",omitempty"`
+}
+
+// compensation is a registered compensating activity call
+type compensation struct {
+	activity string
+	input    []byte
+}
+
+// Saga tracks compensating activities for completed steps and runs them in
+// reverse order when a later step fails
+type Saga struct {
+	ctx           *task.OrchestrationContext
+	metrics       *observability.Metrics
+	policy        RetryPolicy
+	compensations []compensation
+}
+
+// NewSaga creates a saga bound to an orchestration context
+func NewSaga(ctx *task.OrchestrationContext, metrics *observability.Metrics, policy RetryPolicy) *Saga {
+	return &Saga{
+		ctx:     ctx,
+		metrics: metrics,
+		policy:  policy,
+	}
+}
+
+// AddCompensation registers the activity that undoes a step that just succeeded
+func (s *Saga) AddCompensation(activity string, input interface{}) {
+	inputBytes, _ := json.Marshal(input)
+	s.compensations = append(s.compensations, compensation{
+		activity: activity,
+		input:    inputBytes,
+	})
+}
+
+// RemoveCompensation drops the most recently registered compensation for
+// activity, for a step whose effect a later step has superseded
+func (s *Saga) RemoveCompensation(activity string) {
+	for i := len(s.compensations) - 1; i >= 0; i-- {
+		if s.compensations[i].activity == activity {
+			s.compensations = append(s.compensations[:i], s.compensations[i+1:]...)
+			return
+		}
+	}
+}
+
+// Compensate runs all registered compensations in reverse registration order.
+// A compensation that still fails after the policy's MaxAttempts is reported
+// as stuck and the remaining compensations continue to run.
+func (s *Saga) Compensate() []CompensationResult {
+	results := make([]CompensationResult, 0, len(s.compensations))
+
+	for i := len(s.compensations) - 1; i >= 0; i-- {
+		results = append(results, s.run(s.compensations[i]))
+	}
+
+	s.compensations = nil
+	return results
+}
+
+// run executes a single compensation with durable retries between attempts
+func (s *Saga) run(c compensation) CompensationResult {
+	start := s.ctx.CurrentTimeUtc
+	attempts, err := CallActivityWithRetry(s.ctx, s.policy, c.activity, c.input, nil)
+	s.record(s.ctx.CurrentTimeUtc.Sub(start), err)
+
+	result := CompensationResult{
+		Activity: c.activity,
+		Attempts: attempts,
+		Status:   CompensationSucceeded,
+	}
+	if err != nil {
+		result.Status = CompensationStuck
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// record reports compensation metrics once, skipping replayed executions
+func (s *Saga) record(duration time.Duration, err error) {
+	if s.metrics == nil || s.ctx.IsReplaying {
+		return
+	}
+	s.metrics.RecordCompensation(duration, err)
+}
+
+// HasStuckCompensations reports whether any compensation did not succeed
+func HasStuckCompensations(results []CompensationResult) bool {
+	for _, r := range results {
+		if r.Status != CompensationSucceeded {
+			return true
+		}
+	}
+	return false
+}
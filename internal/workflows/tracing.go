@@ -0,0 +1,73 @@
+package workflows
+
+import (
+	"context"
+	"time"
+
+	"github.com/microsoft/durabletask-go/task"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+	"github.com/Youmanvi/taskorchestrator/internal/middleware"
+)
+
+// statusReporter is implemented by orchestration outputs that report a
+// business-level status alongside a nil error
+type statusReporter interface {
+	OrchestrationStatus() string
+}
+
+// traced wraps an orchestrator so each orchestration is recorded as one span.
+//
+// Orchestrator code is replayed, so a span cannot be held open across
+// episodes. Instead the span is recorded once, when the orchestration returns
+// outside of replay, starting at the time of the first episode. Its IDs come
+// from observability.OrchestrationSpanContext, which is also the traceparent
+// sent to every activity, so activity spans recorded earlier nest under it.
+func traced(tracer trace.Tracer, name string, orchestrator task.Orchestrator) task.Orchestrator {
+	return func(ctx *task.OrchestrationContext) (any, error) {
+		start := ctx.CurrentTimeUtc
+
+		output, err := orchestrator(ctx)
+
+		if !ctx.IsReplaying {
+			recordOrchestrationSpan(tracer, string(ctx.ID), name, start, ctx.CurrentTimeUtc, output, err)
+		}
+		return output, err
+	}
+}
+
+// recordOrchestrationSpan records the span of a completed orchestration
+func recordOrchestrationSpan(tracer trace.Tracer, instanceID, name string, start, end time.Time, output any, err error) {
+	spanCtx := observability.ContextWithSpanIDs(context.Background(), observability.OrchestrationSpanContext(instanceID))
+
+	_, span := tracer.Start(spanCtx, "orchestration:"+name,
+		trace.WithNewRoot(),
+		trace.WithTimestamp(start),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String(observability.AttrOrchestrationName, name),
+			attribute.String(observability.AttrOrchestrationID, instanceID),
+		),
+	)
+
+	switch {
+	case err != nil:
+		middleware.RecordSpanError(span, err)
+	default:
+		if r, ok := output.(statusReporter); ok {
+			span.SetAttributes(attribute.String(observability.AttrOrchestrationStatus, r.OrchestrationStatus()))
+		}
+		span.SetStatus(codes.Ok, "")
+	}
+
+	span.End(trace.WithTimestamp(end))
+}
+
+// orchestrationTraceParent returns the traceparent of the orchestration span
+// that activities scheduled by ctx run under
+func orchestrationTraceParent(ctx *task.OrchestrationContext) string {
+	return middleware.FormatTraceParent(observability.OrchestrationSpanContext(string(ctx.ID)))
+}
@@ -0,0 +1,321 @@
+package fakes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ProviderPayment is a payment held by a PaymentProvider
+type ProviderPayment struct {
+	ID         string          `json:"id"`
+	OrderID    string          `json:"order_id,omitempty"`
+	CustomerID string          `json:"customer_id,omitempty"`
+	Method     string          `json:"method,omitempty"`
+	Status     string          `json:"status"` // "authorized", "captured", "refunded" or "voided"
+	Currency   string          `json:"currency"`
+	Authorized decimal.Decimal `json:"authorized"`
+	Captured   decimal.Decimal `json:"captured"`
+	Refunded   decimal.Decimal `json:"refunded"`
+}
+
+// providerFault replaces or delays the reply to one request
+type providerFault struct {
+	status int
+	code   string
+	delay  time.Duration
+}
+
+// idempotentReply is a recorded reply to a request with an Idempotency-Key
+type idempotentReply struct {
+	request []byte // Method, path and body of the original request
+	status  int
+	body    []byte
+}
+
+// PaymentProvider is a fake REST payment provider served by httptest. It
+// speaks the protocol of payment.HTTPGateway, replays the recorded reply to
+// a POST repeated with the same Idempotency-Key, and rejects a key reused
+// with a different request with 409. Faults injected with DeclineNext,
+// FailNext and DelayNext apply to the next requests in order.
+type PaymentProvider struct {
+	server *httptest.Server
+	apiKey string
+
+	mu          sync.Mutex
+	payments    map[string]*ProviderPayment
+	replies     map[string]idempotentReply
+	faults      []providerFault
+	requests    int
+	refundCount int
+}
+
+// NewPaymentProvider starts a fake provider; requests must carry apiKey as
+// a bearer token unless it is empty
+func NewPaymentProvider(apiKey string) *PaymentProvider {
+	p := &PaymentProvider{
+		apiKey:   apiKey,
+		payments: make(map[string]*ProviderPayment),
+		replies:  make(map[string]idempotentReply),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/payments", p.createPayment)
+	mux.HandleFunc("POST /v1/payments/{id}/capture", p.capture)
+	mux.HandleFunc("POST /v1/payments/{id}/void", p.void)
+	mux.HandleFunc("POST /v1/payments/{id}/refunds", p.refund)
+	mux.HandleFunc("GET /v1/payments/{id}", p.get)
+	p.server = httptest.NewServer(p.middleware(mux))
+	return p
+}
+
+// URL returns the provider's base URL
+func (p *PaymentProvider) URL() string {
+	return p.server.URL
+}
+
+// Close stops the provider
+func (p *PaymentProvider) Close() {
+	p.server.Close()
+}
+
+// DeclineNext answers the next request with 402 card_declined
+func (p *PaymentProvider) DeclineNext() {
+	p.addFault(providerFault{status: http.StatusPaymentRequired, code: "card_declined"})
+}
+
+// FailNext answers the next request with status and does not process it
+func (p *PaymentProvider) FailNext(status int) {
+	p.addFault(providerFault{status: status, code: "provider_error"})
+}
+
+// DelayNext processes the next request normally but only replies after
+// delay, so a client with a shorter timeout gives up on a request that
+// succeeded
+func (p *PaymentProvider) DelayNext(delay time.Duration) {
+	p.addFault(providerFault{delay: delay})
+}
+
+// Payments returns every payment created so far
+func (p *PaymentProvider) Payments() []ProviderPayment {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	payments := make([]ProviderPayment, 0, len(p.payments))
+	for i := 1; i <= len(p.payments); i++ {
+		payments = append(payments, *p.payments[paymentID(i)])
+	}
+	return payments
+}
+
+// Requests returns how many requests the provider has received
+func (p *PaymentProvider) Requests() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests
+}
+
+func (p *PaymentProvider) addFault(f providerFault) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.faults = append(p.faults, f)
+}
+
+func paymentID(n int) string {
+	return fmt.Sprintf("pay_%d", n)
+}
+
+// middleware authenticates requests, applies faults and replays idempotent
+// replies
+func (p *PaymentProvider) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p.apiKey != "" && r.Header.Get("Authorization") != "Bearer "+p.apiKey {
+			writeProviderError(w, http.StatusUnauthorized, "unauthorized", "invalid API key")
+			return
+		}
+
+		p.mu.Lock()
+		p.requests++
+		var fault providerFault
+		if len(p.faults) > 0 {
+			fault = p.faults[0]
+			p.faults = p.faults[1:]
+		}
+		p.mu.Unlock()
+
+		if fault.status != 0 {
+			writeProviderError(w, fault.status, fault.code, http.StatusText(fault.status))
+			return
+		}
+		if fault.delay > 0 {
+			time.Sleep(fault.delay)
+		}
+
+		// Handlers run one at a time under p.mu
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		key := r.Header.Get("Idempotency-Key")
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		request := append([]byte(r.Method+" "+r.URL.Path+"\n"), body...)
+		if reply, ok := p.replies[key]; ok {
+			if !bytes.Equal(reply.request, request) {
+				writeProviderError(w, http.StatusConflict, "idempotency_conflict", "idempotency key reused with a different request")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(reply.status)
+			w.Write(reply.body)
+			return
+		}
+
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, r)
+		if rec.Code < 500 {
+			p.replies[key] = idempotentReply{request: request, status: rec.Code, body: rec.Body.Bytes()}
+		}
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	})
+}
+
+func (p *PaymentProvider) createPayment(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		OrderID    string          `json:"order_id"`
+		CustomerID string          `json:"customer_id"`
+		Amount     decimal.Decimal `json:"amount"`
+		Currency   string          `json:"currency"`
+		Method     string          `json:"method"`
+		Capture    bool            `json:"capture"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProviderError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if !req.Amount.IsPositive() || req.Currency == "" {
+		writeProviderError(w, http.StatusBadRequest, "invalid_request", "amount and currency are required")
+		return
+	}
+
+	payment := &ProviderPayment{
+		ID:         paymentID(len(p.payments) + 1),
+		OrderID:    req.OrderID,
+		CustomerID: req.CustomerID,
+		Method:     req.Method,
+		Status:     "authorized",
+		Currency:   req.Currency,
+		Authorized: req.Amount,
+	}
+	if req.Capture {
+		payment.Status = "captured"
+		payment.Captured = req.Amount
+	}
+	p.payments[payment.ID] = payment
+	writeProviderJSON(w, http.StatusCreated, payment)
+}
+
+func (p *PaymentProvider) capture(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Amount decimal.Decimal `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProviderError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	payment, ok := p.payments[r.PathValue("id")]
+	switch {
+	case !ok:
+		writeProviderError(w, http.StatusNotFound, "not_found", "no such payment")
+	case payment.Status != "authorized":
+		writeProviderError(w, http.StatusUnprocessableEntity, "not_authorized", "payment is not authorized")
+	case !req.Amount.IsPositive():
+		writeProviderError(w, http.StatusBadRequest, "invalid_request", "amount must be positive")
+	case req.Amount.GreaterThan(payment.Authorized):
+		writeProviderError(w, http.StatusUnprocessableEntity, "capture_exceeds_authorized", "capture exceeds authorized amount")
+	default:
+		payment.Status = "captured"
+		payment.Captured = req.Amount
+		writeProviderJSON(w, http.StatusOK, payment)
+	}
+}
+
+func (p *PaymentProvider) void(w http.ResponseWriter, r *http.Request) {
+	payment, ok := p.payments[r.PathValue("id")]
+	switch {
+	case !ok:
+		writeProviderError(w, http.StatusNotFound, "not_found", "no such payment")
+	case payment.Status != "authorized":
+		writeProviderError(w, http.StatusUnprocessableEntity, "not_authorized", "payment is not authorized")
+	default:
+		payment.Status = "voided"
+		writeProviderJSON(w, http.StatusOK, payment)
+	}
+}
+
+func (p *PaymentProvider) refund(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Amount decimal.Decimal `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProviderError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	payment, ok := p.payments[r.PathValue("id")]
+	switch {
+	case !ok:
+		writeProviderError(w, http.StatusNotFound, "not_found", "no such payment")
+	case !req.Amount.IsPositive():
+		writeProviderError(w, http.StatusBadRequest, "invalid_request", "amount must be positive")
+	case payment.Refunded.Add(req.Amount).GreaterThan(payment.Captured):
+		writeProviderError(w, http.StatusUnprocessableEntity, "refund_exceeds_captured", "refund exceeds captured amount")
+	default:
+		payment.Refunded = payment.Refunded.Add(req.Amount)
+		if payment.Refunded.Equal(payment.Captured) {
+			payment.Status = "refunded"
+		}
+		p.refundCount++
+		writeProviderJSON(w, http.StatusCreated, map[string]any{
+			"id":     fmt.Sprintf("re_%d", p.refundCount),
+			"amount": req.Amount,
+		})
+	}
+}
+
+func (p *PaymentProvider) get(w http.ResponseWriter, r *http.Request) {
+	payment, ok := p.payments[r.PathValue("id")]
+	if !ok {
+		writeProviderError(w, http.StatusNotFound, "not_found", "no such payment")
+		return
+	}
+	writeProviderJSON(w, http.StatusOK, payment)
+}
+
+func writeProviderJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeProviderError(w http.ResponseWriter, status int, code, message string) {
+	writeProviderJSON(w, status, map[string]any{
+		"error": map[string]string{"code": code, "message": message},
+	})
+}
@@ -0,0 +1,461 @@
+// Package fakes provides in-process fakes of external services for tests
+package fakes
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMTPMessage is a message accepted by an SMTPServer
+type SMTPMessage struct {
+	From    string // Envelope sender
+	To      []string
+	Subject string // Decoded
+	Text    string // Decoded text/plain body
+	HTML    string // Decoded text/html body, if any
+	Header  mail.Header
+	TLS     bool // Received over STARTTLS
+}
+
+// SMTPReply is an SMTP status reply
+type SMTPReply struct {
+	Code int
+	Text string
+}
+
+// SMTPServer is a minimal SMTP server listening on localhost. It accepts
+// every message unless told to reject one, and optionally offers STARTTLS
+// and requires AUTH PLAIN.
+type SMTPServer struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu          sync.Mutex
+	messages    []SMTPMessage
+	rejects     []SMTPReply
+	connections int
+	conns       map[net.Conn]struct{}
+	tlsConfig   *tls.Config
+	username    string
+	password    string
+	closed      bool
+}
+
+// NewSMTPServer starts a fake SMTP server on a free localhost port
+func NewSMTPServer() (*SMTPServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &SMTPServer{
+		listener: listener,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the server's host:port
+func (s *SMTPServer) Addr() string {
+	return net.JoinHostPort(s.Host(), strconv.Itoa(s.Port()))
+}
+
+// Host returns the address the server listens on
+func (s *SMTPServer) Host() string {
+	return s.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+// Port returns the port the server listens on
+func (s *SMTPServer) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// EnableTLS offers STARTTLS with a self-signed certificate for 127.0.0.1
+func (s *SMTPServer) EnableTLS() error {
+	cert, err := selfSignedCertificate()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	return nil
+}
+
+// RequireAuth rejects mail until the client authenticates with AUTH PLAIN
+// as username and password
+func (s *SMTPServer) RequireAuth(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.username = username
+	s.password = password
+}
+
+// RejectNext answers the next RCPT command with code and text instead of
+// accepting it. Calls queue up, one rejection per recipient.
+func (s *SMTPServer) RejectNext(code int, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejects = append(s.rejects, SMTPReply{Code: code, Text: text})
+}
+
+// Messages returns the messages accepted so far
+func (s *SMTPServer) Messages() []SMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SMTPMessage(nil), s.messages...)
+}
+
+// Connections returns how many connections the server has accepted
+func (s *SMTPServer) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+// Close stops the server and drops every open connection
+func (s *SMTPServer) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *SMTPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.connections++
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// session is the state of one SMTP connection
+type session struct {
+	conn          net.Conn
+	text          *textproto.Conn
+	tls           bool
+	authenticated bool
+	from          string
+	to            []string
+}
+
+func (s *SMTPServer) handle(conn net.Conn) {
+	sess := &session{conn: conn, text: textproto.NewConn(conn)}
+	sess.text.PrintfLine("220 fake.smtp ESMTP ready")
+
+	for {
+		line, err := sess.text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			s.hello(sess)
+		case "HELO":
+			sess.text.PrintfLine("250 fake.smtp")
+		case "STARTTLS":
+			if !s.startTLS(sess) {
+				return
+			}
+		case "AUTH":
+			s.auth(sess, arg)
+		case "MAIL":
+			if s.authRequired() && !sess.authenticated {
+				sess.text.PrintfLine("530 5.7.0 Authentication required")
+				continue
+			}
+			sess.from = trimPath(arg, "FROM:")
+			sess.to = nil
+			sess.text.PrintfLine("250 2.1.0 OK")
+		case "RCPT":
+			if sess.from == "" {
+				sess.text.PrintfLine("503 5.5.1 MAIL first")
+				continue
+			}
+			if reply, ok := s.nextReject(); ok {
+				sess.text.PrintfLine("%d %s", reply.Code, reply.Text)
+				continue
+			}
+			sess.to = append(sess.to, trimPath(arg, "TO:"))
+			sess.text.PrintfLine("250 2.1.5 OK")
+		case "DATA":
+			if len(sess.to) == 0 {
+				sess.text.PrintfLine("503 5.5.1 RCPT first")
+				continue
+			}
+			sess.text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(sess.text.DotReader())
+			if err != nil {
+				return
+			}
+			msg, err := parseMessage(data)
+			if err != nil {
+				sess.text.PrintfLine("554 5.6.0 %v", err)
+				continue
+			}
+			msg.From, msg.To, msg.TLS = sess.from, sess.to, sess.tls
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			sess.from, sess.to = "", nil
+			sess.text.PrintfLine("250 2.0.0 OK queued")
+		case "RSET":
+			sess.from, sess.to = "", nil
+			sess.text.PrintfLine("250 2.0.0 OK")
+		case "NOOP":
+			sess.text.PrintfLine("250 2.0.0 OK")
+		case "QUIT":
+			sess.text.PrintfLine("221 2.0.0 Bye")
+			return
+		default:
+			sess.text.PrintfLine("502 5.5.2 Command not recognized")
+		}
+	}
+}
+
+func (s *SMTPServer) hello(sess *session) {
+	s.mu.Lock()
+	offerTLS := s.tlsConfig != nil && !sess.tls
+	offerAuth := s.username != ""
+	s.mu.Unlock()
+
+	lines := []string{"fake.smtp", "8BITMIME"}
+	if offerTLS {
+		lines = append(lines, "STARTTLS")
+	}
+	if offerAuth {
+		lines = append(lines, "AUTH PLAIN")
+	}
+	for i, line := range lines {
+		sep := "-"
+		if i == len(lines)-1 {
+			sep = " "
+		}
+		sess.text.PrintfLine("250%s%s", sep, line)
+	}
+}
+
+// startTLS upgrades the session; it reports false if the connection is lost
+func (s *SMTPServer) startTLS(sess *session) bool {
+	s.mu.Lock()
+	config := s.tlsConfig
+	s.mu.Unlock()
+	if config == nil || sess.tls {
+		sess.text.PrintfLine("502 5.5.1 STARTTLS not available")
+		return true
+	}
+
+	sess.text.PrintfLine("220 2.0.0 Ready to start TLS")
+	tlsConn := tls.Server(sess.conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		return false
+	}
+	sess.conn = tlsConn
+	sess.text = textproto.NewConn(tlsConn)
+	sess.tls = true
+	// The client must greet again and starts from scratch
+	sess.authenticated = false
+	sess.from, sess.to = "", nil
+	return true
+}
+
+func (s *SMTPServer) auth(sess *session, arg string) {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	if !strings.EqualFold(mechanism, "PLAIN") || !s.authRequired() {
+		sess.text.PrintfLine("504 5.5.4 Unrecognized authentication type")
+		return
+	}
+	if initial == "" {
+		sess.text.PrintfLine("334 ")
+		line, err := sess.text.ReadLine()
+		if err != nil {
+			return
+		}
+		initial = line
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(initial)
+	if err != nil {
+		sess.text.PrintfLine("501 5.5.2 Invalid base64")
+		return
+	}
+	// authzid NUL authcid NUL password
+	parts := strings.Split(string(decoded), "\x00")
+	s.mu.Lock()
+	ok := len(parts) == 3 && parts[1] == s.username && parts[2] == s.password
+	s.mu.Unlock()
+	if !ok {
+		sess.text.PrintfLine("535 5.7.8 Authentication credentials invalid")
+		return
+	}
+	sess.authenticated = true
+	sess.text.PrintfLine("235 2.7.0 Authentication successful")
+}
+
+func (s *SMTPServer) authRequired() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.username != ""
+}
+
+func (s *SMTPServer) nextReject() (SMTPReply, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.rejects) == 0 {
+		return SMTPReply{}, false
+	}
+	reply := s.rejects[0]
+	s.rejects = s.rejects[1:]
+	return reply, true
+}
+
+// trimPath extracts the address from "FROM:<a@b>" or "TO:<a@b>"
+func trimPath(arg, prefix string) string {
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	arg, _, _ = strings.Cut(strings.TrimSpace(arg), " ")
+	return strings.Trim(arg, "<>")
+}
+
+// parseMessage decodes a message's subject and text and HTML bodies
+func parseMessage(data []byte) (SMTPMessage, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return SMTPMessage{}, err
+	}
+
+	var decoder mime.WordDecoder
+	subject, err := decoder.DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		return SMTPMessage{}, err
+	}
+	msg := SMTPMessage{Subject: subject, Header: m.Header}
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil {
+		return SMTPMessage{}, err
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		body, err := decodeBody(m.Body, m.Header.Get("Content-Transfer-Encoding"))
+		if err != nil {
+			return SMTPMessage{}, err
+		}
+		// DATA ends the last line with CRLF even when the body does not
+		msg.setBody(mediaType, strings.TrimSuffix(body, "\n"))
+		return msg, nil
+	}
+
+	// Quoted-printable parts are decoded by the multipart reader
+	parts := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			return msg, nil
+		}
+		if err != nil {
+			return SMTPMessage{}, err
+		}
+		partType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			return SMTPMessage{}, err
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			return SMTPMessage{}, err
+		}
+		msg.setBody(partType, string(body))
+	}
+}
+
+func (m *SMTPMessage) setBody(mediaType, body string) {
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	switch mediaType {
+	case "text/plain":
+		m.Text = body
+	case "text/html":
+		m.HTML = body
+	}
+}
+
+func decodeBody(r io.Reader, encoding string) (string, error) {
+	if strings.EqualFold(encoding, "quoted-printable") {
+		r = quotedprintable.NewReader(r)
+	}
+	body, err := io.ReadAll(r)
+	return string(body), err
+}
+
+// selfSignedCertificate creates a short-lived certificate for 127.0.0.1 and
+// localhost
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "fake.smtp"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
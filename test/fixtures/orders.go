@@ -14,12 +14,12 @@ func CreateValidOrder() domain.Order {
 		{
 			SKU:      "ITEM-001",
 			Quantity: 2,
-			Price:    decimal.NewFromInt(29) + decimal.NewFromFloat(0.99),
+			Price:    decimal.RequireFromString("29.99"),
 		},
 		{
 			SKU:      "ITEM-002",
 			Quantity: 1,
-			Price:    decimal.NewFromInt(49) + decimal.NewFromFloat(0.99),
+			Price:    decimal.RequireFromString("49.99"),
 		},
 	}
 
@@ -49,7 +49,7 @@ func CreateSingleItemOrder() domain.Order {
 		{
 			SKU:      "ITEM-001",
 			Quantity: 1,
-			Price:    decimal.NewFromInt(99) + decimal.NewFromFloat(0.99),
+			Price:    decimal.RequireFromString("99.99"),
 		},
 	}
 
@@ -0,0 +1,174 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/microsoft/durabletask-go/api"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/approval"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/payment"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/workflows"
+	"github.com/Youmanvi/taskorchestrator/test/fixtures"
+)
+
+// createHighValueOrder creates an order whose total is above ApprovalThreshold
+func createHighValueOrder() domain.Order {
+	return fixtures.CreateOrderWithItems("CUST-12345", []domain.OrderItem{
+		{SKU: "ITEM-001", Quantity: 2, Price: decimal.NewFromInt(750)},
+	})
+}
+
+// scheduleHighValueOrder schedules a high-value order and waits until it is
+// waiting for approval
+func scheduleHighValueOrder(t *testing.T, harness *TestHarness, order domain.Order) api.InstanceID {
+	ctx := context.Background()
+	execution, err := harness.ScheduleOrder(ctx, &workflows.OrderProcessingInput{
+		Order:         order,
+		CustomerEmail: "customer@example.com",
+	})
+	require.NoError(t, err)
+	require.NoError(t, harness.WaitForApproval(ctx, order.ID, 5*time.Second))
+	return execution
+}
+
+func waitForOrder(t *testing.T, harness *TestHarness, execution api.InstanceID) *workflows.OrderProcessingOutput {
+	result, err := harness.WaitForOrchestration(context.Background(), execution, 10*time.Second)
+	require.NoError(t, err)
+	require.True(t, IsSuccessful(result))
+
+	output, err := GetOrderOutput(result)
+	require.NoError(t, err)
+	return output
+}
+
+func TestOrderProcessingBelowThresholdSkipsApproval(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	order := fixtures.CreateLargeOrder()
+	require.True(t, order.TotalAmount.LessThanOrEqual(ApprovalThreshold))
+
+	output := runOrder(t, harness, order)
+	assert.Equal(t, "confirmed", output.Status)
+	assert.Nil(t, output.Approval)
+
+	_, err = harness.Approvals.Get(ctx, order.ID)
+	assert.ErrorIs(t, err, approval.ErrApprovalNotFound)
+}
+
+func TestOrderProcessingApproved(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	order := createHighValueOrder()
+	execution := scheduleHighValueOrder(t, harness, order)
+
+	// The payment is only authorized while the order waits
+	assert.Equal(t, 1, harness.PaymentGateway.TransactionCount())
+
+	require.NoError(t, harness.RaiseApproval(ctx, order.ID, approval.Decision{
+		Approver: "alice",
+		Decision: approval.DecisionApprove,
+		Comment:  "known customer",
+	}))
+
+	output := waitForOrder(t, harness, execution)
+	assert.Equal(t, "confirmed", output.Status)
+	require.NotNil(t, output.Approval)
+	assert.Equal(t, domain.ApprovalStatusApproved, output.Approval.Status)
+	assert.Equal(t, "alice", output.Approval.Approver)
+	assert.NotEmpty(t, output.PaymentID)
+	assert.Equal(t, string(domain.PaymentStatusCompleted), output.PaymentStatus)
+
+	txn, err := harness.PaymentGateway.GetStatus(ctx, output.TransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.TransactionCaptured, txn.Status)
+
+	recorded, err := harness.Approvals.Get(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ApprovalStatusApproved, recorded.Status)
+	assert.Equal(t, "known customer", recorded.Comment)
+}
+
+func TestOrderProcessingRejectedReleasesReservation(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	order := createHighValueOrder()
+	execution := scheduleHighValueOrder(t, harness, order)
+
+	require.NoError(t, harness.RaiseApproval(ctx, order.ID, approval.Decision{
+		Approver: "bob",
+		Decision: approval.DecisionReject,
+		Comment:  "suspected fraud",
+	}))
+
+	output := waitForOrder(t, harness, execution)
+	assert.Equal(t, "failed", output.Status)
+	assert.Equal(t, "approval rejected by bob: suspected fraud", output.Message)
+	assert.Empty(t, output.PaymentID)
+	assert.Equal(t, string(domain.PaymentStatusVoided), output.PaymentStatus)
+
+	// The authorization is voided; nothing was captured
+	txn, err := harness.PaymentGateway.GetStatus(ctx, output.TransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.TransactionVoided, txn.Status)
+	assert.True(t, txn.Amount.IsZero())
+
+	res, exists := harness.InventoryMgr.GetReservation(output.ReservationID)
+	require.True(t, exists)
+	assert.Equal(t, domain.ReservationStatusReleased, res.Status)
+
+	recorded, err := harness.Approvals.Get(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ApprovalStatusRejected, recorded.Status)
+}
+
+func TestOrderProcessingApprovalExpires(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	order := createHighValueOrder()
+	execution := scheduleHighValueOrder(t, harness, order)
+
+	// The deadline timer is durable: it still fires after a worker restart
+	require.NoError(t, harness.RestartWorker(ctx))
+
+	output := waitForOrder(t, harness, execution)
+	assert.Equal(t, "failed", output.Status)
+	require.NotNil(t, output.Approval)
+	assert.Equal(t, domain.ApprovalStatusExpired, output.Approval.Status)
+
+	res, exists := harness.InventoryMgr.GetReservation(output.ReservationID)
+	require.True(t, exists)
+	assert.Equal(t, domain.ReservationStatusReleased, res.Status)
+
+	recorded, err := harness.Approvals.Get(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ApprovalStatusExpired, recorded.Status)
+
+	pending, err := harness.Approvals.ListPending(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
@@ -0,0 +1,109 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/inventory"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/workflows"
+)
+
+// runBatch schedules a batch orchestration and waits for its output
+func runBatch(t *testing.T, harness *TestHarness, instanceID string, input *workflows.OrchestrationInput) *workflows.OrchestrationOutput {
+	ctx := context.Background()
+	execution, err := harness.ScheduleBatch(ctx, instanceID, input)
+	require.NoError(t, err)
+
+	result, err := harness.WaitForOrchestration(ctx, execution, 10*time.Second)
+	require.NoError(t, err)
+	require.True(t, IsSuccessful(result))
+
+	output, err := GetBatchOutput(result)
+	require.NoError(t, err)
+	return output
+}
+
+// checkItem builds a batch item checking availability of one SKU
+func checkItem(t *testing.T, id, sku string) workflows.BatchItem {
+	input, err := json.Marshal(inventory.CheckAvailabilityInput{
+		Items: []domain.OrderItem{{SKU: sku, Quantity: 1}},
+	})
+	require.NoError(t, err)
+	return workflows.BatchItem{ID: id, Input: input}
+}
+
+func TestBatchOrchestratorFansOutAndAggregates(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	harness.InventoryMgr.SetUnavailable("ITEM-GONE")
+
+	input := &workflows.OrchestrationInput{Activity: "inventory:check", MaxParallelism: 2}
+	for i := 0; i < 5; i++ {
+		input.Items = append(input.Items, checkItem(t, fmt.Sprintf("item-%d", i), fmt.Sprintf("ITEM-%03d", i)))
+	}
+	input.Items = append(input.Items,
+		checkItem(t, "gone", "ITEM-GONE"),
+		// Not a CheckAvailabilityInput, so the activity fails permanently
+		workflows.BatchItem{ID: "bad", Input: json.RawMessage(`"not an object"`)},
+	)
+
+	output := runBatch(t, harness, "batch-fan-out", input)
+	assert.Equal(t, workflows.BatchStatusPartial, output.Status)
+	assert.Equal(t, 7, output.TotalItems)
+	assert.Equal(t, 6, output.SuccessCount)
+	assert.Equal(t, 1, output.FailureCount)
+	require.Len(t, output.Results, 7)
+
+	// Results keep input order
+	for i, result := range output.Results {
+		assert.Equal(t, input.Items[i].ID, result.ID)
+	}
+
+	var gone inventory.CheckAvailabilityOutput
+	require.NoError(t, json.Unmarshal(output.Results[5].Output, &gone))
+	assert.False(t, gone.Available)
+	assert.Equal(t, []string{"ITEM-GONE"}, gone.UnavailableItems)
+
+	bad := output.Results[6]
+	assert.False(t, bad.Success)
+	assert.Equal(t, 1, bad.Attempts, "permanent errors are not retried")
+	assert.Contains(t, bad.Error, "INVALID_INPUT")
+	require.Len(t, output.Errors, 1)
+	assert.Contains(t, output.Errors[0], "item bad")
+}
+
+func TestBatchOrchestratorRejectsInvalidInput(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	output := runBatch(t, harness, "batch-invalid", &workflows.OrchestrationInput{
+		Items: []workflows.BatchItem{
+			checkItem(t, "a", "ITEM-001"),
+			checkItem(t, "a", "ITEM-002"),
+			{ID: "b"},
+		},
+	})
+	assert.Equal(t, workflows.BatchStatusRejected, output.Status)
+	assert.Equal(t, 3, output.FailureCount)
+	assert.Empty(t, output.Results)
+	assert.ElementsMatch(t, []string{
+		"activity is required",
+		"item id a is duplicated",
+		"item b has no input",
+	}, output.Errors)
+}
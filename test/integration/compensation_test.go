@@ -0,0 +1,95 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/payment"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/workflows"
+	"github.com/Youmanvi/taskorchestrator/test/fixtures"
+)
+
+func TestOrderProcessingPaymentFailureCompensates(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	// Every capture attempt fails, so the reservation must be released and
+	// the authorization voided
+	harness.PaymentGateway.SetCaptureError(fmt.Errorf("capture rejected"))
+
+	order := fixtures.CreateValidOrder()
+	input := &workflows.OrderProcessingInput{
+		Order:         order,
+		CustomerEmail: "customer@example.com",
+	}
+
+	execution, err := harness.ScheduleOrder(ctx, input)
+	require.NoError(t, err)
+
+	result, err := harness.WaitForOrchestration(ctx, execution, 10*time.Second)
+	require.NoError(t, err)
+
+	output, err := GetOrderOutput(result)
+	require.NoError(t, err)
+
+	assert.Equal(t, "failed", output.Status)
+	assert.Empty(t, output.PaymentID)
+	assert.Equal(t, string(domain.PaymentStatusVoided), output.PaymentStatus)
+	require.Len(t, output.Compensations, 2)
+	assert.Equal(t, "inventory:release", output.Compensations[0].Activity)
+	assert.Equal(t, "payment:void", output.Compensations[1].Activity)
+	for _, c := range output.Compensations {
+		assert.Equal(t, workflows.CompensationSucceeded, c.Status)
+	}
+
+	// Verify the reservation was released by the compensation
+	res, exists := harness.InventoryMgr.GetReservation(output.ReservationID)
+	require.True(t, exists, "reservation should exist")
+	assert.Equal(t, domain.ReservationStatusReleased, res.Status)
+
+	// The authorization was voided rather than refunded
+	txn, err := harness.PaymentGateway.GetStatus(ctx, output.TransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, payment.TransactionVoided, txn.Status)
+	assert.True(t, txn.Amount.IsZero())
+}
+
+func TestOrderProcessingAuthorizationFailureReservesNothing(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	// Authorization runs before reservation, so a decline has nothing to undo
+	harness.PaymentGateway.SetChargeError(fmt.Errorf("card declined"))
+
+	order := fixtures.CreateValidOrder()
+	execution, err := harness.ScheduleOrder(ctx, &workflows.OrderProcessingInput{
+		Order:         order,
+		CustomerEmail: "customer@example.com",
+	})
+	require.NoError(t, err)
+
+	result, err := harness.WaitForOrchestration(ctx, execution, 10*time.Second)
+	require.NoError(t, err)
+
+	output, err := GetOrderOutput(result)
+	require.NoError(t, err)
+
+	assert.Equal(t, "failed", output.Status)
+	assert.Contains(t, output.Message, "payment authorization failed")
+	assert.Empty(t, output.TransactionID)
+	assert.Empty(t, output.ReservationIDs)
+	assert.Empty(t, output.Compensations)
+}
@@ -0,0 +1,190 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/deadletter"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/notification"
+	"github.com/Youmanvi/taskorchestrator/internal/workflows"
+	"github.com/Youmanvi/taskorchestrator/test/fixtures"
+)
+
+func replayDeadLetter(t *testing.T, harness *TestHarness, entry *deadletter.Entry) *workflows.DeadLetterReplayOutput {
+	ctx := context.Background()
+	execution, err := harness.ScheduleDeadLetterReplay(ctx, entry)
+	require.NoError(t, err)
+
+	result, err := harness.WaitForOrchestration(ctx, execution, 10*time.Second)
+	require.NoError(t, err)
+	require.True(t, IsSuccessful(result))
+
+	output, err := GetReplayOutput(result)
+	require.NoError(t, err)
+	return output
+}
+
+func TestOrderProcessingFailureSendsEmail(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	harness.InventoryMgr.SetUnavailable("ITEM-001")
+
+	order := fixtures.CreateValidOrder()
+	output := runOrder(t, harness, order)
+	assert.Equal(t, "failed", output.Status)
+	assert.Empty(t, output.DeadLetters)
+
+	_, err = harness.WaitForDelivery(ctx, order.ID, "order_failed", 5*time.Second)
+	require.NoError(t, err)
+	messages := harness.EmailService.GetAllMessages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "customer@example.com", messages[0].To)
+	assert.Equal(t, fmt.Sprintf("Order %s Failed", order.ID), messages[0].Subject)
+}
+
+func TestOrderProcessingPaymentFailureSendsEmail(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	harness.PaymentGateway.SetCaptureError(fmt.Errorf("capture rejected"))
+
+	order := fixtures.CreateValidOrder()
+	output := runOrder(t, harness, order)
+	assert.Equal(t, "failed", output.Status)
+	assert.NotEmpty(t, output.Compensations)
+
+	_, err = harness.WaitForDelivery(ctx, order.ID, "order_failed", 5*time.Second)
+	require.NoError(t, err)
+	messages := harness.EmailService.GetAllMessages()
+	require.Len(t, messages, 1)
+	assert.Contains(t, messages[0].Subject, "Failed")
+}
+
+func TestConfirmationEmailFailureIsDeadLetteredAndReplayed(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	harness.Outbox.SetEnqueueError(fmt.Errorf("outbox unavailable"))
+
+	// The order still succeeds; the email goes to the dead-letter queue
+	order := fixtures.CreateValidOrder()
+	output := runOrder(t, harness, order)
+	assert.Equal(t, "confirmed", output.Status)
+	assert.Equal(t, []string{"notification:order_confirmation"}, output.DeadLetters)
+
+	pending, err := harness.DeadLetters.List(ctx, deadletter.StatusPending)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	entry := pending[0]
+	assert.Equal(t, order.ID, entry.OrchestrationID)
+	assert.Equal(t, "notification:order_confirmation", entry.Activity)
+	assert.Equal(t, "OUTBOX_WRITE_FAILED", entry.ErrorCode)
+	assert.Equal(t, 3, entry.Attempts)
+	assert.Contains(t, string(entry.Payload), order.ID)
+
+	// A replay that fails again keeps the entry pending
+	replay := replayDeadLetter(t, harness, entry)
+	assert.Equal(t, string(deadletter.StatusPending), replay.Status)
+	assert.Equal(t, 6, replay.Attempts)
+
+	harness.Outbox.SetEnqueueError(nil)
+	entry, err = harness.DeadLetters.Get(ctx, entry.ID)
+	require.NoError(t, err)
+
+	replay = replayDeadLetter(t, harness, entry)
+	assert.Equal(t, string(deadletter.StatusReplayed), replay.Status)
+	assert.Equal(t, 7, replay.Attempts)
+
+	_, err = harness.WaitForDelivery(ctx, order.ID, "order_confirmed", 5*time.Second)
+	require.NoError(t, err)
+	messages := harness.EmailService.GetAllMessages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, fmt.Sprintf("Order %s Confirmed", order.ID), messages[0].Subject)
+
+	pending, err = harness.DeadLetters.List(ctx, deadletter.StatusPending)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestConfirmationEmailIsRetriedByDispatcher(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	harness.EmailService.SetSendError(fmt.Errorf("smtp unavailable"))
+
+	order := fixtures.CreateValidOrder()
+	execution, err := harness.ScheduleOrder(ctx, &workflows.OrderProcessingInput{
+		Order:         order,
+		CustomerEmail: "customer@example.com",
+	})
+	require.NoError(t, err)
+
+	// Let the first attempt fail, then recover before the dispatcher gives up
+	require.Eventually(t, func() bool {
+		messages, err := harness.Outbox.ListByOrder(ctx, order.ID)
+		return err == nil && len(messages) == 1 && messages[0].LastError != ""
+	}, 5*time.Second, time.Millisecond)
+	harness.EmailService.SetSendError(nil)
+
+	// Queuing the email succeeded, so nothing is dead-lettered
+	result, err := harness.WaitForOrchestration(ctx, execution, 10*time.Second)
+	require.NoError(t, err)
+	output, err := GetOrderOutput(result)
+	require.NoError(t, err)
+	assert.Equal(t, "confirmed", output.Status)
+	assert.Empty(t, output.DeadLetters)
+
+	delivery, err := harness.WaitForDelivery(ctx, order.ID, "order_confirmed", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, notification.OutboxStatusSent, delivery.Status)
+	assert.NotEmpty(t, delivery.MessageID)
+
+	// Delivered exactly once, even across retries
+	time.Sleep(10 * DispatchInterval)
+	messages := harness.EmailService.GetAllMessages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, fmt.Sprintf("Order %s Confirmed", order.ID), messages[0].Subject)
+}
+
+func TestConfirmationEmailFailsAfterMaxAttempts(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	harness.EmailService.SetSendError(fmt.Errorf("smtp unavailable"))
+
+	order := fixtures.CreateValidOrder()
+	output := runOrder(t, harness, order)
+	assert.Equal(t, "confirmed", output.Status)
+
+	delivery, err := harness.WaitForDelivery(ctx, order.ID, "order_confirmed", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, notification.OutboxStatusFailed, delivery.Status)
+	assert.Equal(t, DispatchAttempts, delivery.Attempts)
+	assert.Equal(t, "smtp unavailable", delivery.LastError)
+	assert.Empty(t, harness.EmailService.GetAllMessages())
+}
@@ -8,139 +8,386 @@ import (
 	"time"
 
 	"github.com/microsoft/durabletask-go/api"
+	dtbackend "github.com/microsoft/durabletask-go/backend"
+	"github.com/microsoft/durabletask-go/backend/sqlite"
+	"github.com/microsoft/durabletask-go/task"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shopspring/decimal"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"github.com/Youmanvi/taskorchestrator/internal/activities"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/approval"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/deadletter"
 	"github.com/Youmanvi/taskorchestrator/internal/activities/inventory"
 	"github.com/Youmanvi/taskorchestrator/internal/activities/notification"
 	"github.com/Youmanvi/taskorchestrator/internal/activities/payment"
 	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/backend"
 	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/config"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/idempotency"
 	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
-	"github.com/Youmanvi/taskorchestrator/internal/middleware"
 	"github.com/Youmanvi/taskorchestrator/internal/workflows"
 )
 
+// Orders above ApprovalThreshold wait for approval, and are expired after
+// ApprovalTimeout
+var (
+	ApprovalThreshold = decimal.NewFromInt(1000)
+	ApprovalTimeout   = 2 * time.Second
+)
+
+// The dispatcher polls the outbox every DispatchInterval and retries a failed
+// email after DispatchBackoff, up to DispatchAttempts times
+var (
+	DispatchInterval = 10 * time.Millisecond
+	DispatchBackoff  = 50 * time.Millisecond
+	DispatchAttempts = 3
+)
+
 // TestHarness provides utilities for integration testing
 type TestHarness struct {
-	Backend         api.Backend
-	Client          api.TaskHubClient
-	Worker          api.TaskHubWorker
-	Logger          *observability.Logger
-	Metrics         *observability.Metrics
-	PaymentGateway  *payment.MockPaymentGateway
-	InventoryMgr    *inventory.MockInventoryManager
-	EmailService    *notification.MockEmailService
-	DBFile          string
+	Backend        dtbackend.Backend
+	Client         dtbackend.TaskHubClient
+	Worker         dtbackend.TaskHubWorker
+	Logger         *observability.Logger
+	Metrics        *observability.Metrics
+	Registry       *prometheus.Registry
+	PaymentGateway *payment.MockPaymentGateway
+	InventoryMgr   *inventory.MockInventoryManager
+	EmailService   *notification.MockEmailService
+	SMSService     *notification.MockSMSService
+	Outbox         *notification.MockOutbox
+	Dispatcher     *notification.Dispatcher
+	Approvals      *approval.MockApprovalStore
+	DeadLetters    *deadletter.MockDeadLetterStore
+	Spans          *tracetest.SpanRecorder
+	DBFile         string
+
+	workflowRegistry *task.TaskRegistry
+	activityRegistry *task.TaskRegistry
 }
 
 // NewTestHarness creates a new test harness with SQLite backend
 func NewTestHarness() (*TestHarness, error) {
+	return NewTestHarnessWithEmail(nil)
+}
+
+// NewTestHarnessWithEmail creates a test harness that emails notifications
+// through email, e.g. an SMTPService pointed at a fake server. A nil email
+// uses the harness's MockEmailService.
+func NewTestHarnessWithEmail(email notification.EmailService) (*TestHarness, error) {
 	// Create temporary SQLite database for testing
 	dbFile := fmt.Sprintf("%s/test-orchestrator-%d.db", os.TempDir(), time.Now().UnixNano())
 
-	cfg := &config.BackendConfig{
-		SQLiteFile:    dbFile,
-		MaxConnection: 10,
-	}
-
-	be, err := backend.NewSQLiteBackend(cfg)
-	if err != nil {
-		return nil, err
-	}
+	// A worker stopped in the middle of a work item cannot abandon it, as its
+	// context is already cancelled, so keep locks short enough for the worker
+	// started by RestartWorker to pick the item up again
+	opts := sqlite.NewSqliteOptions(dbFile)
+	opts.OrchestrationLockTimeout = 2 * time.Second
+	opts.ActivityLockTimeout = 2 * time.Second
+	be := sqlite.NewSqliteBackend(opts, dtbackend.DefaultLogger())
 
 	// Create logger
-	logger := observability.NewLogger(&observability.ObservabilityConfig{
+	logger := observability.NewLogger(&config.ObservabilityConfig{
 		LogLevel:  "debug",
 		LogFormat: "text",
 	})
 
 	// Create metrics
-	metrics := observability.NewMetrics()
+	registry := prometheus.NewRegistry()
+	metrics := observability.NewMetrics(registry)
+
+	// Record spans in memory so tests can assert span trees
+	spans := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(spans),
+		sdktrace.WithIDGenerator(observability.NewIDGenerator()),
+	).Tracer(observability.TracerName)
 
 	// Create mock dependencies
 	paymentGateway := payment.NewMockPaymentGateway()
+	// Tests inject payment failures explicitly rather than at random
+	paymentGateway.SetFailureRate(0)
 	inventoryMgr := inventory.NewMockInventoryManager()
 	emailService := notification.NewMockEmailService()
+	smsService := notification.NewMockSMSService()
+	outbox := notification.NewMockOutbox()
+	approvals := approval.NewMockApprovalStore()
+	deadLetters := deadletter.NewMockDeadLetterStore()
 
-	// Create activity dependencies
-	activityDeps := &activities.ActivityDeps{
-		Logger:          logger,
-		Metrics:         metrics,
-		PaymentGateway:  paymentGateway,
-		InventoryMgr:    inventoryMgr,
-		EmailService:    emailService,
-		RetryPolicy:     middleware.DefaultRetryPolicy(3),
-		TimeoutDuration: 30 * time.Second,
+	if email == nil {
+		email = emailService
 	}
 
-	// Create registries
-	activityRegistry := activities.NewActivityRegistry(activityDeps)
-	workflowRegistry := workflows.NewWorkflowRegistry()
-
-	// Create client and worker
-	client, err := api.NewTaskHubClient(be)
+	// Confirmations also go out by SMS when the customer has a phone number
+	templates, err := notification.LoadTemplates(notification.BuiltinTemplates(), "en")
 	if err != nil {
 		return nil, err
 	}
-
-	worker, err := api.NewTaskHubWorker(be, workflowRegistry, activityRegistry)
+	notifier, err := notification.NewNotifier(templates,
+		map[string][]string{"order_confirmed": {notification.ChannelEmail, notification.ChannelSMS}},
+		notification.NewEmailChannel(email),
+		notification.NewSMSChannel(smsService),
+	)
 	if err != nil {
 		return nil, err
 	}
 
+	// Create activity dependencies
+	activityDeps := &activities.ActivityDeps{
+		Logger:         logger,
+		Metrics:        metrics,
+		PaymentGateway: paymentGateway,
+		InventoryMgr:   inventoryMgr,
+		Outbox:         outbox,
+		Notifier:       notifier,
+		Approvals:      approvals,
+		DeadLetters:    deadLetters,
+		Config:         config.ActivitiesConfig{TimeoutSeconds: 30},
+		Idempotency:    idempotency.NewMemoryStore(),
+		Tracer:         tracer,
+	}
+
+	// Create registries
+	activityRegistry := activities.NewActivityRegistry(activityDeps)
+	workflowRegistry := workflows.NewWorkflowRegistry(&workflows.WorkflowDeps{
+		Logger:  logger,
+		Metrics: metrics,
+		RetryPolicies: workflows.RetryPolicies{
+			Default: workflows.RetryPolicy{
+				MaxAttempts:       3,
+				InitialBackoff:    10 * time.Millisecond,
+				MaxBackoff:        50 * time.Millisecond,
+				BackoffMultiplier: 2.0,
+			},
+		},
+		CompensationPolicy: workflows.RetryPolicy{
+			MaxAttempts:       3,
+			InitialBackoff:    10 * time.Millisecond,
+			MaxBackoff:        50 * time.Millisecond,
+			BackoffMultiplier: 2.0,
+		},
+		Tracer:            tracer,
+		ApprovalThreshold: ApprovalThreshold,
+		ApprovalTimeout:   ApprovalTimeout,
+	})
+
+	// Record orchestration metrics as instances start and finish
+	be = backend.WithLifecycleHooks(be, backend.NewMetricsHook(metrics))
+
+	// Create client and worker, wired as in the app
+	client := dtbackend.NewTaskHubClient(be)
+	worker := newWorker(be, workflowRegistry, activityRegistry)
+
+	// Queued emails are delivered in the background, as in the app
+	dispatcher := notification.NewDispatcher(outbox, notifier, notification.DispatcherConfig{
+		Interval:     DispatchInterval,
+		MaxAttempts:  DispatchAttempts,
+		RetryBackoff: DispatchBackoff,
+	}, logger)
+	dispatcher.Start()
+
 	return &TestHarness{
 		Backend:        be,
 		Client:         client,
 		Worker:         worker,
 		Logger:         logger,
 		Metrics:        metrics,
+		Registry:       registry,
 		PaymentGateway: paymentGateway,
 		InventoryMgr:   inventoryMgr,
 		EmailService:   emailService,
+		SMSService:     smsService,
+		Outbox:         outbox,
+		Dispatcher:     dispatcher,
+		Approvals:      approvals,
+		DeadLetters:    deadLetters,
+		Spans:          spans,
 		DBFile:         dbFile,
+
+		workflowRegistry: workflowRegistry,
+		activityRegistry: activityRegistry,
 	}, nil
 }
 
-// Start starts the worker
+// newWorker creates a task hub worker that runs workflowRegistry's
+// orchestrators and activityRegistry's activities against be
+func newWorker(be dtbackend.Backend, workflowRegistry, activityRegistry *task.TaskRegistry) dtbackend.TaskHubWorker {
+	logger := dtbackend.DefaultLogger()
+	orchestrationWorker := dtbackend.NewOrchestrationWorker(be, task.NewTaskExecutor(workflowRegistry), logger)
+	activityWorker := dtbackend.NewActivityTaskWorker(be, task.NewTaskExecutor(activityRegistry), logger)
+	return dtbackend.NewTaskHubWorker(be, orchestrationWorker, activityWorker, logger)
+}
+
+// Start starts the worker, creating the task hub on first use
 func (h *TestHarness) Start(ctx context.Context) error {
-	go h.Worker.Start(ctx)
-	// Give worker time to start
-	time.Sleep(100 * time.Millisecond)
-	return nil
+	return h.Worker.Start(ctx)
 }
 
-// Stop stops the worker and cleans up temporary database
+// Stop stops the worker and the dispatcher and cleans up temporary database
 func (h *TestHarness) Stop(ctx context.Context) error {
-	err := h.Worker.Stop(ctx)
+	err := h.Worker.Shutdown(ctx)
+	h.Dispatcher.Close()
 	// Clean up temporary database file
 	os.Remove(h.DBFile)
 	return err
 }
 
+// RestartWorker stops the worker and starts a new one over the same backend,
+// as if the process had been restarted
+func (h *TestHarness) RestartWorker(ctx context.Context) error {
+	if err := h.Worker.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	h.Worker = newWorker(h.Backend, h.workflowRegistry, h.activityRegistry)
+	return h.Start(ctx)
+}
+
 // ScheduleOrder schedules an order processing orchestration
-func (h *TestHarness) ScheduleOrder(ctx context.Context, input *workflows.OrderProcessingInput) (api.OrchestrationExecution, error) {
-	inputBytes, _ := json.Marshal(input)
+func (h *TestHarness) ScheduleOrder(ctx context.Context, input *workflows.OrderProcessingInput) (api.InstanceID, error) {
 	return h.Client.ScheduleNewOrchestration(
 		ctx,
 		"order_processing",
-		api.WithInstanceID(input.Order.ID),
-		api.WithInput(inputBytes),
+		api.WithInstanceID(api.InstanceID(input.Order.ID)),
+		api.WithInput(input),
 	)
 }
 
-// WaitForOrchestration waits for an orchestration to complete
-func (h *TestHarness) WaitForOrchestration(ctx context.Context, execution api.OrchestrationExecution, timeout time.Duration) (*api.OrchestrationExecutionResult, error) {
+// WaitForOrchestration waits for an orchestration to complete, fail or be
+// terminated
+func (h *TestHarness) WaitForOrchestration(ctx context.Context, id api.InstanceID, timeout time.Duration) (*api.OrchestrationMetadata, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
-	return execution.WaitForCompletion(ctx)
+	return h.Client.WaitForOrchestrationCompletion(ctx, id)
+}
+
+// IsSuccessful reports whether the orchestration ran to completion; an
+// orchestrator that returned an error ends up failed instead
+func IsSuccessful(result *api.OrchestrationMetadata) bool {
+	return result.RuntimeStatus == api.RUNTIME_STATUS_COMPLETED
+}
+
+// decodeOutput parses the orchestration's serialized output into v
+func decodeOutput(result *api.OrchestrationMetadata, v any) error {
+	if result.SerializedOutput == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(result.SerializedOutput), v)
 }
 
 // GetOrderOutput parses the orchestration output as OrderProcessingOutput
-func GetOrderOutput(result *api.OrchestrationExecutionResult) (*workflows.OrderProcessingOutput, error) {
+func GetOrderOutput(result *api.OrchestrationMetadata) (*workflows.OrderProcessingOutput, error) {
 	var output workflows.OrderProcessingOutput
-	if result.Output != nil {
-		if err := json.Unmarshal(result.Output, &output); err != nil {
+	if err := decodeOutput(result, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// ScheduleRefund schedules an order_refund or order_cancellation orchestration
+func (h *TestHarness) ScheduleRefund(ctx context.Context, orchestrator, instanceID string, input *workflows.OrderRefundInput) (api.InstanceID, error) {
+	return h.Client.ScheduleNewOrchestration(
+		ctx,
+		orchestrator,
+		api.WithInstanceID(api.InstanceID(instanceID)),
+		api.WithInput(input),
+	)
+}
+
+// GetRefundOutput parses the orchestration output as OrderRefundOutput
+func GetRefundOutput(result *api.OrchestrationMetadata) (*workflows.OrderRefundOutput, error) {
+	var output workflows.OrderRefundOutput
+	if err := decodeOutput(result, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// ScheduleBatch schedules a batch orchestration
+func (h *TestHarness) ScheduleBatch(ctx context.Context, instanceID string, input *workflows.OrchestrationInput) (api.InstanceID, error) {
+	return h.Client.ScheduleNewOrchestration(
+		ctx,
+		"batch",
+		api.WithInstanceID(api.InstanceID(instanceID)),
+		api.WithInput(input),
+	)
+}
+
+// GetBatchOutput parses the orchestration output as a batch OrchestrationOutput
+func GetBatchOutput(result *api.OrchestrationMetadata) (*workflows.OrchestrationOutput, error) {
+	var output workflows.OrchestrationOutput
+	if err := decodeOutput(result, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// RaiseApproval sends an approval decision to an order's orchestration
+func (h *TestHarness) RaiseApproval(ctx context.Context, orderID string, decision approval.Decision) error {
+	payload, _ := json.Marshal(decision)
+	return h.Client.RaiseEvent(ctx, api.InstanceID(orderID), approval.EventName, api.WithRawEventData(string(payload)))
+}
+
+// WaitForApproval waits until an order's approval request is pending
+func (h *TestHarness) WaitForApproval(ctx context.Context, orderID string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if a, err := h.Approvals.Get(ctx, orderID); err == nil && a.IsPending() {
+			return nil
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return fmt.Errorf("no pending approval for order %s after %s", orderID, timeout)
+}
+
+// WaitForDelivery waits until the dispatcher has finished with an order's
+// email for eventType, sent or failed, and returns it
+func (h *TestHarness) WaitForDelivery(ctx context.Context, orderID, eventType string, timeout time.Duration) (*notification.OutboxMessage, error) {
+	return h.WaitForChannelDelivery(ctx, orderID, eventType, notification.ChannelEmail, timeout)
+}
+
+// WaitForChannelDelivery waits until the dispatcher has finished with an
+// order's notification for eventType on channel, sent or failed, and returns
+// it
+func (h *TestHarness) WaitForChannelDelivery(ctx context.Context, orderID, eventType, channel string, timeout time.Duration) (*notification.OutboxMessage, error) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		messages, err := h.Outbox.ListByOrder(ctx, orderID)
+		if err != nil {
 			return nil, err
 		}
+		for _, msg := range messages {
+			if msg.EventType == eventType && msg.Channel == channel && (msg.Status == notification.OutboxStatusSent || msg.Status == notification.OutboxStatusFailed) {
+				return msg, nil
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return nil, fmt.Errorf("%s %s notification for order %s not delivered after %s", eventType, channel, orderID, timeout)
+}
+
+// ScheduleDeadLetterReplay schedules a dead_letter_replay orchestration for
+// a recorded entry
+func (h *TestHarness) ScheduleDeadLetterReplay(ctx context.Context, entry *deadletter.Entry) (api.InstanceID, error) {
+	input := workflows.DeadLetterReplayInput{
+		EntryID:  entry.ID,
+		Activity: entry.Activity,
+		Payload:  entry.Payload,
+		Attempts: entry.Attempts,
+	}
+	return h.Client.ScheduleNewOrchestration(
+		ctx,
+		"dead_letter_replay",
+		api.WithInstanceID(api.InstanceID(fmt.Sprintf("dead-letter-%d-%d", entry.ID, entry.Attempts))),
+		api.WithInput(input),
+	)
+}
+
+// GetReplayOutput parses the orchestration output as DeadLetterReplayOutput
+func GetReplayOutput(result *api.OrchestrationMetadata) (*workflows.DeadLetterReplayOutput, error) {
+	var output workflows.DeadLetterReplayOutput
+	if err := decodeOutput(result, &output); err != nil {
+		return nil, err
 	}
 	return &output, nil
 }
@@ -0,0 +1,94 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/notification"
+	"github.com/Youmanvi/taskorchestrator/internal/workflows"
+	"github.com/Youmanvi/taskorchestrator/test/fixtures"
+)
+
+func TestOrderNotificationsFanOutToChannels(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	order := fixtures.CreateValidOrder()
+	execution, err := harness.ScheduleOrder(ctx, &workflows.OrderProcessingInput{
+		Order:          order,
+		CustomerEmail:  "client@example.fr",
+		CustomerPhone:  "+33600000000",
+		CustomerLocale: "fr-FR",
+	})
+	require.NoError(t, err)
+
+	result, err := harness.WaitForOrchestration(ctx, execution, 10*time.Second)
+	require.NoError(t, err)
+	require.True(t, IsSuccessful(result))
+	output, err := GetOrderOutput(result)
+	require.NoError(t, err)
+
+	// One confirmation per routed channel, rendered in the customer's locale
+	email, err := harness.WaitForChannelDelivery(ctx, order.ID, "order_confirmed", notification.ChannelEmail, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, notification.OutboxStatusSent, email.Status)
+	sms, err := harness.WaitForChannelDelivery(ctx, order.ID, "order_confirmed", notification.ChannelSMS, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, notification.OutboxStatusSent, sms.Status)
+
+	emails := harness.EmailService.GetAllMessages()
+	require.Len(t, emails, 1)
+	assert.Equal(t, "client@example.fr", emails[0].To)
+	assert.Equal(t, "Commande "+order.ID+" confirmée", emails[0].Subject)
+	assert.Contains(t, emails[0].Body, order.TotalAmount.StringFixed(2))
+	assert.Contains(t, emails[0].Body, output.PaymentID)
+	for _, item := range order.Items {
+		assert.Contains(t, emails[0].Body, item.SKU)
+		assert.Contains(t, emails[0].HTML, item.SKU)
+	}
+
+	texts := harness.SMSService.GetAllMessages()
+	require.Len(t, texts, 1)
+	assert.Equal(t, "+33600000000", texts[0].To)
+	assert.Contains(t, texts[0].Body, order.ID)
+}
+
+func TestOrderFailureNotificationIsEmailOnly(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	harness.InventoryMgr.SetUnavailable("ITEM-001")
+
+	// order_failed has no route, so only the default email channel is used
+	order := fixtures.CreateValidOrder()
+	execution, err := harness.ScheduleOrder(ctx, &workflows.OrderProcessingInput{
+		Order:         order,
+		CustomerEmail: "customer@example.com",
+		CustomerPhone: "+15550100",
+	})
+	require.NoError(t, err)
+
+	result, err := harness.WaitForOrchestration(ctx, execution, 10*time.Second)
+	require.NoError(t, err)
+	require.True(t, IsSuccessful(result))
+
+	_, err = harness.WaitForDelivery(ctx, order.ID, "order_failed", 5*time.Second)
+	require.NoError(t, err)
+
+	messages, err := harness.Outbox.ListByOrder(ctx, order.ID)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, notification.ChannelEmail, messages[0].Channel)
+	assert.Empty(t, harness.SMSService.GetAllMessages())
+}
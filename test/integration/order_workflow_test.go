@@ -5,8 +5,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/microsoft/durabletask-go/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/notification"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
 	"github.com/Youmanvi/taskorchestrator/internal/workflows"
 	"github.com/Youmanvi/taskorchestrator/test/fixtures"
 )
@@ -35,7 +39,7 @@ func TestOrderProcessingSuccessful(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify result
-	assert.True(t, result.IsSuccessful())
+	assert.True(t, IsSuccessful(result))
 
 	output, err := GetOrderOutput(result)
 	require.NoError(t, err)
@@ -44,10 +48,50 @@ func TestOrderProcessingSuccessful(t *testing.T) {
 	assert.Equal(t, order.ID, output.OrderID)
 	assert.NotEmpty(t, output.PaymentID)
 	assert.NotEmpty(t, output.ReservationID)
+	require.Len(t, output.History, 1)
+	assert.Equal(t, string(domain.OrderStatusPending), output.History[0].From)
+	assert.Equal(t, string(domain.OrderStatusConfirmed), output.History[0].To)
+
+	// The paid order's stock is committed, so it no longer expires
+	res, exists := harness.InventoryMgr.GetReservation(output.ReservationID)
+	require.True(t, exists, "reservation should exist")
+	assert.Equal(t, domain.ReservationStatusCommitted, res.Status)
+}
+
+func TestOrderProcessingRejectsNonPendingOrder(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
 
-	// Verify reservation was created
-	_, exists := harness.InventoryMgr.GetReservation(output.ReservationID)
-	assert.True(t, exists, "reservation should exist")
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	order := fixtures.CreateValidOrder()
+	require.NoError(t, order.MarkFailed("declined", time.Now()))
+
+	execution, err := harness.ScheduleOrder(ctx, &workflows.OrderProcessingInput{
+		Order:         order,
+		CustomerEmail: "customer@example.com",
+	})
+	require.NoError(t, err)
+
+	// A failed order cannot be processed again: the orchestration completes
+	// with a failed outcome and tells the customer, without charging anything
+	result, err := harness.WaitForOrchestration(ctx, execution, 5*time.Second)
+	require.NoError(t, err)
+	require.True(t, IsSuccessful(result))
+
+	output, err := GetOrderOutput(result)
+	require.NoError(t, err)
+	assert.Equal(t, "failed", output.Status)
+	assert.Contains(t, output.Message, "failed")
+	assert.Empty(t, output.TransactionID)
+	assert.Empty(t, output.DeadLetters)
+
+	messages, err := harness.Outbox.ListByOrder(ctx, order.ID)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "order_failed", messages[0].EventType)
 }
 
 func TestOrderProcessingSingleItem(t *testing.T) {
@@ -72,7 +116,7 @@ func TestOrderProcessingSingleItem(t *testing.T) {
 	result, err := harness.WaitForOrchestration(ctx, execution, 5*time.Second)
 	require.NoError(t, err)
 
-	assert.True(t, result.IsSuccessful())
+	assert.True(t, IsSuccessful(result))
 
 	output, err := GetOrderOutput(result)
 	require.NoError(t, err)
@@ -102,7 +146,7 @@ func TestOrderProcessingLargeOrder(t *testing.T) {
 	result, err := harness.WaitForOrchestration(ctx, execution, 5*time.Second)
 	require.NoError(t, err)
 
-	assert.True(t, result.IsSuccessful())
+	assert.True(t, IsSuccessful(result))
 
 	output, err := GetOrderOutput(result)
 	require.NoError(t, err)
@@ -134,7 +178,12 @@ func TestOrderNotifications(t *testing.T) {
 	result, err := harness.WaitForOrchestration(ctx, execution, 5*time.Second)
 	require.NoError(t, err)
 
-	assert.True(t, result.IsSuccessful())
+	assert.True(t, IsSuccessful(result))
+
+	// The confirmation is queued by the workflow and sent by the dispatcher
+	delivery, err := harness.WaitForDelivery(ctx, order.ID, "order_confirmed", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, notification.OutboxStatusSent, delivery.Status)
 
 	// Verify confirmation email was sent
 	messages := harness.EmailService.GetAllMessages()
@@ -162,10 +211,7 @@ func TestMultipleOrdersInParallel(t *testing.T) {
 
 	// Schedule multiple orders
 	numOrders := 3
-	type execution interface {
-		WaitForCompletion(context.Context) (*api.OrchestrationExecutionResult, error)
-	}
-	executions := make([]execution, numOrders)
+	executions := make([]api.InstanceID, numOrders)
 
 	for i := 0; i < numOrders; i++ {
 		order := fixtures.CreateValidOrder()
@@ -183,10 +229,40 @@ func TestMultipleOrdersInParallel(t *testing.T) {
 	successCount := 0
 	for i := 0; i < numOrders; i++ {
 		result, err := harness.WaitForOrchestration(ctx, executions[i], 5*time.Second)
-		if err == nil && result.IsSuccessful() {
+		if err == nil && IsSuccessful(result) {
 			successCount++
 		}
 	}
 
 	assert.Equal(t, numOrders, successCount, "all orders should complete successfully")
 }
+
+func TestOrderProcessingRecordsOrchestrationMetricsOnce(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	order := fixtures.CreateValidOrder()
+	execution, err := harness.ScheduleOrder(ctx, &workflows.OrderProcessingInput{
+		Order:         order,
+		CustomerEmail: "customer@example.com",
+	})
+	require.NoError(t, err)
+
+	result, err := harness.WaitForOrchestration(ctx, execution, 5*time.Second)
+	require.NoError(t, err)
+	require.True(t, IsSuccessful(result))
+
+	// Hooks run just after the final commit that completion waits on
+	metrics := harness.Metrics
+	completed := metrics.OrchestrationCompleted.WithLabelValues("order_processing", "confirmed")
+	assert.Eventually(t, func() bool { return testutil.ToFloat64(completed) == 1 }, time.Second, 10*time.Millisecond)
+
+	// The orchestrator replays once per activity, but each instance counts once
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.OrchestrationStarted.WithLabelValues("order_processing")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(completed))
+	assert.Equal(t, 0, testutil.CollectAndCount(metrics.OrchestrationFailed))
+}
@@ -0,0 +1,121 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/workflows"
+	"github.com/Youmanvi/taskorchestrator/test/fixtures"
+)
+
+// createWarehouseOrder creates an order fulfilled from two named warehouses
+// plus the default one
+func createWarehouseOrder() domain.Order {
+	return fixtures.CreateOrderWithItems("CUST-12345", []domain.OrderItem{
+		{SKU: "ITEM-001", Quantity: 1, Price: decimal.NewFromInt(10)},
+		{SKU: "ITEM-002", Quantity: 2, Price: decimal.NewFromInt(20), Warehouse: "east"},
+		{SKU: "ITEM-003", Quantity: 1, Price: decimal.NewFromInt(30), Warehouse: "west"},
+		{SKU: "ITEM-004", Quantity: 3, Price: decimal.NewFromInt(40), Warehouse: "west"},
+	})
+}
+
+func runOrder(t *testing.T, harness *TestHarness, order domain.Order) *workflows.OrderProcessingOutput {
+	ctx := context.Background()
+	execution, err := harness.ScheduleOrder(ctx, &workflows.OrderProcessingInput{
+		Order:         order,
+		CustomerEmail: "customer@example.com",
+	})
+	require.NoError(t, err)
+
+	result, err := harness.WaitForOrchestration(ctx, execution, 10*time.Second)
+	require.NoError(t, err)
+	require.True(t, IsSuccessful(result))
+
+	output, err := GetOrderOutput(result)
+	require.NoError(t, err)
+	return output
+}
+
+func TestOrderProcessingReservesPerWarehouse(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	order := createWarehouseOrder()
+	output := runOrder(t, harness, order)
+	assert.Equal(t, "confirmed", output.Status)
+	assert.Equal(t, []string{
+		domain.ReservationID(order.ID, ""),
+		domain.ReservationID(order.ID, "east"),
+		domain.ReservationID(order.ID, "west"),
+	}, output.ReservationIDs)
+	assert.Equal(t, domain.ReservationID(order.ID, ""), output.ReservationID)
+
+	west, exists := harness.InventoryMgr.GetReservation(domain.ReservationID(order.ID, "west"))
+	require.True(t, exists)
+	assert.Len(t, west.Items, 2)
+
+	// One check per SKU
+	checks := 0
+	for _, span := range harness.Spans.Ended() {
+		if span.Name() == "inventory:check" {
+			checks++
+		}
+	}
+	assert.Equal(t, len(order.Items), checks)
+}
+
+func TestOrderProcessingMergesUnavailableItems(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	harness.InventoryMgr.SetUnavailable("ITEM-002", "ITEM-004")
+
+	output := runOrder(t, harness, createWarehouseOrder())
+	assert.Equal(t, "failed", output.Status)
+	assert.Equal(t, "items not available: ITEM-002, ITEM-004", output.Message)
+	assert.Empty(t, output.ReservationIDs)
+}
+
+func TestOrderProcessingReleasesWarehousesWhenOneFails(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	// Stock runs out in the east warehouse after the availability check
+	harness.InventoryMgr.SetWarehouseClosed("east")
+
+	order := createWarehouseOrder()
+	output := runOrder(t, harness, order)
+	assert.Equal(t, "failed", output.Status)
+	assert.Contains(t, output.Message, "warehouse east")
+
+	// The default and west reservations were made, then released, and the
+	// authorization taken before reserving was voided
+	require.Len(t, output.Compensations, 3)
+	assert.Equal(t, "payment:void", output.Compensations[2].Activity)
+	assert.Equal(t, string(domain.PaymentStatusVoided), output.PaymentStatus)
+	for _, warehouse := range []string{"", "west"} {
+		res, exists := harness.InventoryMgr.GetReservation(domain.ReservationID(order.ID, warehouse))
+		require.True(t, exists, fmt.Sprintf("reservation in warehouse %q", warehouse))
+		assert.Equal(t, domain.ReservationStatusReleased, res.Status)
+	}
+	_, exists := harness.InventoryMgr.GetReservation(domain.ReservationID(order.ID, "east"))
+	assert.False(t, exists)
+}
@@ -0,0 +1,228 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/Youmanvi/taskorchestrator/internal/domain"
+	"github.com/Youmanvi/taskorchestrator/internal/workflows"
+	"github.com/Youmanvi/taskorchestrator/test/fixtures"
+)
+
+// confirmOrder runs an order through order_processing and returns the
+// confirmed order together with its captured payment
+func confirmOrder(t *testing.T, harness *TestHarness) (domain.Order, domain.Payment) {
+	ctx := context.Background()
+	order := fixtures.CreateValidOrder()
+
+	execution, err := harness.ScheduleOrder(ctx, &workflows.OrderProcessingInput{
+		Order:         order,
+		CustomerEmail: "customer@example.com",
+	})
+	require.NoError(t, err)
+
+	result, err := harness.WaitForOrchestration(ctx, execution, 5*time.Second)
+	require.NoError(t, err)
+	output, err := GetOrderOutput(result)
+	require.NoError(t, err)
+	require.Equal(t, "confirmed", output.Status)
+
+	require.NoError(t, order.MarkConfirmed(output.PaymentID, output.ReservationID, time.Now()))
+	pay, err := domain.NewPayment(output.PaymentID, order.ID, order.TotalAmount, domain.PaymentMethodCard)
+	require.NoError(t, err)
+	require.NoError(t, pay.MarkCompleted(output.TransactionID, time.Now()))
+
+	return order, *pay
+}
+
+// runRefund schedules a refund or cancellation and waits for its output
+func runRefund(t *testing.T, harness *TestHarness, orchestrator, instanceID string, input *workflows.OrderRefundInput) *workflows.OrderRefundOutput {
+	ctx := context.Background()
+	execution, err := harness.ScheduleRefund(ctx, orchestrator, instanceID, input)
+	require.NoError(t, err)
+
+	result, err := harness.WaitForOrchestration(ctx, execution, 5*time.Second)
+	require.NoError(t, err)
+	require.True(t, IsSuccessful(result))
+
+	output, err := GetRefundOutput(result)
+	require.NoError(t, err)
+	return output
+}
+
+func TestOrderRefundFullAndPartial(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	order, pay := confirmOrder(t, harness)
+
+	// A partial refund leaves the order confirmed and the stock committed
+	partial := runRefund(t, harness, "order_refund", order.ID+"-refund-1", &workflows.OrderRefundInput{
+		Order:         order,
+		Payment:       pay,
+		Amount:        decimal.NewFromInt(10),
+		CustomerEmail: "customer@example.com",
+	})
+	assert.Equal(t, workflows.RefundStatusPartiallyRefunded, partial.Status)
+	assert.True(t, decimal.NewFromInt(10).Equal(partial.RefundedAmount))
+	assert.Equal(t, domain.OrderStatusConfirmed, partial.OrderStatus)
+	assert.False(t, partial.ReservationRestocked)
+
+	// Refunding more than remains is rejected
+	tooMuch := runRefund(t, harness, "order_refund", order.ID+"-refund-2", &workflows.OrderRefundInput{
+		Order:   order,
+		Payment: pay,
+		Amount:  order.TotalAmount,
+	})
+	assert.Equal(t, workflows.RefundStatusRejected, tooMuch.Status)
+
+	// A zero amount refunds the remainder and restocks the reservation
+	full := runRefund(t, harness, "order_refund", order.ID+"-refund-3", &workflows.OrderRefundInput{
+		Order:         order,
+		Payment:       pay,
+		CustomerEmail: "customer@example.com",
+	})
+	assert.Equal(t, workflows.RefundStatusRefunded, full.Status)
+	assert.True(t, order.TotalAmount.Sub(decimal.NewFromInt(10)).Equal(full.RefundedAmount))
+	assert.Equal(t, domain.OrderStatusRefunded, full.OrderStatus)
+	assert.Equal(t, domain.PaymentStatusRefunded, full.PaymentStatus)
+	assert.True(t, full.ReservationRestocked)
+
+	res, exists := harness.InventoryMgr.GetReservation(order.ReservationID)
+	require.True(t, exists)
+	assert.Equal(t, domain.ReservationStatusRestocked, res.Status)
+
+	// The gateway now reports the payment refunded, so another refund is rejected
+	again := runRefund(t, harness, "order_refund", order.ID+"-refund-4", &workflows.OrderRefundInput{
+		Order:   order,
+		Payment: pay,
+	})
+	assert.Equal(t, workflows.RefundStatusRejected, again.Status)
+}
+
+func TestOrderRefundLineItemReturns(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	order, pay := confirmOrder(t, harness)
+	first, second := order.Items[0], order.Items[1]
+
+	// Returning one unit refunds that line's unit price and restocks only it
+	one := domain.OrderItem{SKU: first.SKU, Quantity: 1}
+	partial := runRefund(t, harness, "order_refund", order.ID+"-return-1", &workflows.OrderRefundInput{
+		Order:   order,
+		Payment: pay,
+		Items:   []domain.OrderItem{one},
+	})
+	assert.Equal(t, workflows.RefundStatusPartiallyRefunded, partial.Status)
+	assert.True(t, first.Price.Equal(partial.RefundedAmount))
+	assert.True(t, first.Price.Equal(partial.TotalRefunded))
+	assert.False(t, partial.ReservationRestocked)
+
+	res, exists := harness.InventoryMgr.GetReservation(order.ReservationID)
+	require.True(t, exists)
+	assert.Equal(t, domain.ReservationStatusCommitted, res.Status)
+	assert.Contains(t, res.Items, domain.ReservedItem{SKU: first.SKU, Quantity: first.Quantity - 1})
+
+	// Returning the same line again in full is rejected before any money
+	// moves: one unit of it is already back in stock
+	again := runRefund(t, harness, "order_refund", order.ID+"-return-again", &workflows.OrderRefundInput{
+		Order:   order,
+		Payment: pay,
+		Items:   []domain.OrderItem{{SKU: first.SKU, Quantity: first.Quantity}},
+	})
+	assert.Equal(t, workflows.RefundStatusRejected, again.Status)
+	assert.Contains(t, again.Message, "RESTOCK_EXCEEDS_COMMITTED")
+	assert.Empty(t, again.RefundID)
+
+	txn, err := harness.PaymentGateway.GetStatus(ctx, pay.TransactionID)
+	require.NoError(t, err)
+	assert.True(t, first.Price.Equal(txn.Refunded), "refunded %s", txn.Refunded)
+
+	// Returning a SKU that was never ordered is rejected
+	unknown := runRefund(t, harness, "order_refund", order.ID+"-return-2", &workflows.OrderRefundInput{
+		Order:   order,
+		Payment: pay,
+		Items:   []domain.OrderItem{{SKU: "ITEM-UNKNOWN", Quantity: 1}},
+	})
+	assert.Equal(t, workflows.RefundStatusRejected, unknown.Status)
+
+	// Returning the rest refunds exactly the remaining captured amount
+	rest := runRefund(t, harness, "order_refund", order.ID+"-return-3", &workflows.OrderRefundInput{
+		Order:   order,
+		Payment: pay,
+		Items: []domain.OrderItem{
+			{SKU: first.SKU, Quantity: first.Quantity - 1},
+			{SKU: second.SKU, Quantity: second.Quantity},
+		},
+	})
+	assert.Equal(t, workflows.RefundStatusRefunded, rest.Status)
+	assert.True(t, order.TotalAmount.Equal(rest.TotalRefunded))
+	assert.True(t, rest.ReservationRestocked)
+	assert.Equal(t, domain.ReservationStatusRestocked, res.Status)
+
+	txn, err = harness.PaymentGateway.GetStatus(ctx, pay.TransactionID)
+	require.NoError(t, err)
+	assert.True(t, order.TotalAmount.Equal(txn.Refunded))
+}
+
+func TestOrderCancellation(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	order, pay := confirmOrder(t, harness)
+
+	output := runRefund(t, harness, "order_cancellation", order.ID+"-cancel", &workflows.OrderRefundInput{
+		Order:         order,
+		Payment:       pay,
+		CustomerEmail: "customer@example.com",
+		Reason:        "customer request",
+	})
+	assert.Equal(t, workflows.RefundStatusCancelled, output.Status)
+	assert.Equal(t, domain.OrderStatusCancelled, output.OrderStatus)
+	assert.True(t, order.TotalAmount.Equal(output.RefundedAmount))
+	assert.True(t, output.ReservationRestocked)
+}
+
+func TestOrderRefundRejectsInvalidStates(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	// A pending order has not been charged yet
+	pending := fixtures.CreateValidOrder()
+	output := runRefund(t, harness, "order_refund", pending.ID+"-refund", &workflows.OrderRefundInput{Order: pending})
+	assert.Equal(t, workflows.RefundStatusRejected, output.Status)
+
+	// An already refunded order cannot be refunded or cancelled again
+	order, pay := confirmOrder(t, harness)
+	require.NoError(t, order.MarkRefunded(time.Now()))
+	output = runRefund(t, harness, "order_refund", order.ID+"-refund", &workflows.OrderRefundInput{Order: order, Payment: pay})
+	assert.Equal(t, workflows.RefundStatusRejected, output.Status)
+	output = runRefund(t, harness, "order_cancellation", order.ID+"-cancel", &workflows.OrderRefundInput{Order: order, Payment: pay})
+	assert.Equal(t, workflows.RefundStatusRejected, output.Status)
+
+	// Nothing was refunded at the gateway
+	txn, err := harness.PaymentGateway.GetStatus(ctx, pay.TransactionID)
+	require.NoError(t, err)
+	assert.True(t, txn.Refunded.IsZero())
+}
@@ -0,0 +1,90 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/Youmanvi/taskorchestrator/internal/activities/notification"
+	"github.com/Youmanvi/taskorchestrator/test/fakes"
+	"github.com/Youmanvi/taskorchestrator/test/fixtures"
+)
+
+// newSMTPHarness starts a fake SMTP server and a harness that emails through
+// it
+func newSMTPHarness(t *testing.T) (*TestHarness, *fakes.SMTPServer) {
+	t.Helper()
+	server, err := fakes.NewSMTPServer()
+	require.NoError(t, err)
+	t.Cleanup(func() { server.Close() })
+
+	smtp, err := notification.NewSMTPService(notification.SMTPOptions{
+		Host:     server.Host(),
+		Port:     server.Port(),
+		From:     "orders@example.com",
+		StartTLS: notification.StartTLSDisabled,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { smtp.Close() })
+
+	harness, err := NewTestHarnessWithEmail(smtp)
+	require.NoError(t, err)
+	return harness, server
+}
+
+func TestOrderConfirmationIsEmailedOverSMTP(t *testing.T) {
+	harness, server := newSMTPHarness(t)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	order := fixtures.CreateValidOrder()
+	output := runOrder(t, harness, order)
+	assert.Equal(t, "confirmed", output.Status)
+
+	delivery, err := harness.WaitForDelivery(ctx, order.ID, "order_confirmed", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, notification.OutboxStatusSent, delivery.Status)
+
+	messages := server.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, []string{"customer@example.com"}, messages[0].To)
+	assert.Equal(t, "Order "+order.ID+" Confirmed", messages[0].Subject)
+	assert.Contains(t, messages[0].Text, order.ID)
+	assert.Contains(t, messages[0].HTML, order.ID)
+	assert.Equal(t, delivery.MessageID, messages[0].Header.Get("Message-ID"))
+}
+
+func TestSMTPRepliesDecideWhetherEmailIsRetried(t *testing.T) {
+	harness, server := newSMTPHarness(t)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	// A 4xx reply is retried and the second attempt is accepted
+	server.RejectNext(451, "4.3.0 Mailbox busy")
+	order := fixtures.CreateValidOrder()
+	runOrder(t, harness, order)
+
+	delivery, err := harness.WaitForDelivery(ctx, order.ID, "order_confirmed", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, notification.OutboxStatusSent, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Len(t, server.Messages(), 1)
+
+	// A 5xx reply fails the email without retrying
+	server.RejectNext(550, "5.1.1 No such user")
+	order = fixtures.CreateValidOrder()
+	runOrder(t, harness, order)
+
+	delivery, err = harness.WaitForDelivery(ctx, order.ID, "order_confirmed", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, notification.OutboxStatusFailed, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Contains(t, delivery.LastError, "SMTP_REJECTED")
+	assert.Len(t, server.Messages(), 1)
+}
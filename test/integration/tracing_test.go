@@ -0,0 +1,119 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"github.com/Youmanvi/taskorchestrator/internal/infrastructure/observability"
+	"github.com/Youmanvi/taskorchestrator/internal/workflows"
+	"github.com/Youmanvi/taskorchestrator/test/fixtures"
+)
+
+// spanAttr returns the value of a span attribute, or an invalid value
+func spanAttr(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+// orchestrationSpan returns the recorded orchestration span and the
+// activity spans grouped by name
+func orchestrationSpan(t *testing.T, spans []sdktrace.ReadOnlySpan) (sdktrace.ReadOnlySpan, map[string][]sdktrace.ReadOnlySpan) {
+	var root sdktrace.ReadOnlySpan
+	children := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range spans {
+		if span.Name() == "orchestration:order_processing" {
+			require.Nil(t, root, "orchestration span must be recorded once")
+			root = span
+			continue
+		}
+		children[span.Name()] = append(children[span.Name()], span)
+	}
+	require.NotNil(t, root, "orchestration span not recorded")
+	return root, children
+}
+
+func TestOrderProcessingSpanTree(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	order := fixtures.CreateValidOrder()
+	execution, err := harness.ScheduleOrder(ctx, &workflows.OrderProcessingInput{
+		Order:         order,
+		CustomerEmail: "customer@example.com",
+	})
+	require.NoError(t, err)
+
+	result, err := harness.WaitForOrchestration(ctx, execution, 5*time.Second)
+	require.NoError(t, err)
+	require.True(t, IsSuccessful(result))
+
+	root, children := orchestrationSpan(t, harness.Spans.Ended())
+	assert.Equal(t, observability.OrchestrationSpanContext(order.ID).SpanID(), root.SpanContext().SpanID())
+	assert.Equal(t, order.ID, spanAttr(root, observability.AttrOrchestrationID).AsString())
+	assert.Equal(t, "confirmed", spanAttr(root, observability.AttrOrchestrationStatus).AsString())
+
+	for _, name := range []string{"inventory:check", "payment:authorize", "inventory:reserve", "payment:capture", "notification:order_confirmation"} {
+		require.NotEmpty(t, children[name], "no span for %s", name)
+		for _, span := range children[name] {
+			assert.Equal(t, root.SpanContext().TraceID(), span.SpanContext().TraceID())
+			assert.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID(), "%s must nest under the orchestration span", name)
+			assert.Equal(t, name, spanAttr(span, observability.AttrActivityName).AsString())
+		}
+	}
+}
+
+func TestOrderProcessingSpanTreeRecordsFailedAttempts(t *testing.T) {
+	harness, err := NewTestHarness()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, harness.Start(ctx))
+	defer harness.Stop(ctx)
+
+	harness.PaymentGateway.SetCaptureError(fmt.Errorf("capture rejected"))
+
+	order := fixtures.CreateValidOrder()
+	execution, err := harness.ScheduleOrder(ctx, &workflows.OrderProcessingInput{
+		Order:         order,
+		CustomerEmail: "customer@example.com",
+	})
+	require.NoError(t, err)
+
+	_, err = harness.WaitForOrchestration(ctx, execution, 10*time.Second)
+	require.NoError(t, err)
+
+	root, children := orchestrationSpan(t, harness.Spans.Ended())
+	assert.Equal(t, "failed", spanAttr(root, observability.AttrOrchestrationStatus).AsString())
+
+	// One span per attempt, each carrying the attempt number and error classification
+	captures := children["payment:capture"]
+	require.Len(t, captures, 3)
+	for i, span := range captures {
+		assert.Equal(t, int64(i+1), spanAttr(span, observability.AttrActivityAttempt).AsInt64())
+		assert.Equal(t, codes.Error, span.Status().Code)
+		assert.Equal(t, "transient", spanAttr(span, observability.AttrErrorType).AsString())
+		assert.NotEmpty(t, spanAttr(span, observability.AttrErrorCode).AsString())
+		assert.Equal(t, root.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+
+	// The compensating release and void are part of the same tree
+	for _, name := range []string{"inventory:release", "payment:void"} {
+		require.NotEmpty(t, children[name], "no span for %s", name)
+		assert.Equal(t, root.SpanContext().SpanID(), children[name][0].Parent().SpanID())
+	}
+}